@@ -0,0 +1,231 @@
+// Command importer imports a directory tree of audio files into the catalog.
+//
+// Every file goes through the same path as POST /api/songs/upload: format
+// detection by magic bytes, duplicate detection and content-addressed
+// storage. Imported songs are created in processing state; the job workers
+// of the web server then read their duration and tags (artists, album,
+// genres and cover art are created when missing). A dry run reads tags and
+// durations itself to report what would be created.
+//
+//	go run ./cmd/importer -dir ./library -uploader <user-id> -workers 8
+//	go run ./cmd/importer -dir ./library -dry-run
+//
+// Progress is appended to a JSON lines file; running the same command again
+// skips files already imported and retries failed ones.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+
+	"spotify-clone/internal/config"
+	"spotify-clone/internal/database"
+	"spotify-clone/internal/song"
+	"spotify-clone/internal/user"
+)
+
+func main() {
+	dir := flag.String("dir", "", "directory to import (required)")
+	uploader := flag.String("uploader", "", "user ID recorded as uploader of the imported songs (required unless -dry-run)")
+	workers := flag.Int("workers", runtime.NumCPU(), "number of files imported in parallel")
+	progressPath := flag.String("progress", "import-progress.jsonl", "progress file used to resume an interrupted import")
+	dryRun := flag.Bool("dry-run", false, "only report what would be imported")
+	flag.Parse()
+
+	if *dir == "" || (*uploader == "" && !*dryRun) {
+		flag.Usage()
+		os.Exit(2)
+	}
+	var uploaderID uuid.UUID
+	if *uploader != "" {
+		var err error
+		if uploaderID, err = uuid.Parse(*uploader); err != nil {
+			log.Fatal("Invalid -uploader user ID:", err)
+		}
+		*uploader = uploaderID.String()
+	}
+	if *workers < 1 {
+		*workers = 1
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal("Failed to load config:", err)
+	}
+	db, err := database.Connect(cfg.Database)
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+	defer db.Close()
+
+	// Kiểm tra uploader tồn tại trước khi chạy worker, tránh import cả thư mục rồi mới lỗi
+	if *uploader != "" {
+		if _, err := user.NewUserRepository(db).FindByID(context.Background(), uploaderID); err != nil {
+			log.Fatal("Failed to find uploader:", err)
+		}
+	}
+
+	blobStorage, err := config.NewStorage(cfg)
+	if err != nil {
+		log.Fatal("Failed to initialize storage:", err)
+	}
+
+	progress, err := openProgress(*progressPath, *dryRun)
+	if err != nil {
+		log.Fatal("Failed to open progress file:", err)
+	}
+	defer progress.Close()
+
+	// Ctrl-C: ngừng nhận file mới, chờ các file đang import xong
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	imp := &importer{
+		root:     *dir,
+		uploader: *uploader,
+		dryRun:   *dryRun,
+		ingestor: song.NewIngestor(song.NewRepository(db), blobStorage),
+		progress: progress,
+		report:   newReport(),
+	}
+	start := time.Now()
+	if err := imp.Run(ctx, *workers); err != nil {
+		log.Fatal("Import failed:", err)
+	}
+	imp.report.Print(os.Stdout, *dryRun, time.Since(start))
+}
+
+// importer feeds the files of root to a pool of workers
+type importer struct {
+	root     string
+	uploader string
+	dryRun   bool
+	ingestor *song.Ingestor
+	progress *progressFile
+	report   *report
+}
+
+// Run walks root and imports every file not already in the progress file
+func (imp *importer) Run(ctx context.Context, workers int) error {
+	paths := make(chan string)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for rel := range paths {
+				imp.importFile(rel)
+			}
+		}()
+	}
+
+	walkErr := filepath.WalkDir(imp.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// Bỏ qua file/thư mục ẩn (.DS_Store, .git, ...)
+		if path != imp.root && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(imp.root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if imp.progress.Done(rel) {
+			imp.report.Resumed()
+			return nil
+		}
+
+		select {
+		case paths <- rel:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	close(paths)
+	wg.Wait()
+
+	if errors.Is(walkErr, context.Canceled) {
+		log.Println("Interrupted, run the same command again to resume")
+		return nil
+	}
+	return walkErr
+}
+
+// importFile ingests one file and records its outcome. It does not use the
+// run context: a file being imported when the import is interrupted is
+// finished so the progress file stays accurate.
+func (imp *importer) importFile(rel string) {
+	entry := progressEntry{Path: rel}
+
+	s, err := imp.ingest(rel)
+	var dupErr *song.DuplicateError
+	switch {
+	case err == nil:
+		entry.Status = statusImported
+		entry.SongID = s.ID
+	case errors.As(err, &dupErr):
+		entry.Status = statusDuplicate
+		entry.SongID = dupErr.SongID
+	case errors.Is(err, song.ErrUnsupportedFormat):
+		entry.Status = statusSkipped
+	default:
+		entry.Status = statusFailed
+		entry.Error = err.Error()
+	}
+	entry.At = time.Now()
+
+	imp.report.Add(entry, s)
+	if err := imp.progress.Record(entry); err != nil {
+		log.Println("progress:", err)
+	}
+}
+
+func (imp *importer) ingest(rel string) (*song.Song, error) {
+	f, err := os.Open(filepath.Join(imp.root, filepath.FromSlash(rel)))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return imp.ingestor.Ingest(context.Background(), song.IngestInput{
+		File:       f,
+		Filename:   filepath.Base(rel),
+		UploadedBy: imp.uploader,
+		DryRun:     imp.dryRun,
+	})
+}
+
+// fmtDuration formats seconds as m:ss or h:mm:ss
+func fmtDuration(seconds int) string {
+	d := time.Duration(seconds) * time.Second
+	h, m, s := int(d.Hours()), int(d.Minutes())%60, seconds%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%d:%02d", m, s)
+}
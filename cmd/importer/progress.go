@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"time"
+)
+
+// Import outcomes of a file
+const (
+	statusImported  = "imported"
+	statusDuplicate = "duplicate"
+	statusSkipped   = "skipped" // not an audio file
+	statusFailed    = "failed"  // retried on the next run
+)
+
+// progressEntry is one line of the progress file
+type progressEntry struct {
+	Path   string    `json:"path"` // relative to the import directory
+	Status string    `json:"status"`
+	SongID string    `json:"song_id,omitempty"`
+	Error  string    `json:"error,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+// progressFile records the outcome of every file as JSON lines so an
+// interrupted import can be resumed. Later lines override earlier ones.
+type progressFile struct {
+	mu   sync.Mutex
+	file *os.File
+	done map[string]bool
+}
+
+// openProgress loads an existing progress file (if any) and opens it for
+// appending. A read-only progress file (dry run) records nothing.
+func openProgress(path string, readOnly bool) (*progressFile, error) {
+	p := &progressFile{done: map[string]bool{}}
+
+	f, err := os.Open(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil {
+		sc := bufio.NewScanner(f)
+		for sc.Scan() {
+			var e progressEntry
+			if json.Unmarshal(sc.Bytes(), &e) != nil {
+				continue // dòng bị cắt dở khi process bị kill
+			}
+			p.done[e.Path] = e.Status != statusFailed
+		}
+		f.Close()
+		if err := sc.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	if readOnly {
+		return p, nil
+	}
+	p.file, err = os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Done reports whether path was handled by a previous run
+func (p *progressFile) Done(path string) bool {
+	return p.done[path]
+}
+
+// Record appends the outcome of a file
+func (p *progressFile) Record(e progressEntry) error {
+	if p.file == nil {
+		return nil
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, err = p.file.Write(append(data, '\n'))
+	return err
+}
+
+func (p *progressFile) Close() error {
+	if p.file == nil {
+		return nil
+	}
+	return p.file.Close()
+}
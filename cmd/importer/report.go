@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"spotify-clone/internal/song"
+	"spotify-clone/pkg/audioduration"
+)
+
+// report prints one line per file as it is handled and a summary at the end
+type report struct {
+	mu       sync.Mutex
+	counts   map[string]int
+	resumed  int
+	duration int // seconds of imported audio
+	formats  map[string]int
+	artists  map[string]string // lower-cased name -> name, from tags
+	albums   map[string]string
+	genres   map[string]string
+}
+
+func newReport() *report {
+	return &report{
+		counts:  map[string]int{},
+		formats: map[string]int{},
+		artists: map[string]string{},
+		albums:  map[string]string{},
+		genres:  map[string]string{},
+	}
+}
+
+// Resumed counts a file skipped because a previous run handled it
+func (r *report) Resumed() {
+	r.mu.Lock()
+	r.resumed++
+	r.mu.Unlock()
+}
+
+// Add records the outcome of a file. s is the created (or, in a dry run,
+// the would-be) song when the file was imported.
+func (r *report) Add(e progressEntry, s *song.Song) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.counts[e.Status]++
+	switch e.Status {
+	case statusImported:
+		if s.Status == song.StatusProcessing {
+			fmt.Printf("%-9s %s  [%s]\n", e.Status, e.Path, s.Title)
+			return
+		}
+		r.duration += s.Duration
+		r.formats[formatName(s.AudioFormat)]++
+		var artists []string
+		for _, a := range s.Artists {
+			addName(r.artists, a.Name)
+			artists = append(artists, a.Name)
+		}
+		album := ""
+		if s.Album != nil {
+			addName(r.albums, s.Album.Title)
+			album = s.Album.Title
+		}
+		for _, g := range s.Genres {
+			addName(r.genres, g.Name)
+		}
+		fmt.Printf("%-9s %s  [%s | %s | %s | %s]\n", e.Status, e.Path,
+			s.Title, strings.Join(artists, ", "), album, fmtDuration(s.Duration))
+	case statusDuplicate:
+		if e.SongID == "" {
+			// Song đã có không thuộc uploader và chưa công khai
+			fmt.Printf("%-9s %s\n", e.Status, e.Path)
+			break
+		}
+		fmt.Printf("%-9s %s  (song %s)\n", e.Status, e.Path, e.SongID)
+	case statusFailed:
+		fmt.Printf("%-9s %s  %s\n", e.Status, e.Path, e.Error)
+	}
+}
+
+func addName(names map[string]string, name string) {
+	if name == "" {
+		return
+	}
+	key := strings.ToLower(name)
+	if _, ok := names[key]; !ok {
+		names[key] = name
+	}
+}
+
+// Print writes the summary
+func (r *report) Print(w io.Writer, dryRun bool, elapsed time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	imported := "Imported"
+	if dryRun {
+		imported = "Would import"
+	}
+	fmt.Fprintln(w, "=== Import summary ===")
+	if dryRun {
+		fmt.Fprintf(w, "%-22s %d (%s of audio)\n", imported+":", r.counts[statusImported], fmtDuration(r.duration))
+	} else {
+		fmt.Fprintf(w, "%-22s %d (processed in the background)\n", imported+":", r.counts[statusImported])
+	}
+	fmt.Fprintf(w, "%-22s %d\n", "Duplicates:", r.counts[statusDuplicate])
+	fmt.Fprintf(w, "%-22s %d\n", "Skipped (not audio):", r.counts[statusSkipped])
+	fmt.Fprintf(w, "%-22s %d\n", "Failed:", r.counts[statusFailed])
+	fmt.Fprintf(w, "%-22s %d\n", "Done in previous runs:", r.resumed)
+	if dryRun {
+		fmt.Fprintf(w, "%-22s %s\n", "Formats:", formatCounts(r.formats))
+		fmt.Fprintf(w, "%-22s %d\n", "Artists in tags:", len(r.artists))
+		fmt.Fprintf(w, "%-22s %d\n", "Albums in tags:", len(r.albums))
+		fmt.Fprintf(w, "%-22s %s\n", "Genres in tags:", strings.Join(sortedValues(r.genres), ", "))
+	}
+	fmt.Fprintf(w, "%-22s %s\n", "Elapsed:", elapsed.Round(time.Millisecond))
+}
+
+func formatName(audioType int) string {
+	switch audioType {
+	case audioduration.TypeMp3:
+		return "mp3"
+	case audioduration.TypeOgg:
+		return "ogg"
+	case audioduration.TypeFlac:
+		return "flac"
+	case audioduration.TypeWav:
+		return "wav"
+	default:
+		return "other"
+	}
+}
+
+func formatCounts(formats map[string]int) string {
+	var parts []string
+	for name, n := range formats {
+		parts = append(parts, fmt.Sprintf("%s=%d", name, n))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, " ")
+}
+
+func sortedValues(m map[string]string) []string {
+	values := make([]string, 0, len(m))
+	for _, v := range m {
+		values = append(values, v)
+	}
+	sort.Strings(values)
+	return values
+}
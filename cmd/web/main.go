@@ -1,19 +1,40 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"spotify-clone/internal/album"
+	"spotify-clone/internal/artist"
 	"spotify-clone/internal/auth"
 	"spotify-clone/internal/config"
 	"spotify-clone/internal/database"
+	"spotify-clone/internal/jobs"
 	"spotify-clone/internal/middleware"
+	"spotify-clone/internal/parental"
+	"spotify-clone/internal/playback"
 	"spotify-clone/internal/ratelimit"
+	"spotify-clone/internal/search"
 	"spotify-clone/internal/song"
+	"spotify-clone/internal/territory"
+	"spotify-clone/internal/upload"
 	"spotify-clone/internal/user"
+	"spotify-clone/pkg/artwork"
+	"spotify-clone/pkg/geoip"
+	"spotify-clone/pkg/hls"
+	"spotify-clone/pkg/tus"
 )
 
 func main() {
@@ -22,6 +43,9 @@ func main() {
 	if err != nil {
 		log.Fatal("Failed to load config:", err)
 	}
+	if err := cfg.ValidateStreamSecret(); err != nil {
+		log.Fatal("Invalid config:", err)
+	}
 
 	// Connect to database
 	db, err := database.Connect(cfg.Database)
@@ -31,6 +55,12 @@ func main() {
 	defer db.Close()
 	log.Println("Connected to database")
 
+	// Initialize file storage (local disk or S3-compatible)
+	blobStorage, err := config.NewStorage(cfg)
+	if err != nil {
+		log.Fatal("Failed to initialize storage:", err)
+	}
+
 	// Initialize JWT service
 	jwtService := auth.NewJWTService(auth.JWTConfig{
 		SecretKey:          cfg.JWT.Secret,
@@ -38,9 +68,64 @@ func main() {
 		RefreshTokenExpiry: cfg.JWT.RefreshTokenExpiry,
 	})
 
+	// Background goroutines run until the HTTP server has shut down, the
+	// play recorder and job worker are waited for so they can save their work
+	background, stopBackground := context.WithCancel(context.Background())
+	var backgroundWG sync.WaitGroup
+
 	// Initialize repositories
 	userRepo := user.NewUserRepository(db)
 	songRepo := song.NewRepository(db)
+	searchRepo := search.NewRepository(db)
+	playbackRepo := playback.NewRepository(db)
+	albumRepo := album.NewRepository(db)
+	artistRepo := artist.NewRepository(db)
+	jobQueue := jobs.NewQueue(db)
+
+	// Build search autocomplete index, kept up to date on song creation
+	suggestIndex := search.NewSuggestIndex(searchRepo)
+	if err := suggestIndex.Load(context.Background()); err != nil {
+		log.Fatal("Failed to build suggestion index:", err)
+	}
+	songRepo.AddObserver(suggestIndex)
+	go suggestIndex.Run(background, cfg.Search.SuggestRefresh)
+
+	// Play counting: events -> tracker -> batched writes to play_history/play_count
+	playRecorder := playback.NewRecorder(playbackRepo, cfg.Playback.BatchSize, cfg.Playback.FlushInterval)
+	backgroundWG.Add(1)
+	go func() {
+		defer backgroundWG.Done()
+		playRecorder.Run(background)
+	}()
+	playTracker := playback.NewTracker(playRecorder.Plays(), cfg.Playback.MaxSessions)
+
+	// Background processing of uploaded songs (duration, tags, lyrics, artwork, waveform, loudness)
+	jobWorker := jobs.NewWorker(jobQueue, cfg.Jobs.Workers, cfg.Jobs.PollInterval, cfg.Jobs.Timeout, cfg.Jobs.Lease)
+	song.NewProcessor(songRepo, blobStorage, jobQueue).Register(jobWorker)
+	backgroundWG.Add(1)
+	go func() {
+		defer backgroundWG.Done()
+		jobWorker.Run(background)
+	}()
+
+	// Scheduled songs and albums go public when their release time has passed
+	releaseScheduler := song.NewScheduler(songRepo)
+	releaseScheduler.AddObserver(suggestIndex)
+	go releaseScheduler.Run(background, cfg.Release.CheckInterval)
+
+	// Listener country for licensing restrictions: profile country, then GeoIP
+	var geoDB *geoip.DB
+	if cfg.GeoIP.DBPath != "" {
+		geoDB, err = geoip.Open(cfg.GeoIP.DBPath)
+		if err != nil {
+			log.Fatal("Failed to load GeoIP database:", err)
+		}
+		log.Printf("Loaded GeoIP database (%d ranges)", geoDB.Len())
+	}
+	territories := territory.NewResolver(userRepo, geoDB)
+
+	// Explicit content is hidden from minors and users who turned it off
+	parentalPolicy := parental.NewPolicy(userRepo, cfg.Parental.ExplicitMinAge)
 
 	// Initialize services
 	authService := auth.NewAuthService(userRepo, jwtService)
@@ -48,18 +133,47 @@ func main() {
 	// Initialize rate limiter: 5 failed attempts = block for 5 minutes
 	loginRateLimiter := ratelimit.NewLoginRateLimiter(5, 5*time.Minute)
 
+	// Initialize stream limiter: concurrent streams per account, by tier
+	streamLimiter := ratelimit.NewStreamLimiter()
+
 	// Initialize handlers
-	authHandler := auth.NewHandler(authService, userRepo, loginRateLimiter)
-	songHandler := song.NewHandler(songRepo)
+	authHandler := auth.NewHandler(authService, userRepo, loginRateLimiter, parentalPolicy)
+	// HLS playlists/segments are generated on demand and cached on disk
+	hlsCache, err := hls.NewCache(cfg.Stream.HLSCacheDir)
+	if err != nil {
+		log.Fatal("Failed to create HLS cache:", err)
+	}
+
+	// Resumable uploads are staged on disk, expired ones are removed hourly
+	uploadStore, err := tus.NewStore(cfg.Upload.Dir, cfg.Upload.Expiry)
+	if err != nil {
+		log.Fatal("Failed to create upload store:", err)
+	}
+	go uploadStore.Run(background, time.Hour)
+
+	songHandler := song.NewHandler(songRepo, userRepo, blobStorage, streamLimiter, cfg.Stream, hlsCache, jobQueue, releaseScheduler, territories, parentalPolicy, playTracker)
+	searchHandler := search.NewHandler(searchRepo, suggestIndex, territories, parentalPolicy)
+	playbackHandler := playback.NewHandler(playbackRepo, playTracker)
+	uploadHandler := upload.NewHandler(uploadStore, song.NewIngestor(songRepo, blobStorage), cfg.Upload.MaxSize)
+	// Album covers and artist images share one artwork store
+	artworkStore := artwork.NewStore(blobStorage)
+	albumHandler := album.NewHandler(albumRepo, artworkStore, releaseScheduler)
+	artistHandler := artist.NewHandler(artistRepo, songRepo, artworkStore, territories, parentalPolicy)
 
 	// Create auth middleware
 	authMiddleware := middleware.AuthMiddleware(jwtService)
+	optionalAuthMiddleware := middleware.OptionalAuthMiddleware(jwtService)
+	adminMiddleware := middleware.AdminMiddleware(cfg.Admin.UserIDs)
 
 	// Setup Gin router
 	r := gin.Default()
+	// Client IP dùng cho GeoIP, giới hạn stream ẩn danh và signed URL, chỉ tin X-Forwarded-For từ proxy đã cấu hình
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES:", err)
+	}
 
-	// Serve static files (for audio streaming)
-	r.Static("/static", "./web/static")
+	// Serve static files. Audio under MUSIC_PATH is only reachable via signed stream URLs
+	r.StaticFS("/static", newStaticFS(cfg.Static.Path, cfg.Static.MusicPath))
 
 	// API routes
 	api := r.Group("/api")
@@ -68,7 +182,22 @@ func main() {
 		auth.RegisterRoutes(api, authHandler, authMiddleware, loginRateLimiter)
 
 		// Song routes: /api/songs/...
-		song.RegisterRoutes(api, songHandler)
+		song.RegisterRoutes(api, songHandler, authMiddleware, optionalAuthMiddleware, adminMiddleware)
+
+		// Search routes: /api/search
+		search.RegisterRoutes(api, searchHandler, optionalAuthMiddleware, adminMiddleware)
+
+		// Playback routes: /api/plays/...
+		playback.RegisterRoutes(api, playbackHandler, authMiddleware)
+
+		// Resumable upload routes (tus 1.0): /api/uploads/...
+		upload.RegisterRoutes(api, uploadHandler, authMiddleware, adminMiddleware)
+
+		// Album routes: /api/albums/:id/cover, /api/albums/:id/release, /api/covers/:name
+		album.RegisterRoutes(api, albumHandler, authMiddleware, adminMiddleware)
+
+		// Artist routes: /api/artists/...
+		artist.RegisterRoutes(api, artistHandler, authMiddleware, optionalAuthMiddleware, adminMiddleware)
 	}
 
 	// Health check
@@ -82,16 +211,94 @@ func main() {
 	log.Println("POST   /api/auth/login       - Login")
 	log.Println("POST   /api/auth/refresh     - Refresh token")
 	log.Println("GET    /api/auth/me          - Get current user (protected)")
+	log.Println("GET    /api/auth/me/explicit - Explicit content setting (protected)")
+	log.Println("PUT    /api/auth/me/explicit - Allow or hide explicit content (protected)")
+	log.Println("GET    /api/songs            - List songs (filter, sort, cursor)")
 	log.Println("GET    /api/songs/:id        - Get song details")
-	log.Println("GET    /api/songs/:id/stream - Stream song audio")
-	log.Println("POST   /api/songs/upload     - Upload new song")
+	log.Println("GET    /api/songs/:id/waveform - Waveform peaks (JSON or .dat)")
+	log.Println("GET    /api/songs/:id/processing - Processing status (uploader/admin)")
+	log.Println("GET    /api/songs/:id/lyrics - Lyrics (JSON lines, or WebVTT with format=vtt)")
+	log.Println("GET    /api/songs/:id/stream-url - Get signed stream URL (protected)")
+	log.Println("GET    /api/songs/:id/stream - Stream song audio (signed URL)")
+	log.Println("GET    /api/songs/:id/hls/index.m3u8 - HLS playlist (signed URL, MP3 only)")
+	log.Println("POST   /api/songs/upload     - Upload new song (protected)")
+	log.Println("POST   /api/uploads/         - Start resumable upload, tus (protected)")
+	log.Println("HEAD   /api/uploads/:id      - Upload offset, tus (protected)")
+	log.Println("PATCH  /api/uploads/:id      - Append chunk, tus (protected)")
+	log.Println("DELETE /api/uploads/:id      - Cancel upload, tus (protected)")
+	log.Println("PATCH  /api/songs/:id        - Update song (uploader/admin)")
+	log.Println("DELETE /api/songs/:id        - Delete song (uploader/admin)")
+	log.Println("PUT    /api/songs/:id/lyrics - Upload plain or LRC lyrics (uploader/admin)")
+	log.Println("DELETE /api/songs/:id/lyrics - Delete lyrics of a language (uploader/admin)")
+	log.Println("GET    /api/search           - Search songs, artists, albums, playlists")
+	log.Println("GET    /api/search/suggest   - Autocomplete suggestions")
+	log.Println("POST   /api/plays/events     - Report player events (protected)")
+	log.Println("GET    /api/plays/history    - Listening history (protected)")
+	log.Println("POST   /api/albums/:id/cover - Upload album cover (owner/admin)")
+	log.Println("PUT    /api/albums/:id/release - Schedule, publish or hide an album (owner/admin)")
+	log.Println("PUT    /api/albums/:id/territories - Countries an album is licensed in (owner/admin)")
+	log.Println("GET    /api/artists          - List artists (name prefix, sort)")
+	log.Println("GET    /api/artists/:id      - Artist with profile and followers")
+	log.Println("GET    /api/artists/:id/albums - Albums of the artist and albums it appears on")
+	log.Println("GET    /api/artists/:id/songs - Songs crediting the artist (cursor)")
+	log.Println("GET    /api/artists/:id/top-tracks - Most played songs of the artist")
+	log.Println("POST   /api/artists          - Create artist (admin)")
+	log.Println("PATCH  /api/artists/:id      - Update artist and profile (owner/admin)")
+	log.Println("DELETE /api/artists/:id      - Delete artist (admin)")
+	log.Println("POST   /api/artists/:id/image - Upload artist image (owner/admin)")
+	log.Println("GET    /api/covers/:name     - Cover image (?size=64|300|640|original)")
 	log.Println("GET    /health               - Health check")
 	log.Println("========================")
 
 	// Start server
 	addr := fmt.Sprintf(":%s", cfg.Port)
 	log.Printf("Server starting on http://localhost%s", addr)
-	if err := r.Run(addr); err != nil {
-		log.Fatal("Server failed:", err)
+	srv := &http.Server{Addr: addr, Handler: r}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("Server failed:", err)
+		}
+	}()
+
+	// Tắt êm khi nhận SIGINT/SIGTERM: ngừng nhận request, đợi request đang chạy,
+	// rồi dừng background và ghi nốt lượt nghe còn trong hàng đợi
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	<-quit
+	log.Println("Shutting down server...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Println("Server shutdown:", err)
+	}
+	stopBackground()
+	backgroundWG.Wait()
+	if n := playTracker.Dropped(); n > 0 {
+		log.Printf("%d plays were dropped while the recorder was overloaded", n)
+	}
+	log.Println("Server stopped")
+}
+
+// hiddenDirFS serves files from fs except those under the hidden directory
+type hiddenDirFS struct {
+	fs     http.FileSystem
+	hidden string // slash-separated, rooted path, e.g. "/music"
+}
+
+func (h hiddenDirFS) Open(name string) (http.File, error) {
+	if h.hidden != "" && (name == h.hidden || strings.HasPrefix(name, h.hidden+"/")) {
+		return nil, os.ErrNotExist
+	}
+	return h.fs.Open(name)
+}
+
+// newStaticFS serves staticPath without exposing musicPath when it lives inside it
+func newStaticFS(staticPath, musicPath string) http.FileSystem {
+	fs := hiddenDirFS{fs: gin.Dir(staticPath, false)}
+	rel, err := filepath.Rel(staticPath, musicPath)
+	if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		fs.hidden = "/" + filepath.ToSlash(rel)
 	}
+	return fs
 }
@@ -0,0 +1,22 @@
+package album
+
+import "time"
+
+// CoverUploadResponse is returned after uploading an album cover
+type CoverUploadResponse struct {
+	AlbumID  string            `json:"album_id"`
+	CoverURL string            `json:"cover_url"`
+	Variants map[string]string `json:"variants"` // size -> URL
+}
+
+// CoverRequest selects the variant of a cover image
+type CoverRequest struct {
+	Size string `form:"size" binding:"omitempty,oneof=64 300 640 original"`
+}
+
+// AlbumReleaseRequest is the body of PUT /albums/:id/release. A future
+// release_at schedules the album and its songs; public or private apply now.
+type AlbumReleaseRequest struct {
+	Visibility string     `json:"visibility" binding:"omitempty,oneof=public scheduled private"`
+	ReleaseAt  *time.Time `json:"release_at"`
+}
@@ -0,0 +1,251 @@
+package album
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"spotify-clone/internal/middleware"
+	"spotify-clone/internal/release"
+	"spotify-clone/internal/territory"
+	"spotify-clone/pkg/artwork"
+	"spotify-clone/pkg/storage"
+)
+
+// maxCoverSize is the largest accepted cover upload
+const maxCoverSize = 10 << 20
+
+// defaultCoverSize is served when the client does not pick a variant
+const defaultCoverSize = "640"
+
+// Releaser schedules an album and its songs (implemented by the song
+// release scheduler, which owns the songs)
+type Releaser interface {
+	SetAlbumRelease(ctx context.Context, albumID, visibility string, releaseAt *time.Time) error
+}
+
+// Handler handles HTTP requests for albums
+type Handler struct {
+	repo     *Repository
+	artwork  *artwork.Store
+	releaser Releaser
+}
+
+// NewHandler creates a new album handler
+func NewHandler(repo *Repository, artworkStore *artwork.Store, releaser Releaser) *Handler {
+	return &Handler{repo: repo, artwork: artworkStore, releaser: releaser}
+}
+
+// canManage reports whether the current user may change the album's cover,
+// schedule or territories: its owner or an admin
+func canManage(c *gin.Context, a *Album) bool {
+	if middleware.IsAdmin(c) {
+		return true
+	}
+	userID, ok := middleware.GetUserID(c)
+	return ok && a.OwnedBy != "" && a.OwnedBy == userID
+}
+
+// UploadCover replaces the cover of an album. Allowed for admins and the
+// album's owner.
+func (h *Handler) UploadCover(c *gin.Context) {
+	albumID := c.Param("id")
+
+	a, err := h.repo.GetByID(c.Request.Context(), albumID)
+	if err != nil {
+		if errors.Is(err, ErrAlbumNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Album not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load album"})
+		return
+	}
+	if !canManage(c, a) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins or the album's owner can change its cover"})
+		return
+	}
+
+	fileHeader, err := c.FormFile("cover")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cover image is required"})
+		return
+	}
+	if fileHeader.Size > maxCoverSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Cover image must be at most 10MB"})
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Cannot read file"})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxCoverSize))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Cannot read file"})
+		return
+	}
+
+	// Lưu ảnh gốc + các variant 64/300/640
+	name, err := h.artwork.Put(c.Request.Context(), data)
+	if err != nil {
+		switch {
+		case errors.Is(err, artwork.ErrUnsupportedImage):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid image. Allowed: JPEG, PNG, GIF"})
+		case errors.Is(err, artwork.ErrImageTooLarge):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Image dimensions are too large"})
+		default:
+			log.Println("artwork:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store cover"})
+		}
+		return
+	}
+
+	coverURL := CoverURL(name)
+	if err := h.repo.SetCoverURL(c.Request.Context(), albumID, coverURL); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update album"})
+		return
+	}
+
+	variants := map[string]string{artwork.VariantOriginal: coverURL + "?size=" + artwork.VariantOriginal}
+	for _, size := range artwork.Sizes {
+		s := strconv.Itoa(size)
+		variants[s] = coverURL + "?size=" + s
+	}
+	c.JSON(http.StatusOK, CoverUploadResponse{
+		AlbumID:  albumID,
+		CoverURL: coverURL,
+		Variants: variants,
+	})
+}
+
+// SetRelease schedules, publishes or hides an album together with its
+// songs. Allowed for the album's owner and admins.
+func (h *Handler) SetRelease(c *gin.Context) {
+	albumID := c.Param("id")
+
+	var req AlbumReleaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	a, err := h.repo.GetByID(c.Request.Context(), albumID)
+	if err != nil {
+		if errors.Is(err, ErrAlbumNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Album not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load album"})
+		return
+	}
+	if !canManage(c, a) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the owner of this album or an admin can change its release"})
+		return
+	}
+
+	if err := h.releaser.SetAlbumRelease(c.Request.Context(), albumID, req.Visibility, req.ReleaseAt); err != nil {
+		switch {
+		case errors.Is(err, release.ErrInvalid):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, ErrAlbumNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Album not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update album release"})
+		}
+		return
+	}
+
+	updated, err := h.repo.GetByID(c.Request.Context(), albumID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load updated album"})
+		return
+	}
+	c.JSON(http.StatusOK, updated)
+}
+
+// SetTerritories replaces the countries an album and its songs are licensed
+// in. Allowed for the album's owner and admins.
+func (h *Handler) SetTerritories(c *gin.Context) {
+	albumID := c.Param("id")
+
+	var req territory.Rules
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	rules, err := territory.NormalizeRules(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	a, err := h.repo.GetByID(c.Request.Context(), albumID)
+	if err != nil {
+		if errors.Is(err, ErrAlbumNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Album not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load album"})
+		return
+	}
+	if !canManage(c, a) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the owner of this album or an admin can change its territories"})
+		return
+	}
+
+	if err := h.repo.SetTerritories(c.Request.Context(), albumID, rules); err != nil {
+		if errors.Is(err, ErrAlbumNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Album not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update album territories"})
+		return
+	}
+
+	updated, err := h.repo.GetByID(c.Request.Context(), albumID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load updated album"})
+		return
+	}
+	c.JSON(http.StatusOK, updated)
+}
+
+// GetCover serves a cover image variant. Images are content addressed so
+// responses are cached forever.
+func (h *Handler) GetCover(c *gin.Context) {
+	var req CoverRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query: " + err.Error()})
+		return
+	}
+	if req.Size == "" {
+		req.Size = defaultCoverSize
+	}
+
+	name := c.Param("name")
+	obj, info, err := h.artwork.Open(c.Request.Context(), name, req.Size)
+	if err != nil {
+		if errors.Is(err, artwork.ErrInvalidName) || errors.Is(err, storage.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Cover not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Cannot read cover"})
+		return
+	}
+	defer obj.Close()
+
+	digest, _, _ := artwork.ParseName(name)
+	c.Header("Content-Type", info.ContentType)
+	c.Header("ETag", `"`+digest+"-"+req.Size+`"`)
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+
+	http.ServeContent(c.Writer, c.Request, name, info.LastModified, obj)
+}
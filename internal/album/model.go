@@ -0,0 +1,29 @@
+package album
+
+import (
+	"time"
+
+	"spotify-clone/internal/territory"
+)
+
+// Album represents an album
+type Album struct {
+	ID          string     `json:"id"`
+	ArtistID    string     `json:"artist_id,omitempty"`
+	OwnedBy     string     `json:"owned_by,omitempty"` // user managing the album, "" = admins only
+	Title       string     `json:"title"`
+	CoverURL    string     `json:"cover_url,omitempty"`
+	ReleaseDate *time.Time `json:"release_date,omitempty"`
+	AlbumType   string     `json:"album_type"`
+	Visibility  string     `json:"visibility"` // release.Public, Scheduled or Private
+	ReleaseAt   *time.Time `json:"release_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+
+	Territories territory.Rules `json:"territories"` // countries the album is licensed in
+}
+
+// CoverURL returns the public URL of a stored cover image.
+// Clients pick a variant with ?size=64|300|640|original.
+func CoverURL(name string) string {
+	return "/api/covers/" + name
+}
@@ -0,0 +1,74 @@
+package album
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"spotify-clone/internal/territory"
+)
+
+var (
+	// ErrAlbumNotFound is returned when an album does not exist
+	ErrAlbumNotFound = errors.New("album not found")
+	// ErrAlbumNotOwned is returned when a user adds a song to an album they
+	// do not own
+	ErrAlbumNotOwned = errors.New("album belongs to another user")
+)
+
+type Repository struct {
+	db *pgxpool.Pool
+}
+
+func NewRepository(db *pgxpool.Pool) *Repository {
+	return &Repository{db: db}
+}
+
+// GetByID returns an album by ID
+func (r *Repository) GetByID(ctx context.Context, id string) (*Album, error) {
+	var a Album
+	err := r.db.QueryRow(ctx, `
+		SELECT id::text, COALESCE(artist_id::text, ''), COALESCE(owned_by::text, ''), title, COALESCE(cover_url, ''),
+		       release_date, COALESCE(album_type, 'album'), visibility, release_at, created_at,
+		       territory_allow, territory_deny
+		FROM albums
+		WHERE id = $1
+	`, id).Scan(&a.ID, &a.ArtistID, &a.OwnedBy, &a.Title, &a.CoverURL, &a.ReleaseDate, &a.AlbumType, &a.Visibility, &a.ReleaseAt, &a.CreatedAt,
+		&a.Territories.Allow, &a.Territories.Deny)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAlbumNotFound
+		}
+		return nil, fmt.Errorf("error querying album: %w", err)
+	}
+	return &a, nil
+}
+
+// SetCoverURL replaces the cover of an album
+func (r *Repository) SetCoverURL(ctx context.Context, id, coverURL string) error {
+	tag, err := r.db.Exec(ctx, `UPDATE albums SET cover_url = $2 WHERE id = $1`, id, coverURL)
+	if err != nil {
+		return fmt.Errorf("error updating album cover: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrAlbumNotFound
+	}
+	return nil
+}
+
+// SetTerritories replaces the countries an album is licensed in
+func (r *Repository) SetTerritories(ctx context.Context, id string, rules territory.Rules) error {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE albums SET territory_allow = $2, territory_deny = $3 WHERE id = $1
+	`, id, rules.Allow, rules.Deny)
+	if err != nil {
+		return fmt.Errorf("error updating album territories: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrAlbumNotFound
+	}
+	return nil
+}
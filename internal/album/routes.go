@@ -0,0 +1,19 @@
+package album
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRoutes registers album and cover routes to the given router group
+func RegisterRoutes(rg *gin.RouterGroup, h *Handler, authMiddleware, adminMiddleware gin.HandlerFunc) {
+	albumGroup := rg.Group("/albums")
+	{
+		// Protected - admins or uploaders of a song on the album
+		albumGroup.POST("/:id/cover", authMiddleware, adminMiddleware, h.UploadCover)
+		albumGroup.PUT("/:id/release", authMiddleware, adminMiddleware, h.SetRelease)
+		albumGroup.PUT("/:id/territories", authMiddleware, adminMiddleware, h.SetTerritories)
+	}
+
+	// Public - cover images (content addressed, cached forever)
+	rg.GET("/covers/:name", h.GetCover)
+}
@@ -0,0 +1,85 @@
+package artist
+
+import "spotify-clone/internal/song"
+
+// ListArtistsRequest filters and pages GET /artists
+type ListArtistsRequest struct {
+	Q      string `form:"q" binding:"max=100"` // name prefix
+	Sort   string `form:"sort" binding:"omitempty,oneof=name followers newest"`
+	Limit  int    `form:"limit" binding:"min=0,max=100"`
+	Offset int    `form:"offset" binding:"min=0"`
+}
+
+// ListArtistsResponse is a page of artists
+type ListArtistsResponse struct {
+	Artists []Artist `json:"artists"`
+	Limit   int      `json:"limit"`
+	Offset  int      `json:"offset"`
+	HasMore bool     `json:"has_more"`
+}
+
+// ProfileRequest holds the profile fields of create and update requests,
+// omitted fields are unchanged and "" clears a field
+type ProfileRequest struct {
+	FullName *string `json:"full_name" binding:"omitempty,max=255"`
+	Sex      *string `json:"sex" binding:"omitempty,max=10"`
+	Birthday *string `json:"birthday" binding:"omitempty,datetime=2006-01-02"`
+	Country  *string `json:"country" binding:"omitempty,max=100"`
+	Verified *bool   `json:"verified"` // admins only
+}
+
+// input converts the request for the repository
+func (r ProfileRequest) input() ProfileInput {
+	return ProfileInput{
+		FullName: r.FullName,
+		Sex:      r.Sex,
+		Birthday: r.Birthday,
+		Country:  r.Country,
+		Verified: r.Verified,
+	}
+}
+
+// CreateArtistRequest is the body of POST /artists (admins only)
+type CreateArtistRequest struct {
+	Name    string  `json:"name" binding:"required,max=255"`
+	OwnerID *string `json:"owner_id" binding:"omitempty,uuid"` // user claiming the artist
+	ProfileRequest
+}
+
+// UpdateArtistRequest is the body of PATCH /artists/:id, omitted fields are unchanged
+type UpdateArtistRequest struct {
+	Name    *string `json:"name" binding:"omitempty,min=1,max=255"`
+	OwnerID *string `json:"owner_id" binding:"omitempty,uuid|len=0"` // admins only, "" removes the owner
+	ProfileRequest
+}
+
+// ImageUploadResponse is returned after uploading an artist image
+type ImageUploadResponse struct {
+	ArtistID  string            `json:"artist_id"`
+	AvatarURL string            `json:"avatar_url"`
+	Variants  map[string]string `json:"variants"` // size -> URL
+}
+
+// AlbumsResponse is the album discography of an artist
+type AlbumsResponse struct {
+	ArtistID string             `json:"artist_id"`
+	Albums   []DiscographyAlbum `json:"albums"`
+}
+
+// ListSongsRequest pages the songs crediting an artist
+type ListSongsRequest struct {
+	Sort   string `form:"sort" binding:"omitempty,oneof=newest most_played title"`
+	Cursor string `form:"cursor"`
+	Limit  int    `form:"limit" binding:"min=0,max=100"`
+}
+
+// TopTracksRequest limits GET /artists/:id/top-tracks
+type TopTracksRequest struct {
+	Limit int `form:"limit" binding:"min=0,max=50"`
+}
+
+// TopTracksResponse is the most played songs of an artist
+type TopTracksResponse struct {
+	ArtistID string      `json:"artist_id"`
+	Tracks   []song.Song `json:"tracks"`
+}
@@ -0,0 +1,403 @@
+package artist
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"spotify-clone/internal/album"
+	"spotify-clone/internal/middleware"
+	"spotify-clone/internal/parental"
+	"spotify-clone/internal/song"
+	"spotify-clone/internal/territory"
+	"spotify-clone/pkg/artwork"
+)
+
+// maxImageSize is the largest accepted artist image upload
+const maxImageSize = 10 << 20
+
+// Songs lists songs crediting an artist (implemented by the song
+// repository, which owns songs and their credits)
+type Songs interface {
+	ListSongs(ctx context.Context, filter song.ListSongsFilter) ([]song.Song, *song.SongCursor, error)
+}
+
+// Handler handles HTTP requests for artists
+type Handler struct {
+	repo        *Repository
+	songs       Songs
+	artwork     *artwork.Store
+	territories *territory.Resolver
+	parental    *parental.Policy
+}
+
+// NewHandler creates a new artist handler
+func NewHandler(repo *Repository, songs Songs, artworkStore *artwork.Store, territories *territory.Resolver, policy *parental.Policy) *Handler {
+	return &Handler{repo: repo, songs: songs, artwork: artworkStore, territories: territories, parental: policy}
+}
+
+// loadArtist returns the artist of the :id parameter, writing 404 or 500 on failure
+func (h *Handler) loadArtist(c *gin.Context) (*Artist, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Artist not found"})
+		return nil, false
+	}
+	a, err := h.repo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, ErrArtistNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Artist not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load artist"})
+		return nil, false
+	}
+	return a, true
+}
+
+// canEdit reports whether the current user may change the artist: admins
+// and the user who claimed it. Crediting an artist on an upload grants no
+// rights on it.
+func canEdit(c *gin.Context, a *Artist) bool {
+	if middleware.IsAdmin(c) {
+		return true
+	}
+	userID, _ := middleware.GetUserID(c)
+	return a.OwnedBy != "" && a.OwnedBy == userID
+}
+
+// writeError writes the response of a failed create or update
+func writeError(c *gin.Context, err error, action string) {
+	switch {
+	case errors.Is(err, ErrArtistNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Artist not found"})
+	case errors.Is(err, ErrOwnerNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Owner not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to " + action + " artist"})
+	}
+}
+
+// viewer returns the current user with their country, which is only
+// needed for non-admins
+func (h *Handler) viewer(c *gin.Context) Viewer {
+	v := Viewer{IsAdmin: middleware.IsAdmin(c)}
+	v.UserID, _ = middleware.GetUserID(c)
+	if !v.IsAdmin {
+		v.Country = h.territories.Country(c.Request.Context(), v.UserID, c.ClientIP())
+	}
+	return v
+}
+
+// songFilter returns the song filter of an artist for the current user:
+// unreleased, unlicensed and (if not allowed) explicit songs are left out
+func (h *Handler) songFilter(c *gin.Context, artistID uuid.UUID) song.ListSongsFilter {
+	v := h.viewer(c)
+	return song.ListSongsFilter{
+		ArtistID:      artistID.String(),
+		ViewerID:      v.UserID,
+		ViewerIsAdmin: v.IsAdmin,
+		Country:       v.Country,
+		HideExplicit:  !h.parental.AllowExplicit(c.Request.Context(), v.UserID),
+	}
+}
+
+// ListArtists returns a page of artists, optionally filtered by name prefix
+func (h *Handler) ListArtists(c *gin.Context) {
+	var req ListArtistsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query: " + err.Error()})
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = 20
+	}
+
+	artists, hasMore, err := h.repo.List(c.Request.Context(), ListArtistsFilter{
+		Query:  req.Q,
+		Sort:   ArtistSort(req.Sort),
+		Limit:  req.Limit,
+		Offset: req.Offset,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list artists"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ListArtistsResponse{
+		Artists: artists,
+		Limit:   req.Limit,
+		Offset:  req.Offset,
+		HasMore: hasMore,
+	})
+}
+
+// GetArtist returns an artist with its profile and follower count
+func (h *Handler) GetArtist(c *gin.Context) {
+	a, ok := h.loadArtist(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, a)
+}
+
+// CreateArtist adds an artist, optionally claimed by a user. Admins only.
+func (h *Handler) CreateArtist(c *gin.Context) {
+	if !middleware.IsAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can create artists"})
+		return
+	}
+	var req CreateArtistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	id, err := h.repo.Create(c.Request.Context(), CreateArtistInput{
+		Name:    req.Name,
+		OwnerID: req.OwnerID,
+		Profile: req.ProfileRequest.input(),
+	})
+	if err != nil {
+		writeError(c, err, "create")
+		return
+	}
+
+	created, err := h.repo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load created artist"})
+		return
+	}
+	c.JSON(http.StatusCreated, created)
+}
+
+// UpdateArtist changes the name and profile of an artist. Allowed for
+// admins and the artist's owner; only admins can verify or assign artists.
+func (h *Handler) UpdateArtist(c *gin.Context) {
+	var req UpdateArtistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if req.Verified != nil && !middleware.IsAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can verify artists"})
+		return
+	}
+	if req.OwnerID != nil && !middleware.IsAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can change the owner of an artist"})
+		return
+	}
+
+	a, ok := h.loadArtist(c)
+	if !ok {
+		return
+	}
+	if !canEdit(c, a) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins or the artist's owner can edit it"})
+		return
+	}
+
+	err := h.repo.Update(c.Request.Context(), a.ID, UpdateArtistInput{
+		Name:    req.Name,
+		OwnerID: req.OwnerID,
+		Profile: req.ProfileRequest.input(),
+	})
+	if err != nil {
+		writeError(c, err, "update")
+		return
+	}
+
+	updated, err := h.repo.GetByID(c.Request.Context(), a.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load updated artist"})
+		return
+	}
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeleteArtist removes an artist with its albums and credits. Admins only.
+func (h *Handler) DeleteArtist(c *gin.Context) {
+	if !middleware.IsAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can delete artists"})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Artist not found"})
+		return
+	}
+
+	if err := h.repo.Delete(c.Request.Context(), id); err != nil {
+		if errors.Is(err, ErrArtistNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Artist not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete artist"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// UploadImage replaces the image of an artist. Allowed for admins and the
+// artist's owner.
+func (h *Handler) UploadImage(c *gin.Context) {
+	a, ok := h.loadArtist(c)
+	if !ok {
+		return
+	}
+	if !canEdit(c, a) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins or the artist's owner can change its image"})
+		return
+	}
+
+	fileHeader, err := c.FormFile("image")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Image is required"})
+		return
+	}
+	if fileHeader.Size > maxImageSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Image must be at most 10MB"})
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Cannot read file"})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxImageSize))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Cannot read file"})
+		return
+	}
+
+	// Dùng chung artwork store với cover album: ảnh gốc + các variant 64/300/640
+	name, err := h.artwork.Put(c.Request.Context(), data)
+	if err != nil {
+		switch {
+		case errors.Is(err, artwork.ErrUnsupportedImage):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid image. Allowed: JPEG, PNG, GIF"})
+		case errors.Is(err, artwork.ErrImageTooLarge):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Image dimensions are too large"})
+		default:
+			log.Println("artwork:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store image"})
+		}
+		return
+	}
+
+	avatarURL := album.CoverURL(name)
+	err = h.repo.Update(c.Request.Context(), a.ID, UpdateArtistInput{Profile: ProfileInput{AvatarURL: &avatarURL}})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update artist"})
+		return
+	}
+
+	variants := map[string]string{artwork.VariantOriginal: avatarURL + "?size=" + artwork.VariantOriginal}
+	for _, size := range artwork.Sizes {
+		s := strconv.Itoa(size)
+		variants[s] = avatarURL + "?size=" + s
+	}
+	c.JSON(http.StatusOK, ImageUploadResponse{
+		ArtistID:  a.ID.String(),
+		AvatarURL: avatarURL,
+		Variants:  variants,
+	})
+}
+
+// GetAlbums returns the albums of an artist and the albums it appears on
+func (h *Handler) GetAlbums(c *gin.Context) {
+	a, ok := h.loadArtist(c)
+	if !ok {
+		return
+	}
+
+	albums, err := h.repo.ListAlbums(c.Request.Context(), a.ID, h.viewer(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list albums"})
+		return
+	}
+	c.JSON(http.StatusOK, AlbumsResponse{ArtistID: a.ID.String(), Albums: albums})
+}
+
+// GetSongs returns a page of the songs crediting an artist, in any role
+func (h *Handler) GetSongs(c *gin.Context) {
+	var req ListSongsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query: " + err.Error()})
+		return
+	}
+	a, ok := h.loadArtist(c)
+	if !ok {
+		return
+	}
+
+	filter := h.songFilter(c, a.ID)
+	filter.Sort = song.SongSort(req.Sort)
+	if filter.Sort == "" {
+		filter.Sort = song.SortNewest
+	}
+	filter.Limit = req.Limit
+	if filter.Limit == 0 {
+		filter.Limit = 20
+	}
+	if req.Cursor != "" {
+		cursor, err := song.DecodeCursor(req.Cursor, filter.Sort)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
+		filter.Cursor = cursor
+	}
+
+	songs, next, err := h.songs.ListSongs(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list songs"})
+		return
+	}
+
+	resp := song.ListSongsResponse{Songs: songs}
+	if resp.Songs == nil {
+		resp.Songs = []song.Song{}
+	}
+	if next != nil {
+		resp.NextCursor = song.EncodeCursor(*next)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetTopTracks returns the most played songs crediting an artist
+func (h *Handler) GetTopTracks(c *gin.Context) {
+	var req TopTracksRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query: " + err.Error()})
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = 10
+	}
+	a, ok := h.loadArtist(c)
+	if !ok {
+		return
+	}
+
+	filter := h.songFilter(c, a.ID)
+	filter.Sort = song.SortMostPlayed
+	filter.Limit = req.Limit
+	songs, _, err := h.songs.ListSongs(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list top tracks"})
+		return
+	}
+	if songs == nil {
+		songs = []song.Song{}
+	}
+	c.JSON(http.StatusOK, TopTracksResponse{ArtistID: a.ID.String(), Tracks: songs})
+}
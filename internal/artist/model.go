@@ -12,14 +12,109 @@ type Artist struct {
 	Name      string    `json:"name"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+
+	OwnedBy   string         `json:"owned_by,omitempty"` // user who claimed the artist, "" if none
+	Followers int            `json:"followers"`          // users following the artist
+	Profile   *ArtistProfile `json:"profile,omitempty"`  // nil if the artist has none
 }
 
 // Profile info for artist
 type ArtistProfile struct {
-	ArtistID         uuid.UUID `json:"artist_id"`
-	Bio              string    `json:"bio"`
-	ImageURL         string    `json:"image_url"`
-	MonthlyListeners int       `json:"monthly_listeners"`
-	Country          string    `json:"country"`
-	Verified         bool      `json:"verified"`
+	ArtistID  uuid.UUID  `json:"artist_id"`
+	FullName  string     `json:"full_name,omitempty"`
+	AvatarURL string     `json:"avatar_url,omitempty"`
+	Sex       string     `json:"sex,omitempty"`
+	Birthday  *time.Time `json:"birthday,omitempty"`
+	Country   string     `json:"country,omitempty"`
+	Verified  bool       `json:"verified"`
+}
+
+// Relations of an album to an artist in a discography
+const (
+	RelationMain      = "main"       // the album's artist
+	RelationAppearsOn = "appears_on" // credited on a song of the album
+)
+
+// DiscographyAlbum is an album of an artist's discography
+type DiscographyAlbum struct {
+	ID          string     `json:"id"`
+	Title       string     `json:"title"`
+	CoverURL    string     `json:"cover_url,omitempty"`
+	ReleaseDate *time.Time `json:"release_date,omitempty"`
+	AlbumType   string     `json:"album_type"`
+	Relation    string     `json:"relation"` // RelationMain or RelationAppearsOn
+	SongCount   int        `json:"song_count"`
+}
+
+// ArtistSort is the ordering used when listing artists
+type ArtistSort string
+
+const (
+	SortName      ArtistSort = "name"
+	SortFollowers ArtistSort = "followers"
+	SortNewest    ArtistSort = "newest"
+)
+
+// ListArtistsFilter holds filters, ordering and pagination for listing artists
+type ListArtistsFilter struct {
+	Query  string // name prefix, accent and case insensitive
+	Sort   ArtistSort
+	Limit  int
+	Offset int
+}
+
+// CreateArtistInput is a new artist, a profile is created if any of its
+// fields is set
+type CreateArtistInput struct {
+	Name    string
+	OwnerID *string // user claiming the artist, nil for none
+	Profile ProfileInput
+}
+
+// ProfileInput holds the profile fields to set, nil means unchanged and ""
+// clears a field
+type ProfileInput struct {
+	FullName  *string
+	AvatarURL *string // set by image uploads
+	Sex       *string
+	Birthday  *string // YYYY-MM-DD
+	Country   *string
+	Verified  *bool
+}
+
+// empty reports whether no field is set
+func (p ProfileInput) empty() bool {
+	return p.FullName == nil && p.AvatarURL == nil && p.Sex == nil && p.Birthday == nil && p.Country == nil && p.Verified == nil
+}
+
+// UpdateArtistInput holds the fields to change, nil means unchanged
+type UpdateArtistInput struct {
+	Name    *string
+	OwnerID *string // "" removes the owner
+	Profile ProfileInput
+}
+
+// Viewer is the user browsing a discography. Unreleased albums are only
+// listed for uploaders of their songs and admins, albums not licensed in
+// Country only for admins.
+type Viewer struct {
+	UserID  string // "" for anonymous users
+	IsAdmin bool
+	Country string // "" when unknown
+}
+
+// id returns the user ID as a query parameter, NULL for anonymous users
+func (v Viewer) id() *string {
+	if v.UserID == "" {
+		return nil
+	}
+	return &v.UserID
+}
+
+// country returns the country as a query parameter, NULL when unknown
+func (v Viewer) country() *string {
+	if v.Country == "" {
+		return nil
+	}
+	return &v.Country
 }
@@ -0,0 +1,294 @@
+package artist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"spotify-clone/internal/territory"
+)
+
+// ErrArtistNotFound is returned when an artist does not exist
+var ErrArtistNotFound = errors.New("artist not found")
+
+// ErrOwnerNotFound is returned when the owner set on an artist is not a user
+var ErrOwnerNotFound = errors.New("owner not found")
+
+// ownerError maps a foreign key violation on owned_by to ErrOwnerNotFound
+func ownerError(err error) error {
+	var pgErr *pgconn.PgError
+	// PostgreSQL error code 23503 = foreign_key_violation
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		return ErrOwnerNotFound
+	}
+	return err
+}
+
+type Repository struct {
+	db *pgxpool.Pool
+}
+
+func NewRepository(db *pgxpool.Pool) *Repository {
+	return &Repository{db: db}
+}
+
+// artistColumns are the columns read by scanArtist, artists aliased as ar
+// and artist_profiles as ap
+const artistColumns = `
+	ar.id, ar.name, ar.created_at, COALESCE(ar.updated_at, ar.created_at), COALESCE(ar.owned_by::text, ''),
+	(SELECT count(*) FROM followed_artists fa WHERE fa.artist_id = ar.id) AS followers,
+	ap.artist_id IS NOT NULL, COALESCE(ap.full_name, ''), COALESCE(ap.avatar_url, ''),
+	COALESCE(ap.sex, ''), ap.birthday, COALESCE(ap.country, ''), COALESCE(ap.verified, FALSE)`
+
+const artistFrom = `artists ar LEFT JOIN artist_profiles ap ON ap.artist_id = ar.id`
+
+// scanArtist scans a row selected with artistColumns
+func scanArtist(row pgx.Row) (*Artist, error) {
+	var a Artist
+	var p ArtistProfile
+	var hasProfile bool
+	err := row.Scan(&a.ID, &a.Name, &a.CreatedAt, &a.UpdatedAt, &a.OwnedBy, &a.Followers,
+		&hasProfile, &p.FullName, &p.AvatarURL, &p.Sex, &p.Birthday, &p.Country, &p.Verified)
+	if err != nil {
+		return nil, err
+	}
+	if hasProfile {
+		p.ArtistID = a.ID
+		a.Profile = &p
+	}
+	return &a, nil
+}
+
+// GetByID returns an artist with its profile and follower count
+func (r *Repository) GetByID(ctx context.Context, id uuid.UUID) (*Artist, error) {
+	a, err := scanArtist(r.db.QueryRow(ctx, `SELECT `+artistColumns+` FROM `+artistFrom+` WHERE ar.id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrArtistNotFound
+		}
+		return nil, fmt.Errorf("error querying artist: %w", err)
+	}
+	return a, nil
+}
+
+// List returns a page of artists matching the filter; hasMore is true when
+// there are artists after the page
+func (r *Repository) List(ctx context.Context, filter ListArtistsFilter) (artists []Artist, hasMore bool, err error) {
+	var conditions []string
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.Query != "" {
+		// Escape ký tự đặc biệt của LIKE trong từ khóa
+		prefix := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(filter.Query)
+		conditions = append(conditions, "search_normalize(ar.name) LIKE search_normalize("+arg(prefix)+") || '%'")
+	}
+
+	query := `SELECT ` + artistColumns + ` FROM ` + artistFrom
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	switch filter.Sort {
+	case SortFollowers:
+		query += " ORDER BY followers DESC, ar.name, ar.id"
+	case SortNewest:
+		query += " ORDER BY ar.created_at DESC, ar.id"
+	default:
+		query += " ORDER BY ar.name, ar.id"
+	}
+	// Lấy thêm 1 dòng để biết còn trang sau không
+	query += " LIMIT " + arg(filter.Limit+1) + " OFFSET " + arg(filter.Offset)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, false, fmt.Errorf("error listing artists: %w", err)
+	}
+	defer rows.Close()
+
+	artists = []Artist{}
+	for rows.Next() {
+		a, err := scanArtist(rows)
+		if err != nil {
+			return nil, false, fmt.Errorf("error scanning artist: %w", err)
+		}
+		artists = append(artists, *a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, fmt.Errorf("error listing artists: %w", err)
+	}
+
+	if len(artists) > filter.Limit {
+		return artists[:filter.Limit], true, nil
+	}
+	return artists, false, nil
+}
+
+// Create inserts an artist, with a profile if any profile field is set
+func (r *Repository) Create(ctx context.Context, input CreateArtistInput) (uuid.UUID, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	id := uuid.New()
+	now := time.Now()
+	_, err = tx.Exec(ctx, `
+		INSERT INTO artists (id, name, owned_by, created_at, updated_at) VALUES ($1, $2, $3, $4, $4)
+	`, id, input.Name, input.OwnerID, now)
+	if err != nil {
+		if err = ownerError(err); errors.Is(err, ErrOwnerNotFound) {
+			return uuid.Nil, err
+		}
+		return uuid.Nil, fmt.Errorf("error inserting artist: %w", err)
+	}
+	if !input.Profile.empty() {
+		if err = upsertProfile(ctx, tx, id, input.Profile); err != nil {
+			return uuid.Nil, err
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return uuid.Nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return id, nil
+}
+
+// Update changes the name and profile of an artist, creating the profile
+// if the artist has none
+func (r *Repository) Update(ctx context.Context, id uuid.UUID, input UpdateArtistInput) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Luôn chạy để kiểm tra artist tồn tại và cập nhật updated_at
+	tag, err := tx.Exec(ctx, `
+		UPDATE artists SET
+			name = COALESCE($2, name),
+			owned_by = CASE WHEN $3::text IS NULL THEN owned_by ELSE NULLIF($3::text, '')::uuid END,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`, id, input.Name, input.OwnerID)
+	if err != nil {
+		if err = ownerError(err); errors.Is(err, ErrOwnerNotFound) {
+			return err
+		}
+		return fmt.Errorf("error updating artist: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrArtistNotFound
+	}
+	if !input.Profile.empty() {
+		if err = upsertProfile(ctx, tx, id, input.Profile); err != nil {
+			return err
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// upsertProfile sets the given profile fields of an artist
+func upsertProfile(ctx context.Context, tx pgx.Tx, artistID uuid.UUID, p ProfileInput) error {
+	columns := []string{"artist_id"}
+	values := []string{"$1"}
+	sets := []string{"updated_at = CURRENT_TIMESTAMP"}
+	args := []any{artistID}
+	// value là biểu thức SQL dùng placeholder %s
+	set := func(column, value string, v any) {
+		args = append(args, v)
+		columns = append(columns, column)
+		values = append(values, fmt.Sprintf(value, fmt.Sprintf("$%d", len(args))))
+		sets = append(sets, column+" = EXCLUDED."+column)
+	}
+	if p.FullName != nil {
+		set("full_name", "NULLIF(%s, '')", *p.FullName)
+	}
+	if p.AvatarURL != nil {
+		set("avatar_url", "NULLIF(%s, '')", *p.AvatarURL)
+	}
+	if p.Sex != nil {
+		set("sex", "NULLIF(%s, '')", *p.Sex)
+	}
+	if p.Birthday != nil {
+		set("birthday", "NULLIF(%s, '')::date", *p.Birthday)
+	}
+	if p.Country != nil {
+		set("country", "NULLIF(%s, '')", *p.Country)
+	}
+	if p.Verified != nil {
+		set("verified", "%s", *p.Verified)
+	}
+
+	_, err := tx.Exec(ctx, `
+		INSERT INTO artist_profiles (`+strings.Join(columns, ", ")+`)
+		VALUES (`+strings.Join(values, ", ")+`)
+		ON CONFLICT (artist_id) DO UPDATE SET `+strings.Join(sets, ", "), args...)
+	if err != nil {
+		return fmt.Errorf("error updating artist profile: %w", err)
+	}
+	return nil
+}
+
+// Delete removes an artist with its profile, credits and albums. Songs of
+// the albums are kept without album.
+func (r *Repository) Delete(ctx context.Context, id uuid.UUID) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM artists WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("error deleting artist: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrArtistNotFound
+	}
+	return nil
+}
+
+// ListAlbums returns the albums of an artist's discography: albums of the
+// artist first, then albums with a song crediting the artist, newest first
+func (r *Repository) ListAlbums(ctx context.Context, artistID uuid.UUID, viewer Viewer) ([]DiscographyAlbum, error) {
+	query := `
+		SELECT al.id::text, al.title, COALESCE(al.cover_url, ''), al.release_date,
+		       COALESCE(al.album_type, 'album'),
+		       CASE WHEN COALESCE(al.artist_id = $1, FALSE) THEN '` + RelationMain + `' ELSE '` + RelationAppearsOn + `' END,
+		       (SELECT count(*) FROM songs s
+		        WHERE s.album_id = al.id AND s.status = 'ready' AND (s.visibility = 'public' OR $3::boolean))
+		FROM albums al
+		WHERE (al.artist_id = $1 OR EXISTS (
+				SELECT 1 FROM songs s INNER JOIN song_artists sa ON sa.song_id = s.id
+				WHERE s.album_id = al.id AND sa.artist_id = $1))
+			-- Album chưa phát hành chỉ hiện với uploader và admin, album không có bản quyền chỉ với admin
+			AND (al.visibility = 'public' OR $3::boolean
+				OR EXISTS (SELECT 1 FROM songs s WHERE s.album_id = al.id AND s.uploaded_by = $2::uuid))
+			AND ($3::boolean OR ` + territory.Condition("al.territory_allow", "al.territory_deny", "$4::text") + `)
+		ORDER BY COALESCE(al.artist_id = $1, FALSE) DESC, al.release_date DESC NULLS LAST, al.created_at DESC, al.id
+	`
+	rows, err := r.db.Query(ctx, query, artistID, viewer.id(), viewer.IsAdmin, viewer.country())
+	if err != nil {
+		return nil, fmt.Errorf("error listing artist albums: %w", err)
+	}
+	defer rows.Close()
+
+	albums := []DiscographyAlbum{}
+	for rows.Next() {
+		var a DiscographyAlbum
+		if err := rows.Scan(&a.ID, &a.Title, &a.CoverURL, &a.ReleaseDate, &a.AlbumType, &a.Relation, &a.SongCount); err != nil {
+			return nil, fmt.Errorf("error scanning artist album: %w", err)
+		}
+		albums = append(albums, a)
+	}
+	return albums, rows.Err()
+}
@@ -0,0 +1,23 @@
+package artist
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRoutes registers all artist routes to the given router group
+func RegisterRoutes(rg *gin.RouterGroup, h *Handler, authMiddleware, optionalAuthMiddleware, adminMiddleware gin.HandlerFunc) {
+	artistGroup := rg.Group("/artists")
+	{
+		// Public, discography chỉ gồm nội dung user hiện tại được xem
+		artistGroup.GET("", h.ListArtists)
+		artistGroup.GET("/:id", h.GetArtist)
+		artistGroup.GET("/:id/albums", optionalAuthMiddleware, adminMiddleware, h.GetAlbums)
+		artistGroup.GET("/:id/songs", optionalAuthMiddleware, adminMiddleware, h.GetSongs)
+		artistGroup.GET("/:id/top-tracks", optionalAuthMiddleware, adminMiddleware, h.GetTopTracks)
+		// Protected - create and delete are admin only, edits for admins and the artist's owner
+		artistGroup.POST("", authMiddleware, adminMiddleware, h.CreateArtist)
+		artistGroup.PATCH("/:id", authMiddleware, adminMiddleware, h.UpdateArtist)
+		artistGroup.DELETE("/:id", authMiddleware, adminMiddleware, h.DeleteArtist)
+		artistGroup.POST("/:id/image", authMiddleware, adminMiddleware, h.UploadImage)
+	}
+}
@@ -22,6 +22,12 @@ type RefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token" validate:"required"`
 }
 
+// ExplicitSettingRequest sets whether explicit content is shown, null
+// restores the default
+type ExplicitSettingRequest struct {
+	AllowExplicit *bool `json:"allow_explicit"`
+}
+
 // ========== RESPONSE DTOs ==========
 
 // AuthResponse is returned after successful login/register
@@ -40,6 +46,14 @@ type UserResponse struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// ExplicitSettingResponse is the stored preference and its effect: explicit
+// content is never allowed under the minimum age
+type ExplicitSettingResponse struct {
+	AllowExplicit   *bool `json:"allow_explicit"`
+	Underage        bool  `json:"underage"`
+	ExplicitAllowed bool  `json:"explicit_allowed"`
+}
+
 // ErrorResponse represents a standardized error response
 type ErrorResponse struct {
 	Error   string `json:"error"`
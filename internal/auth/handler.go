@@ -3,10 +3,12 @@ package auth
 import (
 	"errors"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
+	"spotify-clone/internal/parental"
 	"spotify-clone/internal/ratelimit"
 	"spotify-clone/internal/user"
 )
@@ -15,13 +17,15 @@ type Handler struct {
 	authService AuthService
 	userRepo    user.UserRepository
 	rateLimiter *ratelimit.LoginRateLimiter
+	parental    *parental.Policy
 }
 
-func NewHandler(authService AuthService, userRepo user.UserRepository, rateLimiter *ratelimit.LoginRateLimiter) *Handler {
+func NewHandler(authService AuthService, userRepo user.UserRepository, rateLimiter *ratelimit.LoginRateLimiter, policy *parental.Policy) *Handler {
 	return &Handler{
 		authService: authService,
 		userRepo:    userRepo,
 		rateLimiter: rateLimiter,
+		parental:    policy,
 	}
 }
 
@@ -128,8 +132,7 @@ func (h *Handler) RefreshToken(c *gin.Context) {
 // GET /auth/me - Get current authenticated user
 func (h *Handler) Me(c *gin.Context) {
 	// Get userID from context (set by AuthMiddleware)
-	// Using same key as middleware.UserIDKey = "userID"
-	userIDVal, exists := c.Get("userID")
+	userIDVal, exists := c.Get(UserIDKey)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 		return
@@ -164,3 +167,72 @@ func (h *Handler) Me(c *gin.Context) {
 		CreatedAt: foundUser.CreatedAt,
 	})
 }
+
+// GET /auth/me/explicit - Get the explicit content setting
+func (h *Handler) GetExplicitSetting(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	setting, err := h.userRepo.FindExplicitSetting(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get explicit content setting"})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.explicitSettingResponse(setting))
+}
+
+// PUT /auth/me/explicit - Allow or hide explicit content. Users under the
+// minimum age (by the birthday of their profile) cannot allow it.
+func (h *Handler) SetExplicitSetting(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req ExplicitSettingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	setting, err := h.userRepo.FindExplicitSetting(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get explicit content setting"})
+		return
+	}
+	if req.AllowExplicit != nil && *req.AllowExplicit && h.parental.Underage(setting.Birthday, time.Now()) {
+		c.JSON(http.StatusForbidden, gin.H{"error": parental.ErrUnderage.Error()})
+		return
+	}
+
+	if err := h.userRepo.SetAllowExplicit(ctx, userID, req.AllowExplicit); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update explicit content setting"})
+		return
+	}
+	setting.AllowExplicit = req.AllowExplicit
+
+	c.JSON(http.StatusOK, h.explicitSettingResponse(setting))
+}
+
+func (h *Handler) explicitSettingResponse(setting *user.ExplicitSetting) ExplicitSettingResponse {
+	now := time.Now()
+	return ExplicitSettingResponse{
+		AllowExplicit:   setting.AllowExplicit,
+		Underage:        h.parental.Underage(setting.Birthday, now),
+		ExplicitAllowed: h.parental.Allows(setting, now),
+	}
+}
+
+// currentUserID returns the ID set by AuthMiddleware, writing 401 if missing
+func currentUserID(c *gin.Context) (uuid.UUID, bool) {
+	userID, err := uuid.Parse(c.GetString(UserIDKey))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return uuid.UUID{}, false
+	}
+	return userID, true
+}
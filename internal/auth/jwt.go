@@ -19,6 +19,10 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
+// UserIDKey is the Gin context key the auth middleware stores Claims.UserID
+// under. It is defined here because the middleware package imports auth.
+const UserIDKey = "userID"
+
 // JWTService defines JWT operations interface
 type JWTService interface {
 	GenerateAccessToken(userID, email string) (string, time.Time, error)
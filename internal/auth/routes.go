@@ -15,5 +15,7 @@ func RegisterRoutes(rg *gin.RouterGroup, h *Handler, authMiddleware gin.HandlerF
 		authGroup.POST("/refresh", h.RefreshToken)
 		// Protected route - requires valid JWT
 		authGroup.GET("/me", authMiddleware, h.Me)
+		authGroup.GET("/me/explicit", authMiddleware, h.GetExplicitSetting)
+		authGroup.PUT("/me/explicit", authMiddleware, h.SetExplicitSetting)
 	}
 }
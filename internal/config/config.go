@@ -1,18 +1,33 @@
 package config
 
 import (
+	"errors"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	Port     string
-	Env      string
-	Database DatabaseConfig
-	JWT      JWTConfig
-	Static   StaticConfig
+	Port string
+	Env  string
+	// Proxies whose X-Forwarded-For is trusted for the client IP, none by default
+	TrustedProxies []string
+	Database       DatabaseConfig
+	JWT            JWTConfig
+	Static         StaticConfig
+	Stream         StreamConfig
+	Search         SearchConfig
+	Admin          AdminConfig
+	Storage        StorageConfig
+	Upload         UploadConfig
+	Jobs           JobsConfig
+	Playback       PlaybackConfig
+	Release        ReleaseConfig
+	GeoIP          GeoIPConfig
+	Parental       ParentalConfig
 }
 
 type DatabaseConfig struct {
@@ -35,16 +50,98 @@ type StaticConfig struct {
 	MusicPath string
 }
 
+// StreamConfig holds per-tier streaming limits.
+// Rates are in bytes per second, 0 means unlimited.
+type StreamConfig struct {
+	FreeBytesPerSec    int64
+	PremiumBytesPerSec int64
+	BurstBytes         int64
+	FreeMaxStreams     int
+	PremiumMaxStreams  int
+	URLSecret          string        // HMAC key for signed stream URLs, required by the web server
+	URLExpiry          time.Duration // lifetime of a signed stream URL
+	HLSCacheDir        string        // where generated HLS playlists/segments are cached
+	HLSSegmentDuration time.Duration // target duration of an HLS segment
+}
+
+type SearchConfig struct {
+	SuggestRefresh time.Duration // how often the autocomplete index is rebuilt
+}
+
+// StorageConfig selects where audio files are stored.
+// Backend "local" stores files under Static.MusicPath, "s3" uses the S3 fields.
+type StorageConfig struct {
+	Backend     string
+	S3Endpoint  string
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+	S3PathStyle bool
+}
+
+// UploadConfig controls resumable (tus) uploads, staged on disk until complete
+type UploadConfig struct {
+	Dir     string
+	MaxSize int64         // largest accepted file, in bytes
+	Expiry  time.Duration // unfinished uploads are removed after this long without data
+}
+
+// JobsConfig controls the background workers processing uploaded songs
+type JobsConfig struct {
+	Workers      int
+	PollInterval time.Duration // wait between polls when the queue is empty
+	Timeout      time.Duration // a job's context is cancelled after this
+	Lease        time.Duration // a job running longer is assumed lost and retried, longer than Timeout
+}
+
+// PlaybackConfig controls how counted plays are batched into the database
+type PlaybackConfig struct {
+	BatchSize     int
+	FlushInterval time.Duration
+	MaxSessions   int // open playback sessions per user, the oldest is dropped beyond
+}
+
+// ReleaseConfig controls the scheduler publishing scheduled songs and albums
+type ReleaseConfig struct {
+	CheckInterval time.Duration // releases go live at most this late
+}
+
+// GeoIPConfig locates the IP to country CSV database used for licensing
+// restrictions. Without it, only the country of user profiles is known.
+type GeoIPConfig struct {
+	DBPath string
+}
+
+// ParentalConfig controls explicit content filtering
+type ParentalConfig struct {
+	ExplicitMinAge int // users younger than this never get explicit content, 0 = no age check
+}
+
+type AdminConfig struct {
+	UserIDs []string // users allowed to manage any content
+}
+
 func Load() (*Config, error) {
 	// Load .env file
 	godotenv.Load()
 
 	jwtExpiry, _ := time.ParseDuration(getEnv("JWT_EXPIRY", "24h"))
 	refreshExpiry, _ := time.ParseDuration(getEnv("REFRESH_TOKEN_EXPIRY", "168h"))
+	suggestRefresh, _ := time.ParseDuration(getEnv("SUGGEST_REFRESH", "10m"))
+	playFlush, _ := time.ParseDuration(getEnv("PLAY_FLUSH_INTERVAL", "5s"))
+	streamURLExpiry, _ := time.ParseDuration(getEnv("STREAM_URL_EXPIRY", "6h"))
+	hlsSegment, _ := time.ParseDuration(getEnv("HLS_SEGMENT_DURATION", "6s"))
+	uploadExpiry, _ := time.ParseDuration(getEnv("UPLOAD_EXPIRY", "24h"))
+	jobPoll, _ := time.ParseDuration(getEnv("JOB_POLL_INTERVAL", "2s"))
+	jobTimeout, _ := time.ParseDuration(getEnv("JOB_TIMEOUT", "10m"))
+	jobLease, _ := time.ParseDuration(getEnv("JOB_LEASE", "15m"))
+	releaseCheck, _ := time.ParseDuration(getEnv("RELEASE_CHECK_INTERVAL", "30s"))
 
 	return &Config{
-		Port: getEnv("PORT", "8080"),
-		Env:  getEnv("ENV", "development"),
+		Port:           getEnv("PORT", "8080"),
+		Env:            getEnv("ENV", "development"),
+		TrustedProxies: getEnvList("TRUSTED_PROXIES"),
 		Database: DatabaseConfig{
 			Host:     getEnv("DB_HOST", "localhost"),
 			Port:     getEnv("DB_PORT", "5432"),
@@ -62,12 +159,94 @@ func Load() (*Config, error) {
 			Path:      getEnv("STATIC_PATH", "./web/static"),
 			MusicPath: getEnv("MUSIC_PATH", "./web/static/music"),
 		},
+		Stream: StreamConfig{
+			FreeBytesPerSec:    int64(getEnvInt("STREAM_FREE_RATE", 24*1024)),
+			PremiumBytesPerSec: int64(getEnvInt("STREAM_PREMIUM_RATE", 0)),
+			BurstBytes:         int64(getEnvInt("STREAM_BURST", 512*1024)),
+			FreeMaxStreams:     getEnvInt("STREAM_FREE_MAX_CONCURRENT", 1),
+			PremiumMaxStreams:  getEnvInt("STREAM_PREMIUM_MAX_CONCURRENT", 0),
+			URLSecret:          getEnv("STREAM_URL_SECRET", ""),
+			URLExpiry:          streamURLExpiry,
+			HLSCacheDir:        getEnv("HLS_CACHE_DIR", "./data/hls"),
+			HLSSegmentDuration: hlsSegment,
+		},
+		Search: SearchConfig{
+			SuggestRefresh: suggestRefresh,
+		},
+		Admin: AdminConfig{
+			UserIDs: getEnvList("ADMIN_USER_IDS"),
+		},
+		Storage: StorageConfig{
+			Backend:     getEnv("STORAGE_BACKEND", "local"),
+			S3Endpoint:  getEnv("S3_ENDPOINT", "http://localhost:9000"),
+			S3Region:    getEnv("S3_REGION", "us-east-1"),
+			S3Bucket:    getEnv("S3_BUCKET", "spotify-clone"),
+			S3AccessKey: getEnv("S3_ACCESS_KEY", ""),
+			S3SecretKey: getEnv("S3_SECRET_KEY", ""),
+			S3PathStyle: getEnv("S3_PATH_STYLE", "true") == "true",
+		},
+		Upload: UploadConfig{
+			Dir:     getEnv("UPLOAD_DIR", "./data/uploads"),
+			MaxSize: int64(getEnvInt("UPLOAD_MAX_SIZE", 2<<30)),
+			Expiry:  uploadExpiry,
+		},
+		Jobs: JobsConfig{
+			Workers:      getEnvInt("JOB_WORKERS", 2),
+			PollInterval: jobPoll,
+			Timeout:      jobTimeout,
+			Lease:        jobLease,
+		},
+		Playback: PlaybackConfig{
+			BatchSize:     getEnvInt("PLAY_BATCH_SIZE", 500),
+			FlushInterval: playFlush,
+			MaxSessions:   getEnvInt("PLAY_MAX_SESSIONS", 5),
+		},
+		Release: ReleaseConfig{
+			CheckInterval: releaseCheck,
+		},
+		GeoIP: GeoIPConfig{
+			DBPath: getEnv("GEOIP_DB_PATH", ""),
+		},
+		Parental: ParentalConfig{
+			ExplicitMinAge: getEnvInt("EXPLICIT_MIN_AGE", 18),
+		},
 	}, nil
 }
 
+// ValidateStreamSecret returns an error unless a signing key for stream URLs
+// is set and differs from the JWT secret
+func (c *Config) ValidateStreamSecret() error {
+	if c.Stream.URLSecret == "" {
+		return errors.New("STREAM_URL_SECRET is required")
+	}
+	if c.Stream.URLSecret == c.JWT.Secret {
+		return errors.New("STREAM_URL_SECRET must differ from JWT_SECRET")
+	}
+	return nil
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	}
+	return defaultValue
+}
+
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
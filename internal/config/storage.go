@@ -0,0 +1,26 @@
+package config
+
+import (
+	"fmt"
+
+	"spotify-clone/pkg/storage"
+)
+
+// NewStorage creates the blob storage selected by STORAGE_BACKEND
+func NewStorage(cfg *Config) (storage.Blob, error) {
+	switch cfg.Storage.Backend {
+	case "local":
+		return storage.NewLocal(cfg.Static.MusicPath)
+	case "s3":
+		return storage.NewS3(storage.S3Config{
+			Endpoint:  cfg.Storage.S3Endpoint,
+			Region:    cfg.Storage.S3Region,
+			Bucket:    cfg.Storage.S3Bucket,
+			AccessKey: cfg.Storage.S3AccessKey,
+			SecretKey: cfg.Storage.S3SecretKey,
+			PathStyle: cfg.Storage.S3PathStyle,
+		})
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.Storage.Backend)
+	}
+}
@@ -0,0 +1,196 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrLeaseLost is returned when saving the outcome of a job that is no
+// longer held by the caller: its lease expired and it was requeued, possibly
+// claimed again by another worker
+var ErrLeaseLost = errors.New("job lease lost")
+
+// Execer is a pool or a transaction. Enqueueing inside the caller's
+// transaction makes jobs visible only if the work that needs them commits.
+type Execer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// Queue is a job queue stored in the jobs table
+type Queue struct {
+	db *pgxpool.Pool
+}
+
+func NewQueue(db *pgxpool.Pool) *Queue {
+	return &Queue{db: db}
+}
+
+// Enqueue adds jobs using db, which may be a transaction
+func Enqueue(ctx context.Context, db Execer, jobs ...NewJob) error {
+	for _, j := range jobs {
+		payload := []byte("{}")
+		if j.Payload != nil {
+			var err error
+			if payload, err = json.Marshal(j.Payload); err != nil {
+				return fmt.Errorf("error encoding %s job payload: %w", j.Kind, err)
+			}
+		}
+		var songID *string
+		if j.SongID != "" {
+			songID = &j.SongID
+		}
+		_, err := db.Exec(ctx, `
+			INSERT INTO jobs (kind, song_id, payload) VALUES ($1, $2, $3)
+		`, j.Kind, songID, payload)
+		if err != nil {
+			return fmt.Errorf("error enqueueing %s job: %w", j.Kind, err)
+		}
+	}
+	return nil
+}
+
+// Enqueue adds jobs outside of any transaction
+func (q *Queue) Enqueue(ctx context.Context, jobs ...NewJob) error {
+	return Enqueue(ctx, q.db, jobs...)
+}
+
+const jobColumns = `id, kind, COALESCE(song_id::text, ''), payload, status, attempts, max_attempts,
+	COALESCE(last_error, ''), run_at, created_at, updated_at`
+
+func scanJob(row pgx.Row) (*Job, error) {
+	var j Job
+	err := row.Scan(&j.ID, &j.Kind, &j.SongID, &j.Payload, &j.Status, &j.Attempts, &j.MaxAttempts,
+		&j.LastError, &j.RunAt, &j.CreatedAt, &j.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+// Claim marks the oldest due pending job of one of kinds as running and
+// returns it, or nil if there is none. SKIP LOCKED lets concurrent workers
+// claim different jobs without waiting on each other.
+func (q *Queue) Claim(ctx context.Context, kinds []string) (*Job, error) {
+	job, err := scanJob(q.db.QueryRow(ctx, `
+		UPDATE jobs SET status = 'running', attempts = attempts + 1,
+			locked_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = (
+			SELECT id FROM jobs
+			WHERE status = 'pending' AND run_at <= CURRENT_TIMESTAMP AND kind = ANY($1)
+			ORDER BY run_at, id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+jobColumns, kinds))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error claiming job: %w", err)
+	}
+	return job, nil
+}
+
+// Complete marks a running job as done. It returns ErrLeaseLost if the
+// attempt is not the job's current running one anymore.
+func (q *Queue) Complete(ctx context.Context, job *Job) error {
+	tag, err := q.db.Exec(ctx, `
+		UPDATE jobs SET status = 'done', last_error = NULL, locked_at = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = 'running' AND attempts = $2
+	`, job.ID, job.Attempts)
+	if err != nil {
+		return fmt.Errorf("error completing job %d: %w", job.ID, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// Fail records a failed attempt. The job is retried after delay unless it
+// has no attempts left or the error is permanent. It returns the new status,
+// or ErrLeaseLost if the attempt is not the job's current running one anymore.
+func (q *Queue) Fail(ctx context.Context, job *Job, jobErr error, delay time.Duration) (string, error) {
+	status := StatusPending
+	if job.Attempts >= job.MaxAttempts || IsPermanent(jobErr) {
+		status = StatusFailed
+	}
+	tag, err := q.db.Exec(ctx, `
+		UPDATE jobs SET status = $2, last_error = $3, locked_at = NULL,
+			run_at = CURRENT_TIMESTAMP + $4 * INTERVAL '1 millisecond', updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = 'running' AND attempts = $5
+	`, job.ID, status, jobErr.Error(), delay.Milliseconds(), job.Attempts)
+	if err != nil {
+		return "", fmt.Errorf("error failing job %d: %w", job.ID, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return "", ErrLeaseLost
+	}
+	return status, nil
+}
+
+// Release returns a running job to pending without counting the attempt, for
+// a run interrupted by the worker shutting down. It returns ErrLeaseLost if
+// the attempt is not the job's current running one anymore.
+func (q *Queue) Release(ctx context.Context, job *Job) error {
+	tag, err := q.db.Exec(ctx, `
+		UPDATE jobs SET status = 'pending', attempts = attempts - 1, locked_at = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = 'running' AND attempts = $2
+	`, job.ID, job.Attempts)
+	if err != nil {
+		return fmt.Errorf("error releasing job %d: %w", job.ID, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// RequeueStale returns jobs running for longer than lease to pending: their
+// worker crashed or was stopped. The attempt counts as a failed one. It
+// returns the jobs that failed for good as a result.
+func (q *Queue) RequeueStale(ctx context.Context, lease time.Duration) ([]Job, error) {
+	rows, err := q.db.Query(ctx, `
+		UPDATE jobs SET
+			status = CASE WHEN attempts >= max_attempts THEN 'failed' ELSE 'pending' END,
+			last_error = 'worker lease expired', locked_at = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE status = 'running' AND locked_at < CURRENT_TIMESTAMP - $1 * INTERVAL '1 millisecond'
+		RETURNING `+jobColumns, lease.Milliseconds())
+	if err != nil {
+		return nil, fmt.Errorf("error requeueing stale jobs: %w", err)
+	}
+	return collectJobs(rows, StatusFailed)
+}
+
+// ListBySong returns the jobs of a song, oldest first
+func (q *Queue) ListBySong(ctx context.Context, songID string) ([]Job, error) {
+	rows, err := q.db.Query(ctx, `SELECT `+jobColumns+` FROM jobs WHERE song_id = $1 ORDER BY id`, songID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing jobs: %w", err)
+	}
+	return collectJobs(rows, "")
+}
+
+// collectJobs scans rows into jobs, keeping only those with status if not ""
+func collectJobs(rows pgx.Rows, status string) ([]Job, error) {
+	defer rows.Close()
+
+	var jobs []Job
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning job: %w", err)
+		}
+		if status == "" || job.Status == status {
+			jobs = append(jobs, *job)
+		}
+	}
+	return jobs, rows.Err()
+}
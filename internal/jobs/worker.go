@@ -0,0 +1,206 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Handler runs a job. A returned error is retried with backoff, unless it
+// is wrapped with Permanent.
+type Handler func(ctx context.Context, job *Job) error
+
+// Observer is notified when a job reaches a final status (done or failed)
+type Observer interface {
+	JobSettled(ctx context.Context, job Job)
+}
+
+// store is the part of Queue used by Worker
+type store interface {
+	Claim(ctx context.Context, kinds []string) (*Job, error)
+	Complete(ctx context.Context, job *Job) error
+	Fail(ctx context.Context, job *Job, jobErr error, delay time.Duration) (string, error)
+	Release(ctx context.Context, job *Job) error
+	RequeueStale(ctx context.Context, lease time.Duration) ([]Job, error)
+}
+
+// Worker runs queued jobs with a fixed number of goroutines
+type Worker struct {
+	queue       store
+	handlers    map[string]Handler
+	observers   []Observer
+	concurrency int
+	poll        time.Duration // wait between claims when the queue is empty
+	timeout     time.Duration // a job's context is cancelled after this
+	lease       time.Duration // a job running longer than this is considered lost
+}
+
+// NewWorker creates a worker. Handlers and observers must be registered
+// before Run is called. The lease is kept longer than the timeout so a job
+// is only requeued once its handler had time to return.
+func NewWorker(queue *Queue, concurrency int, poll, timeout, lease time.Duration) *Worker {
+	return newWorker(queue, concurrency, poll, timeout, lease)
+}
+
+func newWorker(queue store, concurrency int, poll, timeout, lease time.Duration) *Worker {
+	if poll <= 0 {
+		poll = 2 * time.Second
+	}
+	if timeout <= 0 {
+		timeout = 10 * time.Minute
+	}
+	if lease <= timeout {
+		lease = timeout + timeout/2
+	}
+	return &Worker{
+		queue:       queue,
+		handlers:    map[string]Handler{},
+		concurrency: max(concurrency, 1),
+		poll:        poll,
+		timeout:     timeout,
+		lease:       lease,
+	}
+}
+
+// Register sets the handler of a job kind
+func (w *Worker) Register(kind string, h Handler) {
+	w.handlers[kind] = h
+}
+
+// AddObserver registers an observer for settled jobs
+func (w *Worker) AddObserver(o Observer) {
+	w.observers = append(w.observers, o)
+}
+
+// Run processes jobs until ctx is cancelled
+func (w *Worker) Run(ctx context.Context) {
+	kinds := make([]string, 0, len(w.handlers))
+	for kind := range w.handlers {
+		kinds = append(kinds, kind)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < w.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.loop(ctx, kinds)
+		}()
+	}
+
+	// Job của worker bị crash (status running quá lease) được trả lại hàng đợi
+	ticker := time.NewTicker(w.lease / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case <-ticker.C:
+			failed, err := w.queue.RequeueStale(ctx, w.lease)
+			if err != nil {
+				log.Println("Failed to requeue stale jobs:", err)
+			}
+			for _, job := range failed {
+				w.notify(ctx, job)
+			}
+		}
+	}
+}
+
+func (w *Worker) loop(ctx context.Context, kinds []string) {
+	for ctx.Err() == nil {
+		job, err := w.queue.Claim(ctx, kinds)
+		if err != nil {
+			log.Println(err)
+		}
+		if job == nil {
+			select {
+			case <-ctx.Done():
+			case <-time.After(w.poll):
+			}
+			continue
+		}
+		w.run(ctx, job)
+	}
+}
+
+// run executes one claimed job and records its outcome
+func (w *Worker) run(ctx context.Context, job *Job) {
+	jobCtx, cancel := context.WithTimeout(ctx, w.timeout)
+	err := w.call(jobCtx, job)
+	cancel()
+
+	// Ghi kết quả kể cả khi ctx bị huỷ (server đang tắt)
+	saveCtx := context.WithoutCancel(ctx)
+	if err == nil {
+		if err := w.queue.Complete(saveCtx, job); err != nil {
+			w.logSaveError(job, err)
+			return
+		}
+		job.Status = StatusDone
+		w.notify(saveCtx, *job)
+		return
+	}
+
+	// Server đang tắt: lần chạy bị ngắt không tính là một lần thử, job được
+	// chạy lại ngay khi có worker, không chờ backoff
+	if ctx.Err() != nil {
+		if err := w.queue.Release(saveCtx, job); err != nil {
+			w.logSaveError(job, err)
+			return
+		}
+		log.Printf("Job %d (%s) interrupted by shutdown, returned to the queue", job.ID, job.Kind)
+		return
+	}
+
+	status, failErr := w.queue.Fail(saveCtx, job, err, retryDelay(job.Attempts))
+	if failErr != nil {
+		w.logSaveError(job, failErr)
+		return
+	}
+	log.Printf("Job %d (%s) attempt %d/%d failed: %v", job.ID, job.Kind, job.Attempts, job.MaxAttempts, err)
+	if status == StatusFailed {
+		job.Status = StatusFailed
+		job.LastError = err.Error()
+		w.notify(saveCtx, *job)
+	}
+}
+
+// logSaveError logs a failure to record a job's outcome
+func (w *Worker) logSaveError(job *Job, err error) {
+	// Job đã bị trả lại hàng đợi (quá lease), kết quả lần chạy này bị bỏ
+	if errors.Is(err, ErrLeaseLost) {
+		log.Printf("Job %d (%s) attempt %d finished after its lease expired, result discarded", job.ID, job.Kind, job.Attempts)
+		return
+	}
+	log.Println(err)
+}
+
+// call runs the handler, turning a panic into an error
+func (w *Worker) call(ctx context.Context, job *Job) (err error) {
+	h, ok := w.handlers[job.Kind]
+	if !ok {
+		return Permanent(fmt.Errorf("no handler for job kind %q", job.Kind))
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return h(ctx, job)
+}
+
+func (w *Worker) notify(ctx context.Context, job Job) {
+	for _, o := range w.observers {
+		o.JobSettled(ctx, job)
+	}
+}
+
+// retryDelay is the backoff before attempt n+1: 15s, 1m, 2m15s, ... capped at 1h
+func retryDelay(attempts int) time.Duration {
+	return min(time.Duration(attempts*attempts)*15*time.Second, time.Hour)
+}
@@ -0,0 +1,155 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeStore is an in-memory queue holding the given pending jobs
+type fakeStore struct {
+	mu       sync.Mutex
+	pending  []*Job
+	done     []int64
+	failed   []int64
+	released []int64
+}
+
+func (f *fakeStore) Claim(ctx context.Context, kinds []string) (*Job, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.pending) == 0 {
+		return nil, nil
+	}
+	job := f.pending[0]
+	f.pending = f.pending[1:]
+	job.Status = StatusRunning
+	job.Attempts++
+	return job, nil
+}
+
+func (f *fakeStore) Complete(ctx context.Context, job *Job) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.done = append(f.done, job.ID)
+	return nil
+}
+
+func (f *fakeStore) Fail(ctx context.Context, job *Job, jobErr error, delay time.Duration) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failed = append(f.failed, job.ID)
+	return StatusFailed, nil
+}
+
+func (f *fakeStore) Release(ctx context.Context, job *Job) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	job.Status = StatusPending
+	job.Attempts--
+	f.released = append(f.released, job.ID)
+	return nil
+}
+
+func (f *fakeStore) RequeueStale(ctx context.Context, lease time.Duration) ([]Job, error) {
+	return nil, nil
+}
+
+func TestWorkerShutdownMidJobReleasesJob(t *testing.T) {
+	job := &Job{ID: 1, Kind: "analyse", Status: StatusPending, MaxAttempts: 1}
+	queue := &fakeStore{pending: []*Job{job}}
+	w := newWorker(queue, 1, 10*time.Millisecond, time.Minute, 2*time.Minute)
+
+	started := make(chan struct{})
+	w.Register("analyse", func(ctx context.Context, job *Job) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	settled := 0
+	w.AddObserver(observerFunc(func(ctx context.Context, job Job) { settled++ }))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		w.Run(ctx)
+		close(stopped)
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("job was not started")
+	}
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("worker did not stop")
+	}
+
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+	if len(queue.released) != 1 || len(queue.failed) != 0 || len(queue.done) != 0 {
+		t.Fatalf("released %v, failed %v, done %v, want only job 1 released", queue.released, queue.failed, queue.done)
+	}
+	if job.Status != StatusPending || job.Attempts != 0 {
+		t.Fatalf("job status %q attempts %d, want pending with 0 attempts", job.Status, job.Attempts)
+	}
+	if settled != 0 {
+		t.Fatalf("observers notified %d times, want 0", settled)
+	}
+}
+
+func TestWorkerFailureCountsAttempt(t *testing.T) {
+	job := &Job{ID: 1, Kind: "analyse", Status: StatusPending, MaxAttempts: 1}
+	queue := &fakeStore{pending: []*Job{job}}
+	w := newWorker(queue, 1, 10*time.Millisecond, time.Minute, 2*time.Minute)
+
+	ran := make(chan struct{})
+	w.Register("analyse", func(ctx context.Context, job *Job) error {
+		defer close(ran)
+		return errors.New("decode failed")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		w.Run(ctx)
+		close(stopped)
+	}()
+
+	select {
+	case <-ran:
+	case <-time.After(5 * time.Second):
+		t.Fatal("job was not started")
+	}
+	// Chờ kết quả được ghi trước khi tắt worker
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		queue.mu.Lock()
+		n := len(queue.failed)
+		queue.mu.Unlock()
+		if n > 0 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-stopped
+
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+	if len(queue.failed) != 1 || len(queue.released) != 0 {
+		t.Fatalf("failed %v, released %v, want only job 1 failed", queue.failed, queue.released)
+	}
+}
+
+type observerFunc func(ctx context.Context, job Job)
+
+func (f observerFunc) JobSettled(ctx context.Context, job Job) { f(ctx, job) }
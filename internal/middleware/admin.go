@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// IsAdminKey is the context key for the admin flag
+const IsAdminKey = "isAdmin"
+
+// AdminMiddleware flags requests made by admin users (configured by user ID).
+// It must run after AuthMiddleware or OptionalAuthMiddleware and never aborts;
+// handlers decide what admins may do with IsAdmin.
+func AdminMiddleware(adminIDs []string) gin.HandlerFunc {
+	admins := make(map[string]bool, len(adminIDs))
+	for _, id := range adminIDs {
+		admins[id] = true
+	}
+
+	return func(c *gin.Context) {
+		if userID, ok := GetUserID(c); ok && admins[userID] {
+			c.Set(IsAdminKey, true)
+		}
+		c.Next()
+	}
+}
+
+// IsAdmin reports whether the request was made by an admin
+func IsAdmin(c *gin.Context) bool {
+	return c.GetBool(IsAdminKey)
+}
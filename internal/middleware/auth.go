@@ -11,7 +11,7 @@ import (
 
 const (
 	// UserIDKey is the context key for user ID
-	UserIDKey = "userID"
+	UserIDKey = auth.UserIDKey
 	// EmailKey is the context key for user email
 	EmailKey = "email"
 	// ClaimsKey is the context key for full claims
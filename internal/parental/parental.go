@@ -0,0 +1,66 @@
+// Package parental decides whether a listener may play explicit content,
+// from the birthday and preference in their profile.
+package parental
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"spotify-clone/internal/user"
+)
+
+// ErrUnderage is returned when a minor tries to enable explicit content
+var ErrUnderage = errors.New("explicit content is not available under the minimum age")
+
+// Settings looks up the explicit content setting of a user
+type Settings interface {
+	FindExplicitSetting(ctx context.Context, id uuid.UUID) (*user.ExplicitSetting, error)
+}
+
+// Policy allows explicit content to anonymous listeners and to users that
+// are not known to be under MinAge, unless they turned it off
+type Policy struct {
+	settings Settings
+	minAge   int // 0 disables the age check
+}
+
+// NewPolicy creates a policy with the given minimum age
+func NewPolicy(settings Settings, minAge int) *Policy {
+	return &Policy{settings: settings, minAge: minAge}
+}
+
+// Underage reports whether someone born on birthday is younger than the
+// minimum age at now. An unknown birthday is not underage.
+func (p *Policy) Underage(birthday *time.Time, now time.Time) bool {
+	if birthday == nil || p.minAge <= 0 {
+		return false
+	}
+	// Sinh ngày 29/2: đủ tuổi từ 1/3 ở năm không nhuận
+	return now.Before(birthday.AddDate(p.minAge, 0, 0))
+}
+
+// Allows applies the policy to a user's setting
+func (p *Policy) Allows(s *user.ExplicitSetting, now time.Time) bool {
+	if p.Underage(s.Birthday, now) {
+		return false
+	}
+	return s.AllowExplicit == nil || *s.AllowExplicit
+}
+
+// AllowExplicit reports whether the user may play explicit content.
+// userID may be "" for anonymous listeners. If the setting cannot be
+// loaded, explicit content is hidden.
+func (p *Policy) AllowExplicit(ctx context.Context, userID string) bool {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return true
+	}
+	setting, err := p.settings.FindExplicitSetting(ctx, id)
+	if err != nil {
+		return false
+	}
+	return p.Allows(setting, time.Now())
+}
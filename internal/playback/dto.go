@@ -0,0 +1,19 @@
+package playback
+
+// PlayEventRequest is the body of POST /plays/events
+type PlayEventRequest struct {
+	SessionID  string `json:"session_id" binding:"required,max=64"` // returned with the song's stream URL
+	SongID     string `json:"song_id" binding:"required,uuid"`
+	Event      string `json:"event" binding:"required,oneof=start progress end"`
+	PositionMs int64  `json:"position_ms" binding:"min=0"`
+}
+
+// PlayEventResponse tells the client whether this session has been counted as a play
+type PlayEventResponse struct {
+	Counted bool `json:"counted"`
+}
+
+// HistoryRequest holds query parameters for GET /plays/history
+type HistoryRequest struct {
+	Limit int `form:"limit" binding:"min=0,max=100"`
+}
@@ -0,0 +1,84 @@
+package playback
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"spotify-clone/internal/middleware"
+)
+
+// Handler handles HTTP requests for play events and history
+type Handler struct {
+	repo    *Repository
+	tracker *Tracker
+}
+
+// NewHandler creates a new playback handler
+func NewHandler(repo *Repository, tracker *Tracker) *Handler {
+	return &Handler{repo: repo, tracker: tracker}
+}
+
+// RecordEvent accepts start/progress/end events from the player for a
+// session granted with the song's stream URL.
+// A play is counted once 30 seconds of the streamed song were actually listened to.
+func (h *Handler) RecordEvent(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req PlayEventRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	counted, err := h.tracker.Record(Event{
+		SessionID: req.SessionID,
+		UserID:    userID,
+		SongID:    req.SongID,
+		Type:      EventType(req.Event),
+		Position:  time.Duration(req.PositionMs) * time.Millisecond,
+		At:        time.Now(),
+	})
+	if err != nil {
+		if errors.Is(err, ErrSessionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Playback session not found, request a new stream URL"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record event"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, PlayEventResponse{Counted: counted})
+}
+
+// History returns the current user's recently played songs
+func (h *Handler) History(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req HistoryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query: " + err.Error()})
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = 50
+	}
+
+	history, err := h.repo.History(c.Request.Context(), userID, req.Limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get play history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"history": history})
+}
@@ -0,0 +1,37 @@
+package playback
+
+import "time"
+
+// EventType is a player event sent by clients
+type EventType string
+
+const (
+	EventStart    EventType = "start"
+	EventProgress EventType = "progress"
+	EventEnd      EventType = "end"
+)
+
+// Event is a single player event
+type Event struct {
+	SessionID string
+	UserID    string
+	SongID    string
+	Type      EventType
+	Position  time.Duration // playback position inside the song
+	At        time.Time     // when the server received the event
+}
+
+// Play is a counted play (listened for at least the play threshold)
+type Play struct {
+	ID       string
+	UserID   string
+	SongID   string
+	PlayedAt time.Time
+}
+
+// HistoryEntry is one item of a user's listening history
+type HistoryEntry struct {
+	SongID   string    `json:"song_id"`
+	Title    string    `json:"title"`
+	PlayedAt time.Time `json:"played_at"`
+}
@@ -0,0 +1,89 @@
+package playback
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Recorder collects counted plays and writes them to the database in batches,
+// off the request path
+type Recorder struct {
+	repo          *Repository
+	plays         chan Play
+	batchSize     int
+	flushInterval time.Duration
+}
+
+// NewRecorder creates a recorder that flushes every flushInterval or as soon
+// as batchSize plays are pending
+func NewRecorder(repo *Repository, batchSize int, flushInterval time.Duration) *Recorder {
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+	if flushInterval <= 0 {
+		flushInterval = 5 * time.Second
+	}
+	return &Recorder{
+		repo:          repo,
+		plays:         make(chan Play, batchSize*4),
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+	}
+}
+
+// Plays returns the channel plays are sent to
+func (r *Recorder) Plays() chan<- Play {
+	return r.plays
+}
+
+// Run writes batches until ctx is cancelled, then flushes what is pending
+func (r *Recorder) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.flushInterval)
+	defer ticker.Stop()
+
+	// Giữ lại batch lỗi để thử lại lần sau, nhưng giới hạn để không tràn bộ nhớ
+	maxPending := r.batchSize * 10
+	var pending []Play
+
+	flush := func(ctx context.Context) {
+		if len(pending) == 0 {
+			return
+		}
+		if err := r.repo.InsertPlays(ctx, pending); err != nil {
+			log.Println("Failed to record plays:", err)
+			if len(pending) > maxPending {
+				pending = pending[len(pending)-maxPending:]
+			}
+			return
+		}
+		pending = pending[:0]
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Drain what is already queued, then flush with a fresh context
+		drain:
+			for {
+				select {
+				case play := <-r.plays:
+					pending = append(pending, play)
+				default:
+					break drain
+				}
+			}
+			flushCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			flush(flushCtx)
+			cancel()
+			return
+		case play := <-r.plays:
+			pending = append(pending, play)
+			if len(pending) >= r.batchSize {
+				flush(ctx)
+			}
+		case <-ticker.C:
+			flush(ctx)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package playback
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Repository struct {
+	db *pgxpool.Pool
+}
+
+func NewRepository(db *pgxpool.Pool) *Repository {
+	return &Repository{db: db}
+}
+
+// InsertPlays writes a batch of plays to play_history and adds them to
+// songs.play_count with one UPDATE per song per batch, so hot songs are not
+// updated once per play.
+// Plays of songs or users deleted in the meantime are skipped.
+func (r *Repository) InsertPlays(ctx context.Context, plays []Play) error {
+	if len(plays) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(plays))
+	userIDs := make([]string, len(plays))
+	songIDs := make([]string, len(plays))
+	playedAt := make([]time.Time, len(plays))
+	counts := make(map[string]int)
+	for i, p := range plays {
+		ids[i] = p.ID
+		userIDs[i] = p.UserID
+		songIDs[i] = p.SongID
+		playedAt[i] = p.PlayedAt
+		counts[p.SongID]++
+	}
+
+	// Sắp xếp theo song ID để các batch luôn khóa row theo cùng thứ tự (tránh deadlock)
+	countSongIDs := make([]string, 0, len(counts))
+	for id := range counts {
+		countSongIDs = append(countSongIDs, id)
+	}
+	sort.Strings(countSongIDs)
+	countValues := make([]int, len(countSongIDs))
+	for i, id := range countSongIDs {
+		countValues[i] = counts[id]
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// 1. Insert play_history
+	_, err = tx.Exec(ctx, `
+		INSERT INTO play_history (id, user_id, song_id, played_at)
+		SELECT v.id, v.user_id, v.song_id, v.played_at
+		FROM unnest($1::uuid[], $2::uuid[], $3::uuid[], $4::timestamp[]) AS v(id, user_id, song_id, played_at)
+		WHERE EXISTS (SELECT 1 FROM songs s WHERE s.id = v.song_id)
+			AND EXISTS (SELECT 1 FROM users u WHERE u.id = v.user_id)
+	`, ids, userIDs, songIDs, playedAt)
+	if err != nil {
+		return fmt.Errorf("error inserting play history: %w", err)
+	}
+
+	// 2. Cộng dồn play_count theo từng song
+	_, err = tx.Exec(ctx, `
+		UPDATE songs s
+		SET play_count = COALESCE(s.play_count, 0) + v.plays
+		FROM unnest($1::uuid[], $2::int[]) AS v(song_id, plays)
+		WHERE s.id = v.song_id
+	`, countSongIDs, countValues)
+	if err != nil {
+		return fmt.Errorf("error updating play counts: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// History returns the most recent plays of a user
+func (r *Repository) History(ctx context.Context, userID string, limit int) ([]HistoryEntry, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT s.id::text, s.title, ph.played_at
+		FROM play_history ph
+		INNER JOIN songs s ON s.id = ph.song_id
+		WHERE ph.user_id = $1
+		ORDER BY ph.played_at DESC
+		LIMIT $2
+	`, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error querying play history: %w", err)
+	}
+	defer rows.Close()
+
+	history := []HistoryEntry{}
+	for rows.Next() {
+		var entry HistoryEntry
+		if err := rows.Scan(&entry.SongID, &entry.Title, &entry.PlayedAt); err != nil {
+			return nil, fmt.Errorf("error scanning play history: %w", err)
+		}
+		history = append(history, entry)
+	}
+	return history, rows.Err()
+}
@@ -0,0 +1,14 @@
+package playback
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRoutes registers all playback routes to the given router group
+func RegisterRoutes(rg *gin.RouterGroup, h *Handler, authMiddleware gin.HandlerFunc) {
+	playGroup := rg.Group("/plays", authMiddleware)
+	{
+		playGroup.POST("/events", h.RecordEvent)
+		playGroup.GET("/history", h.History)
+	}
+}
@@ -0,0 +1,195 @@
+package playback
+
+import (
+	"errors"
+	"log"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// PlayThreshold is how long a song must be listened to before it counts as a play
+	PlayThreshold = 30 * time.Second
+
+	// seekTolerance is extra time allowed between two events before a position
+	// jump is treated as a seek instead of listening
+	seekTolerance = 2 * time.Second
+
+	// sessionTTL is how long an idle session is kept
+	sessionTTL = 30 * time.Minute
+)
+
+// ErrSessionNotFound is returned for events of a session that was not
+// granted to the user for the song, or has expired
+var ErrSessionNotFound = errors.New("playback session not found")
+
+// session is the listening state of one playback
+type session struct {
+	songID    string
+	streamed  bool // the song was streamed by the user after the grant
+	started   bool
+	listened  time.Duration
+	lastPos   time.Duration
+	lastEvent time.Time
+	counted   bool
+}
+
+// Tracker accumulates listening time per playback session from player events
+// and emits a Play once a session passes PlayThreshold.
+// Sessions are granted by the server when a stream URL is issued, so a
+// client cannot invent sessions, and a session counts at most one play and
+// only once the song has actually been streamed. Only time that was
+// actually played counts: jumping forward (seek) doesn't.
+type Tracker struct {
+	sessions    map[string]*session // sessionKey -> session
+	users       map[string][]string // user ID -> session IDs, oldest grant first
+	maxSessions int                 // open sessions per user, the oldest is dropped beyond
+	mu          sync.Mutex
+	plays       chan<- Play
+	dropped     atomic.Int64 // plays lost because the recorder was overloaded
+}
+
+// NewTracker creates a tracker that sends counted plays to plays and keeps
+// at most maxSessions open sessions per user
+func NewTracker(plays chan<- Play, maxSessions int) *Tracker {
+	if maxSessions <= 0 {
+		maxSessions = 5
+	}
+	t := &Tracker{
+		sessions:    make(map[string]*session),
+		users:       make(map[string][]string),
+		maxSessions: maxSessions,
+		plays:       plays,
+	}
+
+	// Cleanup goroutine to remove idle sessions
+	go t.cleanup()
+
+	return t
+}
+
+func sessionKey(userID, sessionID string) string {
+	return userID + ":" + sessionID
+}
+
+// cleanup periodically removes idle sessions
+func (t *Tracker) cleanup() {
+	ticker := time.NewTicker(5 * time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		t.mu.Lock()
+		now := time.Now()
+		for userID, ids := range t.users {
+			for _, id := range ids {
+				if now.Sub(t.sessions[sessionKey(userID, id)].lastEvent) > sessionTTL {
+					t.remove(userID, id)
+				}
+			}
+		}
+		t.mu.Unlock()
+	}
+}
+
+// remove deletes a session, t.mu must be held
+func (t *Tracker) remove(userID, sessionID string) {
+	delete(t.sessions, sessionKey(userID, sessionID))
+	ids := slices.DeleteFunc(slices.Clone(t.users[userID]), func(id string) bool { return id == sessionID })
+	if len(ids) == 0 {
+		delete(t.users, userID)
+		return
+	}
+	t.users[userID] = ids
+}
+
+// Grant opens a playback session of songID for userID and returns its ID.
+// It is called when the user is given a stream URL, after the same checks
+// as streaming. Beyond the per-user limit the oldest session is dropped.
+func (t *Tracker) Grant(userID, songID string) string {
+	sessionID := uuid.NewString()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for len(t.users[userID]) >= t.maxSessions {
+		t.remove(userID, t.users[userID][0])
+	}
+	t.sessions[sessionKey(userID, sessionID)] = &session{songID: songID, lastEvent: time.Now()}
+	t.users[userID] = append(t.users[userID], sessionID)
+	return sessionID
+}
+
+// Streamed marks the sessions of songID granted to userID as streamed, it is
+// called when audio of the song is served to the user
+func (t *Tracker) Streamed(userID, songID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, id := range t.users[userID] {
+		if s := t.sessions[sessionKey(userID, id)]; s.songID == songID {
+			s.streamed = true
+		}
+	}
+}
+
+// Record processes an event and reports whether the session has been
+// counted. It returns ErrSessionNotFound if the session was not granted to
+// the user for the event's song.
+func (t *Tracker) Record(e Event) (bool, error) {
+	key := sessionKey(e.UserID, e.SessionID)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s, exists := t.sessions[key]
+	if !exists || s.songID != e.SongID {
+		return false, ErrSessionNotFound
+	}
+	if e.Type == EventStart || !s.started {
+		// Start đặt lại vị trí nhưng không đặt lại counted: mỗi session chỉ tính một lượt nghe
+		s.started = true
+	} else {
+		// Chỉ cộng phần đã thực sự nghe: vị trí tăng không quá thời gian thực đã trôi qua
+		delta := e.Position - s.lastPos
+		elapsed := e.At.Sub(s.lastEvent) + seekTolerance
+		if delta > 0 && delta <= elapsed {
+			s.listened += delta
+		}
+	}
+	s.lastPos = e.Position
+	s.lastEvent = e.At
+
+	if !s.counted && s.streamed && s.listened >= PlayThreshold {
+		s.counted = true
+		play := Play{
+			ID:       uuid.Must(uuid.NewV7()).String(),
+			UserID:   e.UserID,
+			SongID:   e.SongID,
+			PlayedAt: e.At,
+		}
+		// Không block request nếu recorder đang quá tải, lượt nghe bị bỏ được đếm lại
+		select {
+		case t.plays <- play:
+		default:
+			if n := t.dropped.Add(1); n == 1 || n%100 == 0 {
+				log.Printf("Play recorder overloaded, %d plays dropped so far", n)
+			}
+		}
+	}
+
+	counted := s.counted
+	if e.Type == EventEnd {
+		t.remove(e.UserID, e.SessionID)
+	}
+	return counted, nil
+}
+
+// Dropped returns how many counted plays were dropped because the recorder
+// could not keep up
+func (t *Tracker) Dropped() int64 {
+	return t.dropped.Load()
+}
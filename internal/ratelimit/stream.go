@@ -0,0 +1,158 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// StreamLimiter limits how many songs an account can stream at the same time.
+// Connections for the same song share one slot, so a player that opens
+// several Range requests while seeking still counts as a single stream.
+// Streams made of short requests (HLS segments) hold their slot for a while
+// after each request instead of while a connection is open.
+type StreamLimiter struct {
+	active map[string]map[string]int       // account -> stream ID -> open connections
+	held   map[string]map[string]time.Time // account -> stream ID -> slot expiry
+	mu     sync.Mutex
+}
+
+// NewStreamLimiter creates a new concurrent stream limiter
+func NewStreamLimiter() *StreamLimiter {
+	sl := &StreamLimiter{
+		active: make(map[string]map[string]int),
+		held:   make(map[string]map[string]time.Time),
+	}
+
+	// Cleanup goroutine to remove expired held slots
+	go sl.cleanup()
+
+	return sl
+}
+
+// cleanup periodically removes expired held slots
+func (sl *StreamLimiter) cleanup() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		sl.mu.Lock()
+		now := time.Now()
+		for account := range sl.held {
+			sl.pruneHeld(account, now)
+		}
+		sl.mu.Unlock()
+	}
+}
+
+// pruneHeld removes the expired held slots of account, sl.mu must be held
+func (sl *StreamLimiter) pruneHeld(account string, now time.Time) {
+	held := sl.held[account]
+	for streamID, expires := range held {
+		if !now.Before(expires) {
+			delete(held, streamID)
+		}
+	}
+	if len(held) == 0 {
+		delete(sl.held, account)
+	}
+}
+
+// hasSlot reports whether account may stream streamID: it is already
+// streaming it or has fewer than max streams. sl.mu must be held.
+func (sl *StreamLimiter) hasSlot(account, streamID string, max int, now time.Time) bool {
+	sl.pruneHeld(account, now)
+	if _, playing := sl.active[account][streamID]; playing {
+		return true
+	}
+	if _, playing := sl.held[account][streamID]; playing {
+		return true
+	}
+	if max <= 0 {
+		return true
+	}
+	return sl.count(account) < max
+}
+
+// count returns how many distinct streams the account has, sl.mu must be held
+func (sl *StreamLimiter) count(account string) int {
+	n := len(sl.active[account])
+	for streamID := range sl.held[account] {
+		// Stream vừa có connection vừa có slot giữ chỉ tính một lần
+		if _, open := sl.active[account][streamID]; !open {
+			n++
+		}
+	}
+	return n
+}
+
+// Acquire reserves a slot for account to stream streamID.
+// max <= 0 means unlimited. When ok is true, release must be called once the
+// stream ends.
+func (sl *StreamLimiter) Acquire(account, streamID string, max int) (release func(), ok bool) {
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+
+	// Stream mới (không phải bài đang phát) thì phải còn slot trống
+	if !sl.hasSlot(account, streamID, max, time.Now()) {
+		return nil, false
+	}
+
+	streams, exists := sl.active[account]
+	if !exists {
+		streams = make(map[string]int)
+		sl.active[account] = streams
+	}
+	streams[streamID]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { sl.release(account, streamID) })
+	}, true
+}
+
+// Hold reserves a slot for account to stream streamID until ttl after the
+// last call; calling it again for the same stream refreshes the slot.
+// max <= 0 means unlimited.
+func (sl *StreamLimiter) Hold(account, streamID string, max int, ttl time.Duration) bool {
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+
+	now := time.Now()
+	if !sl.hasSlot(account, streamID, max, now) {
+		return false
+	}
+
+	held, exists := sl.held[account]
+	if !exists {
+		held = make(map[string]time.Time)
+		sl.held[account] = held
+	}
+	held[streamID] = now.Add(ttl)
+	return true
+}
+
+func (sl *StreamLimiter) release(account, streamID string) {
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+
+	streams, exists := sl.active[account]
+	if !exists {
+		return
+	}
+	streams[streamID]--
+	if streams[streamID] <= 0 {
+		delete(streams, streamID)
+	}
+	if len(streams) == 0 {
+		delete(sl.active, account)
+	}
+}
+
+// ActiveStreams returns how many distinct streams the account has open
+func (sl *StreamLimiter) ActiveStreams(account string) int {
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+
+	sl.pruneHeld(account, time.Now())
+	return sl.count(account)
+}
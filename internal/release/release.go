@@ -0,0 +1,84 @@
+// Package release defines the visibility of songs and albums and the event
+// emitted when scheduled content goes public.
+package release
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Visibility states of songs and albums
+const (
+	Public    = "public"
+	Scheduled = "scheduled" // public once release_at has passed
+	Private   = "private"   // uploader and admins only
+)
+
+// ErrInvalid is returned for an invalid visibility / release time combination
+var ErrInvalid = errors.New("invalid release")
+
+// Resolve derives the visibility to store from the requested one and the
+// release time: content with a future release time is scheduled.
+// visibility "" means public.
+func Resolve(visibility string, releaseAt *time.Time, now time.Time) (string, *time.Time, error) {
+	switch visibility {
+	case "", Public, Scheduled:
+		if releaseAt == nil {
+			if visibility == Scheduled {
+				return "", nil, fmt.Errorf("%w: release_at is required to schedule a release", ErrInvalid)
+			}
+			return Public, nil, nil
+		}
+		if releaseAt.After(now) {
+			return Scheduled, releaseAt, nil
+		}
+		return Public, releaseAt, nil
+	case Private:
+		if releaseAt != nil {
+			return "", nil, fmt.Errorf("%w: private content has no release_at", ErrInvalid)
+		}
+		return Private, nil, nil
+	default:
+		return "", nil, fmt.Errorf("%w: unknown visibility %q", ErrInvalid, visibility)
+	}
+}
+
+// ThroughScheduler turns publishing hidden content into a release due now,
+// so it goes public through the scheduler and emits a release event like
+// any scheduled release
+func ThroughScheduler(visibility string, releaseAt *time.Time, current string, now time.Time) (string, *time.Time) {
+	if visibility != Public || current == Public {
+		return visibility, releaseAt
+	}
+	if releaseAt == nil || releaseAt.After(now) {
+		releaseAt = &now
+	}
+	return Scheduled, releaseAt
+}
+
+// Event types
+const (
+	TypeSong  = "song"
+	TypeAlbum = "album"
+)
+
+// Event is a song or album that just went public, or public content that
+// was hidden again
+type Event struct {
+	Type      string    `json:"type"`
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	AlbumID   string    `json:"album_id,omitempty"` // songs only
+	ReleaseAt time.Time `json:"release_at"`         // zero for withdrawn content
+}
+
+// Observer is notified of new releases, e.g. to notify followers or update
+// search suggestions
+type Observer interface {
+	NewRelease(ctx context.Context, e Event)
+	// Withdrawn is called when a public album is made private or
+	// rescheduled, songs are reported through song observers
+	Withdrawn(ctx context.Context, e Event)
+}
@@ -0,0 +1,27 @@
+package search
+
+// SearchRequest holds query parameters for GET /search
+type SearchRequest struct {
+	Q      string `form:"q" binding:"required,max=200"`
+	Type   string `form:"type"` // comma separated: song,artist,album,playlist
+	Limit  int    `form:"limit" binding:"min=0,max=50"`
+	Offset int    `form:"offset" binding:"min=0"`
+}
+
+// SearchResponse contains one page of results per requested type
+type SearchResponse struct {
+	Query   string              `json:"query"`
+	Results map[ResultType]Page `json:"results"`
+}
+
+// SuggestRequest holds query parameters for GET /search/suggest
+type SuggestRequest struct {
+	Q     string `form:"q" binding:"required,max=100"`
+	Limit int    `form:"limit" binding:"min=0,max=20"`
+}
+
+// SuggestResponse contains mixed suggestions ordered by score
+type SuggestResponse struct {
+	Query       string       `json:"query"`
+	Suggestions []Suggestion `json:"suggestions"`
+}
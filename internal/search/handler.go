@@ -0,0 +1,83 @@
+package search
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"spotify-clone/internal/middleware"
+	"spotify-clone/internal/parental"
+	"spotify-clone/internal/territory"
+)
+
+// Handler handles HTTP requests for search
+type Handler struct {
+	repo        *Repository
+	suggest     *SuggestIndex
+	territories *territory.Resolver
+	parental    *parental.Policy
+}
+
+// NewHandler creates a new search handler
+func NewHandler(repo *Repository, suggest *SuggestIndex, territories *territory.Resolver, policy *parental.Policy) *Handler {
+	return &Handler{repo: repo, suggest: suggest, territories: territories, parental: policy}
+}
+
+// Search runs a full-text search across songs, artists, albums and playlists
+func (h *Handler) Search(c *gin.Context) {
+	var req SearchRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query: " + err.Error()})
+		return
+	}
+
+	types, ok := parseTypes(req.Type)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid type. Allowed: song, artist, album, playlist"})
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = 10
+	}
+
+	resp := SearchResponse{
+		Query:   req.Q,
+		Results: make(map[ResultType]Page, len(types)),
+	}
+
+	viewer := Viewer{IsAdmin: middleware.IsAdmin(c)}
+	viewer.UserID, _ = middleware.GetUserID(c)
+	viewer.HideExplicit = !h.parental.AllowExplicit(c.Request.Context(), viewer.UserID)
+	if !viewer.IsAdmin {
+		viewer.Country = h.territories.Country(c.Request.Context(), viewer.UserID, c.ClientIP())
+	}
+
+	for _, t := range types {
+		page, err := h.repo.Search(c.Request.Context(), t, req.Q, req.Limit, req.Offset, viewer)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Search failed"})
+			return
+		}
+		resp.Results[t] = *page
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// Suggest returns autocomplete suggestions from the in-memory prefix index
+func (h *Handler) Suggest(c *gin.Context) {
+	var req SuggestRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query: " + err.Error()})
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = 8
+	}
+	userID, _ := middleware.GetUserID(c)
+
+	c.JSON(http.StatusOK, SuggestResponse{
+		Query:       req.Q,
+		Suggestions: h.suggest.Suggest(req.Q, req.Limit, !h.parental.AllowExplicit(c.Request.Context(), userID)),
+	})
+}
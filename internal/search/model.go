@@ -0,0 +1,70 @@
+package search
+
+// ResultType is a kind of searchable content
+type ResultType string
+
+const (
+	TypeSong     ResultType = "song"
+	TypeArtist   ResultType = "artist"
+	TypeAlbum    ResultType = "album"
+	TypePlaylist ResultType = "playlist"
+)
+
+// AllTypes lists every searchable type, in the order results are returned
+var AllTypes = []ResultType{TypeSong, TypeArtist, TypeAlbum, TypePlaylist}
+
+// Hit is a single ranked search result
+type Hit struct {
+	ID       string  `json:"id"`
+	Title    string  `json:"title"`
+	Subtitle string  `json:"subtitle,omitempty"` // artist name for songs/albums, owner for playlists
+	ImageURL string  `json:"image_url,omitempty"`
+	Snippet  string  `json:"snippet"` // HTML-escaped title with matched terms wrapped in <b></b>
+	Rank     float32 `json:"rank"`
+}
+
+// Page is one page of results for a single type
+type Page struct {
+	Hits   []Hit `json:"hits"`
+	Total  int   `json:"total"`
+	Offset int   `json:"offset"`
+	Limit  int   `json:"limit"`
+	Fuzzy  bool  `json:"fuzzy,omitempty"` // true when results come from similarity fallback
+}
+
+// Viewer is the user searching. Unreleased content is only found by its
+// uploader and admins, content not licensed in Country by admins.
+// HideExplicit leaves out explicit songs, for admins too.
+type Viewer struct {
+	UserID       string // "" for anonymous users
+	IsAdmin      bool
+	Country      string // "" when unknown
+	HideExplicit bool
+}
+
+// id returns the user ID as a query parameter, NULL for anonymous users
+func (v Viewer) id() *string {
+	if v.UserID == "" {
+		return nil
+	}
+	return &v.UserID
+}
+
+// country returns the country as a query parameter, NULL when unknown
+func (v Viewer) country() *string {
+	if v.Country == "" {
+		return nil
+	}
+	return &v.Country
+}
+
+// Suggestion is a single autocomplete entry
+type Suggestion struct {
+	Type     ResultType `json:"type"`
+	ID       string     `json:"id"`
+	Title    string     `json:"title"`
+	Subtitle string     `json:"subtitle,omitempty"`
+	ImageURL string     `json:"image_url,omitempty"`
+	Explicit bool       `json:"explicit,omitempty"`
+	Weight   float64    `json:"-"` // popularity: play count, follower count, ...
+}
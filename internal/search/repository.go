@@ -0,0 +1,318 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"spotify-clone/internal/territory"
+)
+
+// typeSpec describes how one content type is searched.
+// document must match the expression of the GIN indexes so they are used.
+type typeSpec struct {
+	from     string // FROM clause, main table aliased as t
+	document string // text that is searched and highlighted
+	subtitle string
+	imageURL string
+	filter   string // extra WHERE condition, optional, may use q.viewer, q.admin, q.country and q.hide_explicit
+	tiebreak string // ORDER BY after rank
+}
+
+var specs = map[ResultType]typeSpec{
+	TypeSong: {
+		from:     "songs t LEFT JOIN albums al ON al.id = t.album_id",
+		document: "t.title",
+		subtitle: `(SELECT a.name FROM song_artists sa
+			INNER JOIN artists a ON a.id = sa.artist_id
+			WHERE sa.song_id = t.id AND sa.role = 'performer'
+			ORDER BY sa.position LIMIT 1)`,
+		imageURL: "al.cover_url",
+		// Song chưa phát hành chỉ hiện với uploader và admin, song không có bản quyền chỉ với admin
+		filter: "t.status = 'ready' AND (t.visibility = 'public' OR q.admin OR t.uploaded_by = q.viewer)" +
+			" AND (q.admin OR (" + territory.Condition("t.territory_allow", "t.territory_deny", "q.country") +
+			" AND " + territory.Condition("al.territory_allow", "al.territory_deny", "q.country") + "))" +
+			" AND NOT (q.hide_explicit AND COALESCE(t.explicit, FALSE))",
+		tiebreak: "COALESCE(t.play_count, 0) DESC, t.id",
+	},
+	TypeArtist: {
+		from:     "artists t LEFT JOIN artist_profiles ap ON ap.artist_id = t.id",
+		document: "t.name",
+		subtitle: "NULL",
+		imageURL: "ap.avatar_url",
+		tiebreak: "t.id",
+	},
+	TypeAlbum: {
+		from:     "albums t LEFT JOIN artists a ON a.id = t.artist_id",
+		document: "t.title",
+		subtitle: "a.name",
+		imageURL: "t.cover_url",
+		filter: `(t.visibility = 'public' OR q.admin
+			OR EXISTS (SELECT 1 FROM songs s WHERE s.album_id = t.id AND s.uploaded_by = q.viewer))
+			AND (q.admin OR ` + territory.Condition("t.territory_allow", "t.territory_deny", "q.country") + `)`,
+		tiebreak: "t.release_date DESC NULLS LAST, t.id",
+	},
+	TypePlaylist: {
+		from:     "playlists t LEFT JOIN users u ON u.id = t.user_id",
+		document: "t.name",
+		subtitle: "u.username",
+		imageURL: "t.cover_url",
+		filter:   "t.is_public",
+		tiebreak: "t.updated_at DESC NULLS LAST, t.id",
+	},
+}
+
+type Repository struct {
+	db *pgxpool.Pool
+}
+
+func NewRepository(db *pgxpool.Pool) *Repository {
+	return &Repository{db: db}
+}
+
+// Search runs a ranked, accent-insensitive full-text search for one content
+// type. When full-text search matches nothing, it falls back to trigram
+// similarity so typos still return the closest titles.
+// Unreleased songs and albums are only found by their uploader and admins.
+func (r *Repository) Search(ctx context.Context, t ResultType, text string, limit, offset int, viewer Viewer) (*Page, error) {
+	spec, ok := specs[t]
+	if !ok {
+		return nil, fmt.Errorf("unsupported search type: %s", t)
+	}
+
+	if tsquery := buildTSQuery(text); tsquery != "" {
+		page, err := r.fullText(ctx, t, spec, text, tsquery, limit, offset, viewer)
+		if err != nil {
+			return nil, err
+		}
+		if len(page.Hits) > 0 {
+			return page, nil
+		}
+
+		// Trang rỗng có thể do offset vượt quá số kết quả, chỉ fallback khi FTS không khớp gì
+		if offset > 0 {
+			matched, err := r.hasFullTextMatch(ctx, t, spec, tsquery, viewer)
+			if err != nil {
+				return nil, err
+			}
+			if matched {
+				return page, nil
+			}
+		}
+	}
+
+	if strings.TrimSpace(text) == "" {
+		return &Page{Hits: []Hit{}, Limit: limit, Offset: offset}, nil
+	}
+	return r.fuzzy(ctx, t, spec, text, limit, offset, viewer)
+}
+
+// snippetDocument returns the document of spec without the highlight
+// markers, so only ts_headline can add them
+func snippetDocument(spec typeSpec) string {
+	return "translate(" + spec.document + ", '" + highlightStart + highlightStop + "', '')"
+}
+
+// fullTextCondition returns the WHERE clause matching spec against q.query
+func fullTextCondition(spec typeSpec) string {
+	where := "to_tsvector('simple_unaccent', " + spec.document + ") @@ q.query"
+	if spec.filter != "" {
+		where += " AND " + spec.filter
+	}
+	return where
+}
+
+// fullText ranks matches by ts_rank, near-misses within the matches by similarity
+func (r *Repository) fullText(ctx context.Context, t ResultType, spec typeSpec, text, tsquery string, limit, offset int, viewer Viewer) (*Page, error) {
+	// count(*) OVER() trả về tổng số kết quả trước khi LIMIT/OFFSET
+	query := `
+		WITH q AS (SELECT to_tsquery('simple_unaccent', $1) AS query, search_normalize($4) AS text,
+			$5::uuid AS viewer, $6::boolean AS admin, $7::text AS country, $8::boolean AS hide_explicit)
+		SELECT
+			t.id::text,
+			` + spec.document + `,
+			COALESCE(` + spec.subtitle + `, ''),
+			COALESCE(` + spec.imageURL + `, ''),
+			ts_headline('simple_unaccent', ` + snippetDocument(spec) + `, q.query,
+				'StartSel=` + highlightStart + `, StopSel=` + highlightStop + `, HighlightAll=true'),
+			ts_rank(to_tsvector('simple_unaccent', ` + spec.document + `), q.query)
+				+ word_similarity(q.text, search_normalize(` + spec.document + `)) AS rank,
+			count(*) OVER()
+		FROM ` + spec.from + `
+		CROSS JOIN q
+		WHERE ` + fullTextCondition(spec) + `
+		ORDER BY rank DESC, ` + spec.tiebreak + `
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.Query(ctx, query, tsquery, limit, offset, text, viewer.id(), viewer.IsAdmin, viewer.country(), viewer.HideExplicit)
+	if err != nil {
+		return nil, fmt.Errorf("error searching %ss: %w", t, err)
+	}
+	return collectPage(rows, t, limit, offset)
+}
+
+// hasFullTextMatch reports whether any row matches the full-text query
+func (r *Repository) hasFullTextMatch(ctx context.Context, t ResultType, spec typeSpec, tsquery string, viewer Viewer) (bool, error) {
+	query := `
+		WITH q AS (SELECT to_tsquery('simple_unaccent', $1) AS query,
+			$2::uuid AS viewer, $3::boolean AS admin, $4::text AS country, $5::boolean AS hide_explicit)
+		SELECT EXISTS (
+			SELECT 1 FROM ` + spec.from + `
+			CROSS JOIN q
+			WHERE ` + fullTextCondition(spec) + `
+		)
+	`
+
+	var matched bool
+	if err := r.db.QueryRow(ctx, query, tsquery, viewer.id(), viewer.IsAdmin, viewer.country(), viewer.HideExplicit).Scan(&matched); err != nil {
+		return false, fmt.Errorf("error searching %ss: %w", t, err)
+	}
+	return matched, nil
+}
+
+// fuzzy ranks rows by trigram word similarity to the query (typo-tolerant)
+func (r *Repository) fuzzy(ctx context.Context, t ResultType, spec typeSpec, text string, limit, offset int, viewer Viewer) (*Page, error) {
+	where := "q.text <% search_normalize(" + spec.document + ")"
+	if spec.filter != "" {
+		where += " AND " + spec.filter
+	}
+
+	query := `
+		WITH q AS (SELECT search_normalize($1) AS text, $4::uuid AS viewer, $5::boolean AS admin, $6::text AS country, $7::boolean AS hide_explicit)
+		SELECT
+			t.id::text,
+			` + spec.document + `,
+			COALESCE(` + spec.subtitle + `, ''),
+			COALESCE(` + spec.imageURL + `, ''),
+			` + snippetDocument(spec) + `,
+			word_similarity(q.text, search_normalize(` + spec.document + `)) AS rank,
+			count(*) OVER()
+		FROM ` + spec.from + `
+		CROSS JOIN q
+		WHERE ` + where + `
+		ORDER BY rank DESC, ` + spec.tiebreak + `
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.Query(ctx, query, text, limit, offset, viewer.id(), viewer.IsAdmin, viewer.country(), viewer.HideExplicit)
+	if err != nil {
+		return nil, fmt.Errorf("error fuzzy searching %ss: %w", t, err)
+	}
+
+	page, err := collectPage(rows, t, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	page.Fuzzy = true
+	return page, nil
+}
+
+// collectPage scans search hits into a page and closes rows
+func collectPage(rows pgx.Rows, t ResultType, limit, offset int) (*Page, error) {
+	defer rows.Close()
+
+	page := &Page{Hits: []Hit{}, Limit: limit, Offset: offset}
+	for rows.Next() {
+		var hit Hit
+		if err := rows.Scan(&hit.ID, &hit.Title, &hit.Subtitle, &hit.ImageURL, &hit.Snippet, &hit.Rank, &page.Total); err != nil {
+			return nil, fmt.Errorf("error scanning %s hit: %w", t, err)
+		}
+		hit.Snippet = highlightHTML(hit.Snippet)
+		page.Hits = append(page.Hits, hit)
+	}
+
+	return page, rows.Err()
+}
+
+// albumSuggestions selects the suggestions of released albums
+const albumSuggestions = `
+	SELECT al.id::text, al.title,
+		COALESCE(a.name, ''),
+		COALESCE(al.cover_url, ''),
+		COALESCE((SELECT sum(s.play_count) FROM songs s WHERE s.album_id = al.id), 0)::bigint
+			+ (SELECT count(*) FROM saved_albums sv WHERE sv.album_id = al.id),
+		FALSE
+	FROM albums al
+	LEFT JOIN artists a ON a.id = al.artist_id
+	WHERE al.visibility = 'public'
+`
+
+// LoadSuggestions reads every released song, artist and album with its
+// popularity weight, used to build the autocomplete index (shared by all
+// users, so unreleased content is left out)
+func (r *Repository) LoadSuggestions(ctx context.Context) ([]Suggestion, error) {
+	queries := []struct {
+		t     ResultType
+		query string
+	}{
+		{TypeSong, `
+			SELECT s.id::text, s.title,
+				COALESCE((SELECT a.name FROM song_artists sa
+					INNER JOIN artists a ON a.id = sa.artist_id
+					WHERE sa.song_id = s.id AND sa.role = 'performer'
+					ORDER BY sa.position LIMIT 1), ''),
+				COALESCE(al.cover_url, ''),
+				COALESCE(s.play_count, 0)::bigint,
+				COALESCE(s.explicit, FALSE)
+			FROM songs s
+			LEFT JOIN albums al ON al.id = s.album_id
+			WHERE s.status = 'ready' AND s.visibility = 'public'
+		`},
+		{TypeArtist, `
+			SELECT a.id::text, a.name, '',
+				COALESCE(ap.avatar_url, ''),
+				(SELECT count(*) FROM followed_artists fa WHERE fa.artist_id = a.id),
+				FALSE
+			FROM artists a
+			LEFT JOIN artist_profiles ap ON ap.artist_id = a.id
+		`},
+		{TypeAlbum, albumSuggestions},
+	}
+
+	var suggestions []Suggestion
+	for _, q := range queries {
+		rows, err := r.db.Query(ctx, q.query)
+		if err != nil {
+			return nil, fmt.Errorf("error loading %s suggestions: %w", q.t, err)
+		}
+
+		for rows.Next() {
+			s := Suggestion{Type: q.t}
+			var weight int64
+			if err := rows.Scan(&s.ID, &s.Title, &s.Subtitle, &s.ImageURL, &weight, &s.Explicit); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("error scanning %s suggestion: %w", q.t, err)
+			}
+			s.Weight = float64(weight)
+			suggestions = append(suggestions, s)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("error loading %s suggestions: %w", q.t, err)
+		}
+	}
+
+	return suggestions, nil
+}
+
+// AlbumSuggestion reads the suggestion of one album, nil if the album does
+// not exist or is not released
+func (r *Repository) AlbumSuggestion(ctx context.Context, id string) (*Suggestion, error) {
+	s := Suggestion{Type: TypeAlbum}
+	var weight int64
+	err := r.db.QueryRow(ctx, albumSuggestions+` AND al.id = $1`, id).
+		Scan(&s.ID, &s.Title, &s.Subtitle, &s.ImageURL, &weight, &s.Explicit)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error loading album suggestion: %w", err)
+	}
+	s.Weight = float64(weight)
+	return &s, nil
+}
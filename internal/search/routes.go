@@ -0,0 +1,16 @@
+package search
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRoutes registers all search routes to the given router group
+func RegisterRoutes(rg *gin.RouterGroup, h *Handler, optionalAuthMiddleware, adminMiddleware gin.HandlerFunc) {
+	searchGroup := rg.Group("/search")
+	{
+		// Nội dung chưa phát hành chỉ hiện với uploader và admin
+		searchGroup.GET("", optionalAuthMiddleware, adminMiddleware, h.Search)
+		// Bài explicit bị ẩn với user không cho phép
+		searchGroup.GET("/suggest", optionalAuthMiddleware, h.Suggest)
+	}
+}
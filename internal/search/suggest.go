@@ -0,0 +1,269 @@
+package search
+
+import (
+	"context"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"spotify-clone/internal/release"
+	"spotify-clone/internal/song"
+)
+
+// prefixEntry is one row of the sorted prefix table.
+// Every item has one entry per word of its title, so "tung" finds "Sơn Tùng".
+type prefixEntry struct {
+	key   string // normalized title, starting at one of its words
+	item  *Suggestion
+	first bool // key starts at the first word of the title
+}
+
+// SuggestIndex is an in-memory prefix index for search-as-you-type.
+// It is built from the database at startup, updated when songs are created
+// or changed and albums released or hidden, and rebuilt periodically to
+// pick up popularity changes.
+type SuggestIndex struct {
+	repo *Repository
+
+	mu      sync.RWMutex
+	entries []prefixEntry // sorted by key
+	items   map[string]*Suggestion
+}
+
+// NewSuggestIndex creates an empty suggestion index
+func NewSuggestIndex(repo *Repository) *SuggestIndex {
+	return &SuggestIndex{
+		repo:  repo,
+		items: make(map[string]*Suggestion),
+	}
+}
+
+func itemKey(t ResultType, id string) string {
+	return string(t) + ":" + id
+}
+
+// entriesFor builds the prefix entries of an item
+func entriesFor(item *Suggestion) []prefixEntry {
+	words := strings.Fields(normalize(item.Title))
+	entries := make([]prefixEntry, 0, len(words))
+	for i := range words {
+		entries = append(entries, prefixEntry{
+			key:   strings.Join(words[i:], " "),
+			item:  item,
+			first: i == 0,
+		})
+	}
+	return entries
+}
+
+// Load rebuilds the whole index from the database
+func (ix *SuggestIndex) Load(ctx context.Context) error {
+	suggestions, err := ix.repo.LoadSuggestions(ctx)
+	if err != nil {
+		return err
+	}
+
+	items := make(map[string]*Suggestion, len(suggestions))
+	var entries []prefixEntry
+	for i := range suggestions {
+		item := &suggestions[i]
+		items[itemKey(item.Type, item.ID)] = item
+		entries = append(entries, entriesFor(item)...)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+
+	ix.mu.Lock()
+	ix.entries = entries
+	ix.items = items
+	ix.mu.Unlock()
+
+	return nil
+}
+
+// Run rebuilds the index every interval until ctx is cancelled.
+// interval <= 0 disables periodic rebuilds.
+func (ix *SuggestIndex) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := ix.Load(ctx); err != nil {
+				log.Println("Failed to refresh suggestion index:", err)
+			}
+		}
+	}
+}
+
+// Add inserts an item, replacing any existing item with the same type and ID
+func (ix *SuggestIndex) Add(s Suggestion) {
+	item := &s
+	newEntries := entriesFor(item)
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	key := itemKey(s.Type, s.ID)
+	if old, exists := ix.items[key]; exists {
+		ix.removeEntriesLocked(old)
+	}
+	ix.items[key] = item
+
+	// Chèn từng entry vào đúng vị trí để bảng luôn được sắp xếp
+	for _, e := range newEntries {
+		i := sort.Search(len(ix.entries), func(i int) bool { return ix.entries[i].key >= e.key })
+		ix.entries = append(ix.entries, prefixEntry{})
+		copy(ix.entries[i+1:], ix.entries[i:])
+		ix.entries[i] = e
+	}
+}
+
+// Remove deletes an item from the index
+func (ix *SuggestIndex) Remove(t ResultType, id string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	key := itemKey(t, id)
+	if old, exists := ix.items[key]; exists {
+		ix.removeEntriesLocked(old)
+		delete(ix.items, key)
+	}
+}
+
+func (ix *SuggestIndex) removeEntriesLocked(item *Suggestion) {
+	kept := ix.entries[:0]
+	for _, e := range ix.entries {
+		if e.item != item {
+			kept = append(kept, e)
+		}
+	}
+	ix.entries = kept
+}
+
+// songSuggestion converts a song into a suggestion
+func songSuggestion(s song.Song) Suggestion {
+	suggestion := Suggestion{
+		Type:     TypeSong,
+		ID:       s.ID,
+		Title:    s.Title,
+		Explicit: s.Explicit,
+		Weight:   float64(s.PlayCount),
+	}
+	for _, a := range s.Artists {
+		if a.Role == song.RolePerformer {
+			suggestion.Subtitle = a.Name
+			break
+		}
+	}
+	if s.Album != nil {
+		suggestion.ImageURL = s.Album.CoverURL
+	}
+	return suggestion
+}
+
+// SongCreated implements song.SongObserver
+func (ix *SuggestIndex) SongCreated(s song.Song) {
+	ix.SongUpdated(s)
+}
+
+// SongUpdated implements song.SongObserver. Only released songs are suggested.
+func (ix *SuggestIndex) SongUpdated(s song.Song) {
+	if !s.Listed() {
+		ix.Remove(TypeSong, s.ID)
+		return
+	}
+	ix.Add(songSuggestion(s))
+}
+
+// SongDeleted implements song.SongObserver
+func (ix *SuggestIndex) SongDeleted(id string) {
+	ix.Remove(TypeSong, id)
+}
+
+// NewRelease implements release.Observer: released albums are added
+func (ix *SuggestIndex) NewRelease(ctx context.Context, e release.Event) {
+	// Song được cập nhật qua SongObserver
+	if e.Type != release.TypeAlbum {
+		return
+	}
+	s, err := ix.repo.AlbumSuggestion(ctx, e.ID)
+	if err != nil {
+		log.Println("Failed to index released album:", err)
+		return
+	}
+	if s != nil {
+		ix.Add(*s)
+	}
+}
+
+// Withdrawn implements release.Observer: hidden albums are removed
+func (ix *SuggestIndex) Withdrawn(ctx context.Context, e release.Event) {
+	if e.Type == release.TypeAlbum {
+		ix.Remove(TypeAlbum, e.ID)
+	}
+}
+
+// Suggest returns the top suggestions whose title has a word starting with q.
+// Score = log(1 + popularity), with a bonus when the title itself starts with q.
+// hideExplicit leaves out explicit songs.
+func (ix *SuggestIndex) Suggest(q string, limit int, hideExplicit bool) []Suggestion {
+	prefix := normalize(q)
+	if prefix == "" || limit <= 0 {
+		return []Suggestion{}
+	}
+
+	type scored struct {
+		item  *Suggestion
+		score float64
+	}
+	best := make(map[*Suggestion]float64)
+
+	ix.mu.RLock()
+	start := sort.Search(len(ix.entries), func(i int) bool { return ix.entries[i].key >= prefix })
+	for i := start; i < len(ix.entries) && strings.HasPrefix(ix.entries[i].key, prefix); i++ {
+		e := ix.entries[i]
+		if hideExplicit && e.item.Explicit {
+			continue
+		}
+		score := math.Log1p(e.item.Weight)
+		if e.first {
+			score += 1
+			if e.key == prefix {
+				score += 2
+			}
+		}
+		if current, seen := best[e.item]; !seen || score > current {
+			best[e.item] = score
+		}
+	}
+	ix.mu.RUnlock()
+
+	results := make([]scored, 0, len(best))
+	for item, score := range best {
+		results = append(results, scored{item: item, score: score})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].score != results[j].score {
+			return results[i].score > results[j].score
+		}
+		return results[i].item.Title < results[j].item.Title
+	})
+
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	suggestions := make([]Suggestion, len(results))
+	for i, r := range results {
+		suggestions[i] = *r.item
+	}
+	return suggestions
+}
@@ -0,0 +1,89 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// maxTerms limits how many words of the query are searched
+const maxTerms = 8
+
+// Markers put around matched terms by ts_headline, private use characters
+// removed from titles before highlighting. The snippet is HTML-escaped
+// first, then the markers become <b></b>, so titles cannot inject markup.
+const (
+	highlightStart = "\uE000"
+	highlightStop  = "\uE001"
+)
+
+// highlightHTML escapes a ts_headline snippet and turns its markers into <b></b>
+func highlightHTML(snippet string) string {
+	return strings.NewReplacer(highlightStart, "<b>", highlightStop, "</b>").Replace(html.EscapeString(snippet))
+}
+
+// buildTSQuery turns free text into a to_tsquery expression.
+// All words must match and the last word is a prefix ("tung" -> 'tung':*) so
+// results update while the user is still typing.
+// Only letters and digits are kept, so the output is always valid tsquery syntax.
+func buildTSQuery(text string) string {
+	terms := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(terms) == 0 {
+		return ""
+	}
+	if len(terms) > maxTerms {
+		terms = terms[:maxTerms]
+	}
+
+	for i, term := range terms {
+		terms[i] = "'" + term + "'"
+	}
+	terms[len(terms)-1] += ":*"
+
+	return strings.Join(terms, " & ")
+}
+
+// parseTypes parses a comma separated list of types.
+// An empty list means all types. ok is false on an unknown type.
+func parseTypes(s string) (types []ResultType, ok bool) {
+	if strings.TrimSpace(s) == "" {
+		return AllTypes, true
+	}
+
+	seen := make(map[ResultType]bool)
+	for _, part := range strings.Split(s, ",") {
+		t := ResultType(strings.TrimSpace(part))
+		if _, known := specs[t]; !known {
+			return nil, false
+		}
+		if !seen[t] {
+			seen[t] = true
+			types = append(types, t)
+		}
+	}
+	return types, true
+}
+
+// normalize lowercases text, removes diacritics and collapses everything that
+// is not a letter or digit into single spaces ("Sơn Tùng M-TP" -> "son tung m tp").
+// It mirrors search_normalize() in the database.
+func normalize(text string) string {
+	// Transformer có state nên tạo mới mỗi lần gọi (an toàn khi dùng đồng thời)
+	stripMarks := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	s, _, err := transform.String(stripMarks, strings.ToLower(text))
+	if err != nil {
+		s = strings.ToLower(text)
+	}
+	// đ không phải là dấu kết hợp nên NFD không tách được
+	s = strings.ReplaceAll(s, "đ", "d")
+
+	return strings.Join(strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
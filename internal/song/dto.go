@@ -1,15 +1,136 @@
 package song
 
+import (
+	"time"
+
+	"spotify-clone/internal/territory"
+)
+
 type SongUploadRequest struct {
-	Title     string   `form:"title" json:"title" binding:"required,max=255"`
-	AlbumID   string   `form:"album_id" json:"album_id"`     // optional
-	ArtistIDs []string `form:"artist_ids" json:"artist_ids"` // optional
-	GenreIDs  []string `form:"genre_ids" json:"genre_ids"`   // optional
+	Title     string   `form:"title" json:"title" binding:"omitempty,max=255"` // optional, read from the file's tags
+	AlbumID   string   `form:"album_id" json:"album_id"`                       // optional
+	ArtistIDs []string `form:"artist_ids" json:"artist_ids"`                   // optional
+	GenreIDs  []string `form:"genre_ids" json:"genre_ids"`                     // optional
+
+	// Visibility defaults to public; a future ReleaseAt schedules the song
+	Visibility string     `form:"visibility" json:"visibility" binding:"omitempty,oneof=public scheduled private"`
+	ReleaseAt  *time.Time `form:"release_at" json:"release_at" time_format:"2006-01-02T15:04:05Z07:00"`
+
+	// Explicit marks explicit content; when omitted the file's advisory tag
+	// (iTunes rtng, ITUNESADVISORY) is used
+	Explicit *bool `form:"explicit" json:"explicit"`
+
+	// LinkAsNewRelease allows uploading a file that already exists in the
+	// catalog; the new song shares the stored audio
+	LinkAsNewRelease bool `form:"link_as_new_release" json:"link_as_new_release"`
 }
 
 type SongUploadResponse struct {
 	ID       string `json:"id"`
 	Title    string `json:"title"`
 	Duration int    `json:"duration"`
+	Status   string `json:"status"` // "processing" until background jobs finish
 	Message  string `json:"message"`
 }
+
+// ProcessingJob is the state of one processing step of a song
+type ProcessingJob struct {
+	Kind        string    `json:"kind"`
+	Status      string    `json:"status"`
+	Attempts    int       `json:"attempts"`
+	MaxAttempts int       `json:"max_attempts"`
+	LastError   string    `json:"last_error,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ProcessingResponse is the body of GET /songs/:id/processing
+type ProcessingResponse struct {
+	SongID string          `json:"song_id"`
+	Status string          `json:"status"`
+	Jobs   []ProcessingJob `json:"jobs"`
+}
+
+// SongUpdateRequest is the body of PATCH /songs/:id, omitted fields are unchanged
+type SongUpdateRequest struct {
+	Title       *string          `json:"title" binding:"omitempty,min=1,max=255"`
+	AlbumID     *string          `json:"album_id" binding:"omitempty,uuid|len=0"`  // "" removes the album
+	ArtistIDs   *[]string        `json:"artist_ids" binding:"omitempty,dive,uuid"` // replaces the performers
+	Credits     *[]CreditRequest `json:"credits" binding:"omitempty,dive"`         // replaces all credits
+	GenreIDs    *[]string        `json:"genre_ids" binding:"omitempty,dive,uuid"`  // replaces all genres
+	TrackNumber *int             `json:"track_number" binding:"omitempty,min=0"`
+
+	// A future release_at schedules the song, public or private apply now
+	Visibility *string    `json:"visibility" binding:"omitempty,oneof=public scheduled private"`
+	ReleaseAt  *time.Time `json:"release_at"`
+
+	// Replaces the countries the song is licensed in (ISO 3166-1 alpha-2)
+	Territories *territory.Rules `json:"territories"`
+
+	Explicit *bool `json:"explicit"`
+}
+
+// CreditRequest credits an artist on a song. Credits of the same role are
+// ordered as listed, the first performer is the primary artist.
+type CreditRequest struct {
+	ArtistID string `json:"artist_id" binding:"required,uuid"`
+	Role     string `json:"role" binding:"required,oneof=performer featured composer lyricist producer remixer"`
+}
+
+// ListSongsRequest holds query parameters for GET /songs
+type ListSongsRequest struct {
+	Genre         string     `form:"genre" binding:"omitempty,uuid"`
+	Artist        string     `form:"artist" binding:"omitempty,uuid"`
+	Album         string     `form:"album" binding:"omitempty,uuid"`
+	MinDuration   int        `form:"min_duration" binding:"min=0"`
+	MaxDuration   int        `form:"max_duration" binding:"min=0"`
+	CreatedAfter  *time.Time `form:"created_after" time_format:"2006-01-02T15:04:05Z07:00"`
+	CreatedBefore *time.Time `form:"created_before" time_format:"2006-01-02T15:04:05Z07:00"`
+	Sort          string     `form:"sort" binding:"omitempty,oneof=newest most_played title"`
+	Cursor        string     `form:"cursor"`
+	Limit         int        `form:"limit" binding:"min=0,max=100"`
+}
+
+// ListSongsResponse is a page of songs
+type ListSongsResponse struct {
+	Songs      []Song `json:"songs"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// StreamURLRequest controls how a stream URL is signed
+type StreamURLRequest struct {
+	BindIP bool `form:"bind_ip"` // only allow the URL from the caller's IP
+}
+
+// StreamURLResponse is a signed, expiring stream URL
+type StreamURLResponse struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+	SessionID string    `json:"session_id"` // playback session for POST /plays/events
+}
+
+// WaveformRequest selects the size and encoding of waveform data
+type WaveformRequest struct {
+	Points int    `form:"points" binding:"omitempty,min=1,max=4000"`
+	Format string `form:"format" binding:"omitempty,oneof=json dat"`
+}
+
+// LyricsRequest selects the language and encoding of GET /songs/:id/lyrics.
+// Without language, the Accept-Language header picks among the available ones.
+type LyricsRequest struct {
+	Language string `form:"language" binding:"omitempty,max=35"`
+	Format   string `form:"format" binding:"omitempty,oneof=json vtt"`
+}
+
+// LyricsResponse is the JSON body of GET /songs/:id/lyrics
+type LyricsResponse struct {
+	SongLyrics
+	Languages []string `json:"languages"` // all languages the song has lyrics in
+}
+
+// LyricsUploadRequest is the JSON body of PUT /songs/:id/lyrics. Text is
+// plain lyrics (one line per line) or LRC. A text/plain body with
+// ?language= is also accepted.
+type LyricsUploadRequest struct {
+	Language string `json:"language" binding:"omitempty,max=35"` // "und" if omitted
+	Text     string `json:"text" binding:"required,max=65536"`
+}
@@ -2,56 +2,183 @@
 package song
 
 import (
-	"fmt"
+	"context"
+	"errors"
+	"log"
 	"net/http"
-	"os"
-	"spotify-clone/pkg/audioduration"
+	"slices"
+	"spotify-clone/internal/album"
+	"spotify-clone/internal/config"
+	"spotify-clone/internal/jobs"
+	"spotify-clone/internal/middleware"
+	"spotify-clone/internal/parental"
+	"spotify-clone/internal/playback"
+	"spotify-clone/internal/ratelimit"
+	"spotify-clone/internal/release"
+	"spotify-clone/internal/territory"
+	"spotify-clone/internal/user"
+	"spotify-clone/pkg/hls"
+	"spotify-clone/pkg/signedurl"
+	"spotify-clone/pkg/storage"
+	"spotify-clone/pkg/throttle"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 // Handler handles HTTP requests for songs
 type Handler struct {
-	repo *Repository
+	repo          *Repository
+	userRepo      user.UserRepository
+	blob          storage.Blob
+	ingestor      *Ingestor
+	streamLimiter *ratelimit.StreamLimiter
+	streamBuckets *throttle.Buckets
+	streamCfg     config.StreamConfig
+	signer        *signedurl.Signer
+	hlsCache      *hls.Cache
+	queue         *jobs.Queue
+	releases      *Scheduler
+	territories   *territory.Resolver
+	parental      *parental.Policy
+	plays         *playback.Tracker
 }
 
 // NewHandler creates a new song handler
-func NewHandler(repo *Repository) *Handler {
-	return &Handler{repo: repo}
+func NewHandler(repo *Repository, userRepo user.UserRepository, blob storage.Blob, streamLimiter *ratelimit.StreamLimiter, streamCfg config.StreamConfig, hlsCache *hls.Cache, queue *jobs.Queue, releases *Scheduler, territories *territory.Resolver, policy *parental.Policy, plays *playback.Tracker) *Handler {
+	return &Handler{
+		repo:          repo,
+		userRepo:      userRepo,
+		blob:          blob,
+		ingestor:      NewIngestor(repo, blob),
+		streamLimiter: streamLimiter,
+		streamBuckets: throttle.NewBuckets(),
+		streamCfg:     streamCfg,
+		signer:        signedurl.NewSigner(streamCfg.URLSecret),
+		hlsCache:      hlsCache,
+		queue:         queue,
+		releases:      releases,
+		territories:   territories,
+		parental:      policy,
+		plays:         plays,
+	}
+}
+
+// GetStreamURL returns a signed, expiring URL for streaming a song.
+// The URL can be used directly in an <audio> tag without auth headers.
+func (h *Handler) GetStreamURL(c *gin.Context) {
+	songID := c.Param("id")
+
+	var req StreamURLRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query: " + err.Error()})
+		return
+	}
+
+	userID, _ := middleware.GetUserID(c)
+
+	song, err := h.repo.GetByID(c.Request.Context(), songID)
+	if err != nil || !canView(c, song) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Song not found"})
+		return
+	}
+	if !requireReady(c, song) || !h.requireAvailable(c, song, userID) || !h.requireExplicitAllowed(c, song, userID) {
+		return
+	}
+
+	claims := signedurl.Claims{
+		Resource: songID,
+		UserID:   userID,
+		Expires:  time.Now().Add(h.streamCfg.URLExpiry),
+	}
+	// Gắn URL với IP của client nếu được yêu cầu (chống chia sẻ link)
+	if req.BindIP {
+		claims.IP = c.ClientIP()
+	}
+
+	query := h.signer.Sign(claims)
+	c.JSON(http.StatusOK, StreamURLResponse{
+		URL:       "/api/songs/" + songID + "/stream?" + query.Encode(),
+		ExpiresAt: claims.Expires,
+		// Player gửi play event với session này, lượt nghe chỉ được tính khi bài đã được stream
+		SessionID: h.plays.Grant(userID, songID),
+	})
 }
 
 // StreamSong streams audio file for a song
 func (h *Handler) StreamSong(c *gin.Context) {
 	songID := c.Param("id")
 
+	// Kiểm tra chữ ký URL trước khi đụng tới DB
+	claims, ok := h.verifyStreamURL(c, songID)
+	if !ok {
+		return
+	}
+
 	// Lấy song từ DB
 	song, err := h.repo.GetByID(c.Request.Context(), songID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Song not found"})
 		return
 	}
+	if !requireReady(c, song) || !h.requireAvailable(c, song, claims.UserID) || !h.requireExplicitAllowed(c, song, claims.UserID) {
+		return
+	}
 
-	// Mở file audio
-	file, err := os.Open(song.FileURL)
+	// Lấy thông tin file từ storage
+	info, err := h.blob.Stat(c.Request.Context(), song.FileURL)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		if errors.Is(err, storage.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Cannot read file info"})
 		return
 	}
-	defer file.Close()
 
-	// Lấy thông tin file
-	stat, err := file.Stat()
+	// Giới hạn số stream đồng thời theo tier (free: 1 bài cùng lúc)
+	account, tier := h.streamIdentity(c, claims.UserID)
+	limits := h.limitsFor(tier)
+	release, ok := h.streamLimiter.Acquire(account, song.ID, limits.MaxStreams)
+	if !ok {
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error": "too many concurrent streams",
+			"tier":  tier,
+		})
+		return
+	}
+	defer release()
+	if claims.UserID != "" {
+		h.plays.Streamed(claims.UserID, song.ID)
+	}
+
+	// Mở file audio
+	file, err := h.blob.Open(c.Request.Context(), song.FileURL)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Cannot read file info"})
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
 		return
 	}
+	defer file.Close()
 
 	// Set headers cho streaming
-	c.Header("Content-Type", "audio/mpeg")
+	c.Header("Content-Type", audioContentType(song.AudioFormat))
 	c.Header("Accept-Ranges", "bytes")
 
+	// File lưu theo SHA-256 nên nội dung không bao giờ đổi -> strong ETag + cache lâu.
+	// http.ServeContent dùng ETag để xử lý If-None-Match (304) và If-Range (resume).
+	// URL đã ký theo từng user nên chỉ cho browser cache, không cho shared cache
+	if song.FileDigest != "" {
+		c.Header("ETag", `"`+song.FileDigest+`"`)
+		c.Header("Cache-Control", "private, max-age=31536000, immutable")
+	} else {
+		c.Header("Cache-Control", "private, max-age=3600")
+	}
+
+	// Giới hạn băng thông theo tier, chung cho mọi request (Range) của stream
+	reader := throttle.NewReadSeeker(c.Request.Context(), file, h.streamBucket(account, song.ID, limits))
+
 	// Gin's http.ServeContent tự động xử lý Range requests cho seek/skip
-	http.ServeContent(c.Writer, c.Request, song.Title, stat.ModTime(), file)
+	http.ServeContent(c.Writer, c.Request, song.Title, info.LastModified, reader)
 }
 
 // GetSong returns song details as JSON
@@ -59,20 +186,89 @@ func (h *Handler) GetSong(c *gin.Context) {
 	songID := c.Param("id")
 
 	song, err := h.repo.GetByID(c.Request.Context(), songID)
-	if err != nil {
+	if err != nil || !canView(c, song) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Song not found"})
 		return
 	}
+	if !h.requireAllowed(c, song) {
+		return
+	}
 
 	c.JSON(http.StatusOK, song)
 }
 
+// ListSongs returns a page of songs with filtering, sorting and cursor pagination
+func (h *Handler) ListSongs(c *gin.Context) {
+	var req ListSongsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query: " + err.Error()})
+		return
+	}
+
+	filter := ListSongsFilter{
+		GenreID:       req.Genre,
+		ArtistID:      req.Artist,
+		AlbumID:       req.Album,
+		MinDuration:   req.MinDuration,
+		MaxDuration:   req.MaxDuration,
+		CreatedAfter:  req.CreatedAfter,
+		CreatedBefore: req.CreatedBefore,
+		Sort:          SongSort(req.Sort),
+		Limit:         req.Limit,
+		ViewerIsAdmin: middleware.IsAdmin(c),
+	}
+	filter.ViewerID, _ = middleware.GetUserID(c)
+	filter.HideExplicit = !h.parental.AllowExplicit(c.Request.Context(), filter.ViewerID)
+	if !filter.ViewerIsAdmin {
+		filter.Country = h.territories.Country(c.Request.Context(), filter.ViewerID, c.ClientIP())
+		// Cả album không có bản quyền ở nước này
+		if filter.AlbumID != "" {
+			var unavailable *territory.UnavailableError
+			rules, err := h.repo.GetAlbumTerritories(c.Request.Context(), filter.AlbumID)
+			if err == nil && errors.As(rules.Check(filter.Country, "album"), &unavailable) {
+				unavailableResponse(c, unavailable)
+				return
+			}
+		}
+	}
+	if filter.Sort == "" {
+		filter.Sort = SortNewest
+	}
+	if filter.Limit == 0 {
+		filter.Limit = 20
+	}
+	if req.Cursor != "" {
+		cursor, err := DecodeCursor(req.Cursor, filter.Sort)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
+		filter.Cursor = cursor
+	}
+
+	songs, next, err := h.repo.ListSongs(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list songs"})
+		return
+	}
+
+	resp := ListSongsResponse{Songs: songs}
+	if resp.Songs == nil {
+		resp.Songs = []Song{}
+	}
+	if next != nil {
+		resp.NextCursor = EncodeCursor(*next)
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
 // Allowed audio MIME types
 var allowedAudioTypes = map[string]bool{
 	"audio/mpeg": true, // MP3
 	"audio/mp3":  true, // MP3 (alternative)
-	// "audio/wav":   true, // WAV
-	// "audio/wave":  true, // WAV (alternative)
+	"audio/wav":  true, // WAV
+	"audio/wave": true, // WAV (alternative)
 	"audio/ogg":  true, // OGG
 	"audio/flac": true, // FLAC
 	// "audio/aac":   true, // AAC
@@ -96,7 +292,6 @@ func (h *Handler) UploadSong(c *gin.Context) {
 		return
 	}
 
-	// 3. Validate file type by reading magic bytes (more reliable than Content-Type header)
 	file, err := fileHeader.Open()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Cannot read file"})
@@ -104,98 +299,332 @@ func (h *Handler) UploadSong(c *gin.Context) {
 	}
 	defer file.Close()
 
-	// Read first 512 bytes to detect content type
-	buffer := make([]byte, 512)
-	_, err = file.Read(buffer)
+	uploaderID, _ := middleware.GetUserID(c)
+
+	// 3. Validate, hash, store and save to database
+	song, err := h.ingestor.Ingest(c.Request.Context(), IngestInput{
+		File:           file,
+		Filename:       fileHeader.Filename,
+		Title:          req.Title,
+		AlbumID:        req.AlbumID,
+		ArtistIDs:      req.ArtistIDs,
+		GenreIDs:       req.GenreIDs,
+		UploadedBy:     uploaderID,
+		IsAdmin:        middleware.IsAdmin(c),
+		Visibility:     req.Visibility,
+		ReleaseAt:      req.ReleaseAt,
+		Explicit:       req.Explicit,
+		AllowDuplicate: req.LinkAsNewRelease,
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Cannot read file content"})
+		c.JSON(IngestErrorResponse(err))
 		return
 	}
 
-	// Detect audio format using magic bytes
-	audioType := -1
+	// 4. Return success response
+	c.JSON(http.StatusCreated, SongUploadResponse{
+		ID:       song.ID,
+		Title:    song.Title,
+		Duration: song.Duration,
+		Status:   song.Status,
+		Message:  "Song uploaded, processing",
+	})
+}
 
-	// Check for MP3 (ID3 tag or MP3 frame sync)
-	if (len(buffer) >= 3 && buffer[0] == 0x49 && buffer[1] == 0x44 && buffer[2] == 0x33) || // ID3v2 tag
-		(len(buffer) >= 2 && buffer[0] == 0xFF && (buffer[1]&0xE0) == 0xE0) { // MP3 frame sync
-		audioType = audioduration.TypeMp3
+// IngestErrorResponse maps an Ingest error to the HTTP status and body
+// returned by upload endpoints
+func IngestErrorResponse(err error) (int, gin.H) {
+	var dup *DuplicateError
+	switch {
+	case errors.Is(err, ErrUnsupportedFormat):
+		return http.StatusBadRequest, gin.H{
+			"error": "Invalid file type. Allowed: MP3, OGG, FLAC, WAV",
+		}
+	case errors.Is(err, release.ErrInvalid):
+		return http.StatusBadRequest, gin.H{"error": err.Error()}
+	case errors.Is(err, album.ErrAlbumNotFound):
+		return http.StatusBadRequest, gin.H{"error": "Album not found"}
+	case errors.Is(err, album.ErrAlbumNotOwned):
+		return http.StatusForbidden, gin.H{"error": "Songs can only be added to your own albums"}
+	case errors.Is(err, ErrUnknownArtist):
+		return http.StatusBadRequest, gin.H{"error": "Artist not found"}
+	case errors.Is(err, ErrUnknownGenre):
+		return http.StatusBadRequest, gin.H{"error": "Genre not found"}
+	case errors.As(err, &dup):
+		body := gin.H{
+			"error":   "This audio file already exists in the catalog",
+			"message": "Set link_as_new_release=true to add it as a new release",
+		}
+		// Không lộ ID của song mà uploader không xem được
+		if dup.SongID != "" {
+			body["existing_song_id"] = dup.SongID
+		}
+		return http.StatusConflict, body
+	default:
+		return http.StatusInternalServerError, gin.H{"error": "Failed to upload song: " + err.Error()}
 	}
+}
 
-	// Check for OGG (magic: OggS)
-	if len(buffer) >= 4 && buffer[0] == 0x4F && buffer[1] == 0x67 && buffer[2] == 0x67 && buffer[3] == 0x53 {
-		audioType = audioduration.TypeOgg
+// GetProcessing returns the processing status of a song and its jobs.
+// Only the uploader or an admin can see it.
+func (h *Handler) GetProcessing(c *gin.Context) {
+	songID := c.Param("id")
+
+	song, err := h.repo.GetByID(c.Request.Context(), songID)
+	if err != nil {
+		if errors.Is(err, ErrSongNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Song not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load song"})
+		return
+	}
+	if !canModify(c, song) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the uploader or an admin can view processing status"})
+		return
 	}
 
-	// Check for FLAC (magic: fLaC)
-	if len(buffer) >= 4 && buffer[0] == 0x66 && buffer[1] == 0x4C && buffer[2] == 0x61 && buffer[3] == 0x43 {
-		audioType = audioduration.TypeFlac
+	list, err := h.queue.ListBySong(c.Request.Context(), songID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load processing jobs"})
+		return
 	}
 
-	if audioType == -1 {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid file type. Allowed: MP3, OGG, FLAC",
+	resp := ProcessingResponse{SongID: song.ID, Status: song.Status, Jobs: []ProcessingJob{}}
+	for _, j := range list {
+		resp.Jobs = append(resp.Jobs, ProcessingJob{
+			Kind:        j.Kind,
+			Status:      j.Status,
+			Attempts:    j.Attempts,
+			MaxAttempts: j.MaxAttempts,
+			LastError:   j.LastError,
+			UpdatedAt:   j.UpdatedAt,
 		})
-		return
 	}
+	c.JSON(http.StatusOK, resp)
+}
 
-	// Reset file position for later use
-	file.Seek(0, 0)
+// canModify reports whether the current user may edit or delete the song:
+// only the uploader or an admin
+func canModify(c *gin.Context, song *Song) bool {
+	if middleware.IsAdmin(c) {
+		return true
+	}
+	userID, ok := middleware.GetUserID(c)
+	return ok && song.UploadedBy != "" && song.UploadedBy == userID
+}
 
-	// 4. Generate unique filename with UUID
-	fileExt := getFileExtension(fileHeader.Filename)
-	newFileName := fmt.Sprintf("%s%s", generateUUID(), fileExt)
-	filePath := fmt.Sprintf("./assets/audio/%s", newFileName)
+// requireAvailable rejects songs that are not licensed in the listener's
+// country with 451. userID may be "" for anonymous listeners.
+func (h *Handler) requireAvailable(c *gin.Context, song *Song, userID string) bool {
+	if !song.restricted() {
+		return true
+	}
+	country := h.territories.Country(c.Request.Context(), userID, c.ClientIP())
+	var unavailable *territory.UnavailableError
+	if errors.As(song.Available(country), &unavailable) {
+		unavailableResponse(c, unavailable)
+		return false
+	}
+	return true
+}
+
+// unavailableResponse writes 451 Unavailable For Legal Reasons
+func unavailableResponse(c *gin.Context, err *territory.UnavailableError) {
+	c.JSON(http.StatusUnavailableForLegalReasons, gin.H{
+		"error":   "Content is not available in your country",
+		"reason":  err.Reason,
+		"country": err.Country,
+	})
+}
+
+// requireExplicitAllowed rejects explicit songs with 403 for listeners that
+// do not allow explicit content. userID may be "" for anonymous listeners.
+func (h *Handler) requireExplicitAllowed(c *gin.Context, song *Song, userID string) bool {
+	if !song.Explicit || h.parental.AllowExplicit(c.Request.Context(), userID) {
+		return true
+	}
+	c.JSON(http.StatusForbidden, gin.H{"error": "Explicit content is disabled for this account", "explicit": true})
+	return false
+}
+
+// requireAllowed applies requireAvailable and requireExplicitAllowed to the
+// current user for endpoints serving song data (metadata, lyrics, waveform).
+// The uploader and admins are always allowed, to manage the song.
+func (h *Handler) requireAllowed(c *gin.Context, song *Song) bool {
+	if canModify(c, song) {
+		return true
+	}
+	userID, _ := middleware.GetUserID(c)
+	return h.requireAvailable(c, song, userID) && h.requireExplicitAllowed(c, song, userID)
+}
+
+// canView reports whether the current user may see the song: songs that are
+// not released yet are only visible to the uploader and admins
+func canView(c *gin.Context, song *Song) bool {
+	return song.Visibility == release.Public || canModify(c, song)
+}
 
-	// 5. Save file to disk
-	if err := c.SaveUploadedFile(fileHeader, filePath); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file"})
+// UpdateSong corrects song metadata (title, album, artists, genres, track number)
+// and schedules or hides the song (visibility, release_at)
+func (h *Handler) UpdateSong(c *gin.Context) {
+	songID := c.Param("id")
+
+	var req SongUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
 		return
 	}
 
-	// 6. Get audio duration
-	audioFile, err := os.Open(filePath)
+	song, err := h.repo.GetByID(c.Request.Context(), songID)
 	if err != nil {
-		os.Remove(filePath)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read audio file"})
+		c.JSON(http.StatusNotFound, gin.H{"error": "Song not found"})
 		return
 	}
-	defer audioFile.Close()
+	if !canModify(c, song) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the uploader or an admin can edit this song"})
+		return
+	}
+
+	if req.AlbumID != nil && *req.AlbumID != "" {
+		userID, _ := middleware.GetUserID(c)
+		if err := h.repo.CheckAlbumAccess(c.Request.Context(), *req.AlbumID, userID, middleware.IsAdmin(c)); err != nil {
+			c.JSON(IngestErrorResponse(err))
+			return
+		}
+	}
 
-	duration, err := audioduration.Duration(audioFile, audioType)
+	input := UpdateSongInput{
+		Title:       req.Title,
+		AlbumID:     req.AlbumID,
+		ArtistIDs:   req.ArtistIDs,
+		GenreIDs:    req.GenreIDs,
+		TrackNumber: req.TrackNumber,
+		Explicit:    req.Explicit,
+	}
+	if req.Credits != nil {
+		if req.ArtistIDs != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Use either artist_ids or credits, not both"})
+			return
+		}
+		credits := make([]Credit, 0, len(*req.Credits))
+		for _, cr := range *req.Credits {
+			credit := Credit{ArtistID: cr.ArtistID, Role: cr.Role}
+			if slices.Contains(credits, credit) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Duplicate credit: artist " + cr.ArtistID + " as " + cr.Role})
+				return
+			}
+			credits = append(credits, credit)
+		}
+		input.Credits = &credits
+	}
+	if req.Visibility != nil || req.ReleaseAt != nil {
+		visibility, releaseAt, err := release.Resolve(stringOrEmpty(req.Visibility), req.ReleaseAt, time.Now())
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		// Song ẩn được công khai qua scheduler để phát sự kiện release
+		visibility, releaseAt = release.ThroughScheduler(visibility, releaseAt, song.Visibility, time.Now())
+		input.Visibility, input.ReleaseAt = &visibility, releaseAt
+	}
+	if req.Territories != nil {
+		rules, err := territory.NormalizeRules(*req.Territories)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		input.Territories = &rules
+	}
+	if err := h.repo.UpdateSong(c.Request.Context(), songID, input); err != nil {
+		switch {
+		case errors.Is(err, ErrSongNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Song not found"})
+			return
+		case errors.Is(err, album.ErrAlbumNotFound):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Album not found"})
+			return
+		case errors.Is(err, ErrUnknownArtist):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Artist not found"})
+			return
+		case errors.Is(err, ErrUnknownGenre):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Genre not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update song: " + err.Error()})
+		return
+	}
+	if input.Visibility != nil {
+		if err := h.releases.PublishDue(c.Request.Context()); err != nil {
+			log.Println("Failed to publish releases:", err)
+		}
+	}
 
-	// 7. Create song in database
-	songID := generateUUID()
-	var albumIDPtr *string
-	if req.AlbumID != "" {
-		albumIDPtr = &req.AlbumID
+	updated, err := h.repo.GetByID(c.Request.Context(), songID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load updated song"})
+		return
 	}
 
-	input := CreateSongInput{
-		Song: Song{
-			ID:        songID,
-			Title:     req.Title,
-			Duration:  int(duration),
-			FileURL:   filePath,
-			PlayCount: 0,
-			CreatedAt: getCurrentTime(),
-		},
-		AlbumID:   albumIDPtr,
-		ArtistIDs: req.ArtistIDs,
-		GenreIDs:  req.GenreIDs,
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeleteSong removes a song and its audio file
+func (h *Handler) DeleteSong(c *gin.Context) {
+	songID := c.Param("id")
+
+	song, err := h.repo.GetByID(c.Request.Context(), songID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Song not found"})
+		return
+	}
+	if !canModify(c, song) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the uploader or an admin can delete this song"})
+		return
 	}
 
-	if err := h.repo.CreateSong(c.Request.Context(), input); err != nil {
-		// Rollback: delete uploaded file
-		os.Remove(filePath)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save to database: " + err.Error()})
+	unreferenced, err := h.repo.DeleteSong(c.Request.Context(), songID)
+	if err != nil {
+		if errors.Is(err, ErrSongNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Song not found"})
+			return
+		}
+		log.Println("Failed to delete song:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete song"})
 		return
 	}
 
-	// 8. Return success response
-	c.JSON(http.StatusCreated, SongUploadResponse{
-		ID:       songID,
-		Title:    req.Title,
-		Duration: int(duration),
-		Message:  "Song uploaded successfully",
-	})
+	// File chỉ bị xóa sau khi song đã commit, kể cả khi client ngắt kết nối
+	h.removeAudio(context.WithoutCancel(c.Request.Context()), song, unreferenced)
+
+	c.Status(http.StatusNoContent)
+}
+
+// removeAudio deletes the audio file of a deleted song and its HLS cache
+// entries when no other song uses the file. It is best effort: the song is
+// already deleted, so failures are only logged.
+func (h *Handler) removeAudio(ctx context.Context, song *Song, unreferenced bool) {
+	switch {
+	case song.FileDigest == "":
+		// File lưu trước khi có audio_blobs, không dùng chung với song khác
+		if song.FileURL != "" {
+			if err := h.blob.Delete(ctx, song.FileURL); err != nil {
+				log.Println("Failed to delete audio file:", err)
+			}
+		}
+	case unreferenced:
+		purged, err := h.repo.PurgeBlob(ctx, song.FileDigest, h.blob.Delete)
+		if err != nil {
+			log.Println("Failed to delete audio file:", err)
+			return
+		}
+		if !purged {
+			return // một upload khác vừa dùng lại file
+		}
+	default:
+		return
+	}
+	if err := h.hlsCache.Remove(h.hlsCacheID(song)); err != nil {
+		log.Println("Failed to delete HLS cache:", err)
+	}
 }
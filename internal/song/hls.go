@@ -0,0 +1,246 @@
+package song
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"spotify-clone/pkg/audioduration"
+	"spotify-clone/pkg/hls"
+	"spotify-clone/pkg/signedurl"
+	"spotify-clone/pkg/throttle"
+)
+
+const defaultHLSSegmentDuration = 6 * time.Second
+
+// hlsStreamHold is how many segment durations an HLS stream keeps its
+// concurrent stream slot after a request. Players fetch about one segment
+// per segment duration, so the slot is freed soon after playback stops.
+const hlsStreamHold = 4
+
+// hlsCacheKey is the cache directory of a song's HLS output. Files are content
+// addressed so songs sharing audio share segments; the segment duration is
+// part of the key because changing it changes every segment.
+func (h *Handler) hlsCacheKey(song *Song) string {
+	return fmt.Sprintf("%s/%d", h.hlsCacheID(song), h.hlsSegmentDuration().Milliseconds())
+}
+
+// hlsCacheID is the cache directory holding all HLS entries of the song's file
+func (h *Handler) hlsCacheID(song *Song) string {
+	if song.FileDigest == "" {
+		return "song-" + song.ID
+	}
+	return song.FileDigest
+}
+
+func (h *Handler) hlsSegmentDuration() time.Duration {
+	if h.streamCfg.HLSSegmentDuration <= 0 {
+		return defaultHLSSegmentDuration
+	}
+	return h.streamCfg.HLSSegmentDuration
+}
+
+// hlsPlan returns the segment layout of a song, scanning the audio file once
+// and caching the result
+func (h *Handler) hlsPlan(ctx context.Context, song *Song) ([]hls.Segment, error) {
+	name := h.hlsCacheKey(song) + "/plan.json"
+
+	if f, err := h.hlsCache.Open(name); err == nil {
+		defer f.Close()
+		var segments []hls.Segment
+		if err := json.NewDecoder(f).Decode(&segments); err == nil {
+			return segments, nil
+		}
+		// File cache hỏng -> tạo lại
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	obj, err := h.blob.Open(ctx, song.FileURL)
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+
+	frames, err := audioduration.Mp3Frames(obj)
+	if err != nil {
+		return nil, err
+	}
+	segments := hls.Plan(frames, h.hlsSegmentDuration())
+
+	err = h.hlsCache.Store(name, func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(segments)
+	})
+	if err != nil {
+		// Không cache được vẫn trả playlist
+		log.Println("hls: cache plan:", err)
+	}
+	return segments, nil
+}
+
+// loadHLSSong verifies the signed URL and loads a song that can be served as HLS.
+// On failure it writes the response and returns nil.
+func (h *Handler) loadHLSSong(c *gin.Context) (*Song, *signedurl.Claims) {
+	songID := c.Param("id")
+	claims, ok := h.verifyStreamURL(c, songID)
+	if !ok {
+		return nil, nil
+	}
+
+	song, err := h.repo.GetByID(c.Request.Context(), songID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Song not found"})
+		return nil, nil
+	}
+	if !requireReady(c, song) || !h.requireAvailable(c, song, claims.UserID) || !h.requireExplicitAllowed(c, song, claims.UserID) {
+		return nil, nil
+	}
+	// Hiện tại chỉ đóng gói HLS cho MP3
+	if song.AudioFormat != audioduration.TypeMp3 {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "HLS is only available for MP3 songs"})
+		return nil, nil
+	}
+	return song, claims
+}
+
+// holdHLSStream reserves or refreshes the concurrent stream slot of the
+// song for the current account, writing 429 when the tier's limit is
+// reached. It returns the bandwidth bucket of the stream, shared by all its
+// segments (nil when the tier is not throttled).
+func (h *Handler) holdHLSStream(c *gin.Context, song *Song, claims *signedurl.Claims) (*throttle.Bucket, bool) {
+	account, tier := h.streamIdentity(c, claims.UserID)
+	limits := h.limitsFor(tier)
+	if !h.streamLimiter.Hold(account, song.ID, limits.MaxStreams, hlsStreamHold*h.hlsSegmentDuration()) {
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error": "too many concurrent streams",
+			"tier":  tier,
+		})
+		return nil, false
+	}
+	return h.streamBucket(account, song.ID, limits), true
+}
+
+// GetHLSPlaylist returns the HLS media playlist of a song.
+// Segment URIs carry the same signed parameters as the playlist URL.
+func (h *Handler) GetHLSPlaylist(c *gin.Context) {
+	song, claims := h.loadHLSSong(c)
+	if song == nil {
+		return
+	}
+	if _, ok := h.holdHLSStream(c, song, claims); !ok {
+		return
+	}
+
+	segments, err := h.hlsPlan(c.Request.Context(), song)
+	if err != nil {
+		if errors.Is(err, audioduration.ErrNoMp3Frames) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Audio file has no MP3 frames"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Cannot build playlist"})
+		return
+	}
+
+	query := c.Request.URL.RawQuery
+	var buf bytes.Buffer
+	err = hls.WritePlaylist(&buf, segments, func(i int) string {
+		return fmt.Sprintf("segments/%d.mp3?%s", i, query)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Cannot build playlist"})
+		return
+	}
+
+	// Playlist chứa URL đã ký nên không cho cache lâu
+	c.Header("Cache-Control", "private, no-cache")
+	c.Data(http.StatusOK, "application/vnd.apple.mpegurl", buf.Bytes())
+}
+
+// GetHLSSegment serves one HLS segment, generating and caching it on first use
+func (h *Handler) GetHLSSegment(c *gin.Context) {
+	index, err := strconv.Atoi(strings.TrimSuffix(c.Param("segment"), ".mp3"))
+	if err != nil || index < 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Segment not found"})
+		return
+	}
+
+	song, claims := h.loadHLSSong(c)
+	if song == nil {
+		return
+	}
+	// Mỗi segment giữ lại slot stream của bài, như một connection /stream
+	bucket, ok := h.holdHLSStream(c, song, claims)
+	if !ok {
+		return
+	}
+
+	segments, err := h.hlsPlan(c.Request.Context(), song)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Cannot build playlist"})
+		return
+	}
+	if index >= len(segments) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Segment not found"})
+		return
+	}
+	if claims.UserID != "" {
+		h.plays.Streamed(claims.UserID, song.ID)
+	}
+
+	name := fmt.Sprintf("%s/%d.mp3", h.hlsCacheKey(song), index)
+	file, err := h.hlsCache.Open(name)
+	if errors.Is(err, os.ErrNotExist) {
+		// Cache miss: cắt segment từ file gốc rồi lưu lại
+		err = h.storeHLSSegment(c.Request.Context(), song, segments[index], name)
+		if err == nil {
+			file, err = h.hlsCache.Open(name)
+		}
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Cannot read segment"})
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Cannot read segment"})
+		return
+	}
+
+	c.Header("Content-Type", "audio/mpeg")
+	if song.FileDigest != "" {
+		c.Header("Cache-Control", "private, max-age=31536000, immutable")
+	} else {
+		c.Header("Cache-Control", "private, max-age=3600")
+	}
+
+	// Giới hạn băng thông theo tier như stream thường, bucket chung cho mọi segment
+	reader := throttle.NewReadSeeker(c.Request.Context(), file, bucket)
+
+	http.ServeContent(c.Writer, c.Request, name, info.ModTime(), reader)
+}
+
+// storeHLSSegment cuts a segment from the stored audio into the cache
+func (h *Handler) storeHLSSegment(ctx context.Context, song *Song, seg hls.Segment, name string) error {
+	obj, err := h.blob.Open(ctx, song.FileURL)
+	if err != nil {
+		return err
+	}
+	defer obj.Close()
+
+	return h.hlsCache.Store(name, func(w io.Writer) error {
+		return hls.WriteSegment(w, obj, seg)
+	})
+}
@@ -0,0 +1,303 @@
+package song
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"spotify-clone/internal/release"
+	"spotify-clone/pkg/audioduration"
+	"spotify-clone/pkg/audiotag"
+	"spotify-clone/pkg/storage"
+)
+
+// ErrUnsupportedFormat is returned when the file is not MP3, OGG, FLAC or WAV
+var ErrUnsupportedFormat = errors.New("unsupported audio format")
+
+// DuplicateError is returned when an identical file already exists in the
+// catalog. SongID is empty when the uploader cannot see the existing song.
+type DuplicateError struct {
+	SongID string
+}
+
+func (e *DuplicateError) Error() string {
+	if e.SongID == "" {
+		return "audio file already exists"
+	}
+	return fmt.Sprintf("audio file already exists as song %s", e.SongID)
+}
+
+// IngestInput is an audio file plus the metadata to create its song with
+type IngestInput struct {
+	File       io.Reader
+	Filename   string // original file name, title fallback when there is no tag
+	Title      string // optional, taken from the file's tags when empty
+	AlbumID    string
+	ArtistIDs  []string
+	GenreIDs   []string
+	UploadedBy string
+	IsAdmin    bool // admins may add the song to any album
+
+	// Visibility and ReleaseAt schedule the song (see release.Resolve),
+	// public by default
+	Visibility string
+	ReleaseAt  *time.Time
+
+	// Explicit marks the song's content, nil leaves it to the file's
+	// advisory tag
+	Explicit *bool
+
+	// AllowDuplicate creates the song even if the same file is already in the
+	// catalog ("link as new release"), sharing the stored blob
+	AllowDuplicate bool
+
+	// DryRun validates the file and returns the song that would be created,
+	// with artist/album/genre names from the tags, without storing anything
+	DryRun bool
+}
+
+// Ingestor validates audio files, stores them content-addressed (by SHA-256)
+// and creates songs. It is the single path for adding audio to the catalog.
+// Songs are created in processing state; duration, tags, artwork and
+// waveform are filled in by background jobs (see Processor).
+type Ingestor struct {
+	repo *Repository
+	blob storage.Blob
+}
+
+// NewIngestor creates a new ingestor
+func NewIngestor(repo *Repository, blob storage.Blob) *Ingestor {
+	return &Ingestor{repo: repo, blob: blob}
+}
+
+// CheckAlbum returns an error unless userID may add songs to the album (see
+// Repository.CheckAlbumAccess)
+func (in *Ingestor) CheckAlbum(ctx context.Context, albumID, userID string, isAdmin bool) error {
+	return in.repo.CheckAlbumAccess(ctx, albumID, userID, isAdmin)
+}
+
+// CheckArtists returns ErrUnknownArtist unless every artist exists
+func (in *Ingestor) CheckArtists(ctx context.Context, artistIDs []string) error {
+	return in.repo.CheckArtists(ctx, artistIDs)
+}
+
+// Ingest stores the file and creates the song
+func (in *Ingestor) Ingest(ctx context.Context, input IngestInput) (*Song, error) {
+	// 0. Chỉ owner của album (hoặc admin) được thêm bài vào album, artist phải tồn tại
+	if input.AlbumID != "" {
+		if err := in.CheckAlbum(ctx, input.AlbumID, input.UploadedBy, input.IsAdmin); err != nil {
+			return nil, err
+		}
+	}
+	if err := in.CheckArtists(ctx, input.ArtistIDs); err != nil {
+		return nil, err
+	}
+
+	// 1. Copy to a temp file, hashing while copying
+	tmpFile, err := os.CreateTemp("", "upload-*")
+	if err != nil {
+		return nil, fmt.Errorf("error creating temp file: %w", err)
+	}
+	defer os.Remove(tmpFile.Name())
+	defer tmpFile.Close()
+
+	hasher := sha256.New()
+	size, err := io.Copy(tmpFile, io.TeeReader(input.File, hasher))
+	if err != nil {
+		return nil, fmt.Errorf("error reading upload: %w", err)
+	}
+	digest := hex.EncodeToString(hasher.Sum(nil))
+
+	// 2. Validate file type by reading magic bytes (more reliable than Content-Type header)
+	buffer := make([]byte, 512)
+	n, _ := tmpFile.ReadAt(buffer, 0)
+	audioType := detectAudioType(buffer[:n])
+	if audioType == -1 {
+		return nil, ErrUnsupportedFormat
+	}
+
+	visibility, releaseAt, err := release.Resolve(input.Visibility, input.ReleaseAt, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	// Title tạm lấy từ tên file, job tags sẽ thay bằng title trong tag (nếu có)
+	title := input.Title
+	if title == "" {
+		title = strings.TrimSuffix(filepath.Base(input.Filename), filepath.Ext(input.Filename))
+	}
+	if title == "" || title == "." {
+		title = "Untitled"
+	}
+
+	// 3. Duplicate detection
+	if !input.AllowDuplicate {
+		// ID chỉ được trả về khi uploader xem được song đó
+		existingID, exists, err := in.repo.FindSongIDByDigest(ctx, digest, input.UploadedBy, input.IsAdmin)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, &DuplicateError{SongID: existingID}
+		}
+	}
+
+	if input.DryRun {
+		return dryRunSong(tmpFile, input, title, digest, audioType, visibility, releaseAt)
+	}
+
+	// 4. Reference the blob, then save the file to storage keyed by digest
+	// (skip if already stored). The reference keeps a concurrent delete of
+	// another song with the same file from removing it.
+	fileKey := fmt.Sprintf("audio/%s%s", digest, audioExtension(audioType))
+	if err := in.repo.RetainBlob(ctx, digest, fileKey, size); err != nil {
+		return nil, err
+	}
+	if err := in.storeBlob(ctx, tmpFile, fileKey, size, audioType); err != nil {
+		in.releaseBlob(ctx, digest)
+		return nil, err
+	}
+
+	// 5. Create song in database and queue its processing jobs
+	var albumIDPtr *string
+	if input.AlbumID != "" {
+		albumIDPtr = &input.AlbumID
+	}
+
+	song := Song{
+		ID:          generateUUID(),
+		Title:       truncate(title, 255),
+		FileURL:     fileKey,
+		FileDigest:  digest,
+		AudioFormat: audioType,
+		PlayCount:   0,
+		UploadedBy:  input.UploadedBy,
+		Status:      StatusProcessing,
+		CreatedAt:   getCurrentTime(),
+		Visibility:  visibility,
+		ReleaseAt:   releaseAt,
+		Explicit:    input.Explicit != nil && *input.Explicit,
+	}
+
+	err = in.repo.CreateSong(ctx, CreateSongInput{
+		Song:      song,
+		AlbumID:   albumIDPtr,
+		ArtistIDs: input.ArtistIDs,
+		GenreIDs:  input.GenreIDs,
+		Jobs:      processingJobs(song, input.Title == ""),
+		Explicit:  input.Explicit,
+	})
+	if err != nil {
+		// Rollback: the file is only deleted if no other song references it
+		in.releaseBlob(ctx, digest)
+		return nil, fmt.Errorf("error saving to database: %w", err)
+	}
+
+	return &song, nil
+}
+
+// storeBlob stores the file under key unless it is already stored
+func (in *Ingestor) storeBlob(ctx context.Context, file *os.File, key string, size int64, audioType int) error {
+	_, err := in.blob.Stat(ctx, key)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("error checking stored file: %w", err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err := in.blob.Put(ctx, key, file, size, audioContentType(audioType)); err != nil {
+		return fmt.Errorf("error storing file: %w", err)
+	}
+	return nil
+}
+
+// releaseBlob drops the blob reference of a failed ingest, deleting the
+// file when no other song uses it
+func (in *Ingestor) releaseBlob(ctx context.Context, digest string) {
+	// Vẫn phải trả reference khi request đã bị hủy
+	ctx = context.WithoutCancel(ctx)
+	unreferenced, err := in.repo.ReleaseBlob(ctx, digest)
+	if err != nil {
+		log.Println("ingest: release blob:", err)
+		return
+	}
+	if unreferenced {
+		if _, err := in.repo.PurgeBlob(ctx, digest, in.blob.Delete); err != nil {
+			log.Println("ingest: purge blob:", err)
+		}
+	}
+}
+
+// dryRunSong builds the song Ingest would create, reading tags and duration
+// synchronously. Related data only has the names read from the tags, IDs
+// given in input are kept as is.
+func dryRunSong(file *os.File, input IngestInput, title, digest string, audioType int, visibility string, releaseAt *time.Time) (*Song, error) {
+	tags, err := audiotag.Read(file)
+	if err != nil {
+		if !errors.Is(err, audiotag.ErrNoTags) {
+			log.Println("audiotag:", err)
+		}
+		tags = &audiotag.Tags{}
+	}
+	if input.Title == "" && tags.Title != "" {
+		title = tags.Title
+	}
+	duration, err := audioduration.Duration(file, audioType)
+	if err != nil {
+		return nil, fmt.Errorf("error reading duration: %w", err)
+	}
+
+	song := &Song{
+		Title:       truncate(title, 255),
+		TrackNumber: tags.TrackNumber,
+		Duration:    int(duration),
+		FileURL:     fmt.Sprintf("audio/%s%s", digest, audioExtension(audioType)),
+		FileDigest:  digest,
+		AudioFormat: audioType,
+		UploadedBy:  input.UploadedBy,
+		Status:      StatusReady,
+		Visibility:  visibility,
+		ReleaseAt:   releaseAt,
+	}
+	if explicit := cmp.Or(input.Explicit, tags.Explicit); explicit != nil {
+		song.Explicit = *explicit
+	}
+	if input.AlbumID != "" {
+		song.Album = &Album{ID: input.AlbumID}
+	} else if tags.Album != "" {
+		song.Album = &Album{Title: truncate(tags.Album, 255)}
+	}
+	for i, id := range input.ArtistIDs {
+		song.Artists = append(song.Artists, SongArtist{ID: id, Role: RolePerformer, Position: i, IsPrimary: i == 0})
+	}
+	if len(input.ArtistIDs) == 0 {
+		performers, featured := splitFeaturing(tags.Artists)
+		for i, name := range performers {
+			song.Artists = append(song.Artists, SongArtist{Name: truncate(name, 255), Role: RolePerformer, Position: i, IsPrimary: i == 0})
+		}
+		for i, name := range featured {
+			song.Artists = append(song.Artists, SongArtist{Name: truncate(name, 255), Role: RoleFeatured, Position: i})
+		}
+	}
+	for _, id := range input.GenreIDs {
+		song.Genres = append(song.Genres, Genre{ID: id})
+	}
+	if len(input.GenreIDs) == 0 {
+		for _, name := range tags.Genres {
+			song.Genres = append(song.Genres, Genre{Name: truncate(name, 100)})
+		}
+	}
+	return song, nil
+}
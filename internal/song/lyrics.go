@@ -0,0 +1,324 @@
+package song
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"spotify-clone/pkg/audiotag"
+	"spotify-clone/pkg/lyrics"
+)
+
+const (
+	// undeterminedLanguage is stored when the language of lyrics is unknown
+	undeterminedLanguage = "und"
+	// maxLyricsSize bounds a text/plain lyrics upload
+	maxLyricsSize = 64 << 10
+)
+
+// languageTag accepts BCP 47 like codes: "en", "vie", "pt-br", "zh-hant"
+var languageTag = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})*$`)
+
+// normalizeLanguage lower-cases a language code, "" becomes "und".
+// ok is false for invalid codes.
+func normalizeLanguage(lang string) (string, bool) {
+	lang = strings.ToLower(strings.TrimSpace(strings.ReplaceAll(lang, "_", "-")))
+	if lang == "" {
+		return undeterminedLanguage, true
+	}
+	return lang, len(lang) <= 35 && languageTag.MatchString(lang)
+}
+
+// newSongLyrics converts parsed lyrics for storage
+func newSongLyrics(songID, language, source string, l *lyrics.Lyrics) *SongLyrics {
+	sl := &SongLyrics{SongID: songID, Language: language, Synced: l.Synced, Source: source}
+	for _, line := range l.Lines {
+		sl.Lines = append(sl.Lines, LyricsLine{TimeMS: line.Time.Milliseconds(), Text: line.Text})
+	}
+	return sl
+}
+
+// lyrics converts stored lyrics back for encoding
+func (sl *SongLyrics) lyrics() *lyrics.Lyrics {
+	l := &lyrics.Lyrics{Synced: sl.Synced}
+	for _, line := range sl.Lines {
+		l.Lines = append(l.Lines, lyrics.Line{Time: time.Duration(line.TimeMS) * time.Millisecond, Text: line.Text})
+	}
+	return l
+}
+
+// songDuration is the duration lyrics are validated against. songs.duration
+// is truncated to seconds, so a line may start up to a second after it.
+// Callers make sure the duration is known (PutLyrics, the lyrics job runs
+// after the duration job); 0 disables the check.
+func songDuration(song *Song) time.Duration {
+	if song.Duration <= 0 {
+		return 0
+	}
+	return time.Duration(song.Duration+1) * time.Second
+}
+
+// pickLanguage returns the language of available best matching an
+// Accept-Language header, the first available one if none matches
+func pickLanguage(available []string, acceptLanguage string) string {
+	type pref struct {
+		lang string
+		q    float64
+	}
+	var prefs []pref
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		lang, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		p := pref{lang: strings.ToLower(strings.TrimSpace(lang)), q: 1}
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			v, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+			p.q = v
+		}
+		if p.lang != "" && p.lang != "*" && p.q > 0 {
+			prefs = append(prefs, p)
+		}
+	}
+	sort.SliceStable(prefs, func(i, j int) bool { return prefs[i].q > prefs[j].q })
+
+	for _, p := range prefs {
+		// "en-US" khớp "en-us", rồi "en"
+		primary, _, _ := strings.Cut(p.lang, "-")
+		for _, lang := range available {
+			if lang == p.lang {
+				return lang
+			}
+		}
+		for _, lang := range available {
+			if lang == primary || strings.HasPrefix(lang, primary+"-") {
+				return lang
+			}
+		}
+	}
+	return available[0]
+}
+
+// GetLyrics returns the lyrics of a song as JSON lines, or as WebVTT
+// (format=vtt) for synced lyrics
+func (h *Handler) GetLyrics(c *gin.Context) {
+	var req LyricsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query: " + err.Error()})
+		return
+	}
+	ctx := c.Request.Context()
+	songID := c.Param("id")
+
+	song, err := h.repo.GetByID(ctx, songID)
+	if err != nil {
+		if errors.Is(err, ErrSongNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Song not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load song"})
+		return
+	}
+	if !canView(c, song) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Song not found"})
+		return
+	}
+	if !h.requireAllowed(c, song) {
+		return
+	}
+
+	languages, err := h.repo.ListLyricsLanguages(ctx, songID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load lyrics"})
+		return
+	}
+	if len(languages) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No lyrics for this song"})
+		return
+	}
+
+	language := pickLanguage(languages, c.GetHeader("Accept-Language"))
+	if req.Language != "" {
+		var ok bool
+		if language, ok = normalizeLanguage(req.Language); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid language code"})
+			return
+		}
+	}
+
+	sl, err := h.repo.GetLyrics(ctx, songID, language)
+	if err != nil {
+		if errors.Is(err, ErrLyricsNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No lyrics in this language", "languages": languages})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load lyrics"})
+		return
+	}
+	c.Header("Content-Language", sl.Language)
+	c.Header("Vary", "Accept-Language")
+
+	if req.Format == "vtt" {
+		if !sl.Synced {
+			c.JSON(http.StatusNotAcceptable, gin.H{"error": "WebVTT is only available for time-synced lyrics"})
+			return
+		}
+		var buf bytes.Buffer
+		if err := sl.lyrics().WriteVTT(&buf, time.Duration(song.Duration)*time.Second); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode lyrics"})
+			return
+		}
+		c.Data(http.StatusOK, "text/vtt; charset=utf-8", buf.Bytes())
+		return
+	}
+	c.JSON(http.StatusOK, LyricsResponse{SongLyrics: *sl, Languages: languages})
+}
+
+// PutLyrics uploads plain or LRC lyrics for a language, replacing existing
+// ones. Timestamps must not exceed the song's duration, so lyrics are
+// rejected with 409 until the duration job has run.
+// Only the uploader or an admin can change lyrics.
+func (h *Handler) PutLyrics(c *gin.Context) {
+	var req LyricsUploadRequest
+	if strings.HasPrefix(c.ContentType(), "text/") {
+		// curl --data-binary @song.lrc -H 'Content-Type: text/plain' ...?language=en
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxLyricsSize+1))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+			return
+		}
+		if len(body) > maxLyricsSize {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Lyrics are too long"})
+			return
+		}
+		req.Language = c.Query("language")
+		req.Text = string(body)
+	} else if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	language, ok := normalizeLanguage(req.Language)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid language code"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	song, err := h.repo.GetByID(ctx, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Song not found"})
+		return
+	}
+	if !canModify(c, song) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the uploader or an admin can edit lyrics"})
+		return
+	}
+	// Không kiểm tra được timestamp khi job duration chưa chạy xong
+	if song.Duration <= 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Song duration is not known yet, retry once processing has finished"})
+		return
+	}
+
+	parsed, err := lyrics.Parse(req.Text)
+	if err == nil {
+		err = parsed.Validate(songDuration(song))
+	}
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid lyrics: " + strings.TrimPrefix(err.Error(), "lyrics: ")})
+		return
+	}
+
+	sl := newSongLyrics(song.ID, language, LyricsSourceUpload, parsed)
+	if err := h.repo.SaveLyrics(ctx, sl, true); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save lyrics"})
+		return
+	}
+
+	saved, err := h.repo.GetLyrics(ctx, song.ID, language)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load saved lyrics"})
+		return
+	}
+	c.JSON(http.StatusOK, saved)
+}
+
+// DeleteLyrics removes the lyrics of a song in one language (?language=)
+func (h *Handler) DeleteLyrics(c *gin.Context) {
+	language, ok := normalizeLanguage(c.Query("language"))
+	if !ok || c.Query("language") == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A valid language query parameter is required"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	song, err := h.repo.GetByID(ctx, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Song not found"})
+		return
+	}
+	if !canModify(c, song) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the uploader or an admin can edit lyrics"})
+		return
+	}
+
+	if err := h.repo.DeleteLyrics(ctx, song.ID, language); err != nil {
+		if errors.Is(err, ErrLyricsNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No lyrics in this language"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete lyrics"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// saveTagLyrics stores lyrics embedded in the song's file for languages the
+// song has no lyrics in yet. Synced lyrics win over plain ones of the same
+// language; invalid lyrics are skipped.
+func (p *Processor) saveTagLyrics(ctx context.Context, song *Song, embedded []audiotag.Lyrics) error {
+	sort.SliceStable(embedded, func(i, j int) bool {
+		return len(embedded[i].Lines) > 0 && len(embedded[j].Lines) == 0
+	})
+
+	for _, e := range embedded {
+		language, ok := normalizeLanguage(e.Language)
+		if !ok {
+			language = undeterminedLanguage
+		}
+
+		var parsed *lyrics.Lyrics
+		var err error
+		if len(e.Lines) > 0 {
+			lines := make([]lyrics.Line, len(e.Lines))
+			for i, line := range e.Lines {
+				lines[i] = lyrics.Line{Time: line.Time, Text: line.Text}
+			}
+			parsed, err = lyrics.NewSynced(lines)
+		} else {
+			parsed, err = lyrics.Parse(e.Text) // USLT thường chứa cả LRC
+		}
+		if err == nil {
+			err = parsed.Validate(songDuration(song))
+		}
+		if err != nil {
+			log.Printf("Skipping embedded lyrics of song %s: %v", song.ID, err)
+			continue
+		}
+
+		if err := p.repo.SaveLyrics(ctx, newSongLyrics(song.ID, language, LyricsSourceTag, parsed), false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
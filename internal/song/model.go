@@ -1,20 +1,56 @@
 package song
 
-import "time"
+import (
+	"time"
 
-// Artist represents basic artist info for a song
+	"spotify-clone/internal/jobs"
+	"spotify-clone/internal/release"
+	"spotify-clone/internal/territory"
+)
+
+// Credit roles of artists on a song, in display order
+const (
+	RolePerformer = "performer"
+	RoleFeatured  = "featured"
+	RoleRemixer   = "remixer"
+	RoleComposer  = "composer"
+	RoleLyricist  = "lyricist"
+	RoleProducer  = "producer"
+)
+
+// Artist represents an artist credited on a song. Artists are ordered by
+// role, then by position within the role.
 type SongArtist struct {
 	ID        string `json:"id"`
 	Name      string `json:"name"`
 	ImageURL  string `json:"image_url,omitempty"`
-	IsPrimary bool   `json:"is_primary"`
+	Role      string `json:"role"`
+	Position  int    `json:"position"`
+	IsPrimary bool   `json:"is_primary"` // first performer
+}
+
+// Credit links an artist to a song with a role. Credits of the same role are
+// ordered as in the list they are given in.
+type Credit struct {
+	ArtistID string
+	Role     string
+}
+
+// performerCredits credits artistIDs as performers, the first one primary
+func performerCredits(artistIDs []string) []Credit {
+	credits := make([]Credit, len(artistIDs))
+	for i, id := range artistIDs {
+		credits[i] = Credit{ArtistID: id, Role: RolePerformer}
+	}
+	return credits
 }
 
 // Album represents basic album info for a song
 type Album struct {
-	ID       string `json:"id"`
-	Title    string `json:"title"`
-	CoverURL string `json:"cover_url,omitempty"`
+	ID          string          `json:"id"`
+	Title       string          `json:"title"`
+	CoverURL    string          `json:"cover_url,omitempty"`
+	Territories territory.Rules `json:"territories"`
 }
 
 // Genre represents a music genre
@@ -23,25 +59,177 @@ type Genre struct {
 	Name string `json:"name"`
 }
 
+// Song processing statuses. Uploads are analysed by background jobs and
+// only listed once ready.
+const (
+	StatusProcessing = "processing"
+	StatusReady      = "ready"
+	StatusFailed     = "failed"
+)
+
 // Song represents a song with all related data
 type Song struct {
 	ID          string    `json:"id"`
 	Title       string    `json:"title"`
 	Duration    int       `json:"duration"`
 	FileURL     string    `json:"file_url"`
+	FileDigest  string    `json:"file_digest,omitempty"` // SHA-256 of the audio file
+	AudioFormat int       `json:"-"`                     // audioduration.Type* constant
 	PlayCount   int       `json:"play_count"`
 	TrackNumber int       `json:"track_number,omitempty"`
+	UploadedBy  string    `json:"uploaded_by,omitempty"`
+	Status      string    `json:"status"`
 	CreatedAt   time.Time `json:"created_at"`
 
+	// release.Public, Scheduled or Private; scheduled songs go public at ReleaseAt
+	Visibility string     `json:"visibility"`
+	ReleaseAt  *time.Time `json:"release_at,omitempty"`
+
+	// Countries the song is licensed in, on top of its album's
+	Territories territory.Rules `json:"territories"`
+
+	// Explicit lyrics or content, hidden from listeners that do not allow it
+	Explicit bool `json:"explicit"`
+
+	// Normalization metadata, nil until analysed (WAV/FLAC only)
+	Loudness *Loudness `json:"loudness,omitempty"`
+
 	// Related data (populated via JOINs)
 	Album   *Album       `json:"album,omitempty"`
 	Artists []SongArtist `json:"artists,omitempty"`
 	Genres  []Genre      `json:"genres,omitempty"`
 }
 
+// Listed reports whether the song is visible to everyone
+func (s *Song) Listed() bool {
+	return s.Status == StatusReady && s.Visibility == release.Public
+}
+
+// restricted reports whether the song or its album is limited to some countries
+func (s *Song) restricted() bool {
+	return s.Territories.Restricted() || (s.Album != nil && s.Album.Territories.Restricted())
+}
+
+// Available returns a *territory.UnavailableError if the song or its album
+// cannot be played in country ("" = unknown)
+func (s *Song) Available(country string) error {
+	if err := s.Territories.Check(country, "song"); err != nil {
+		return err
+	}
+	if s.Album != nil {
+		return s.Album.Territories.Check(country, "album")
+	}
+	return nil
+}
+
+// Loudness is the EBU R128 / ReplayGain 2.0 analysis of a song. Gains bring
+// playback to -18 LUFS; peaks are linear, for clipping prevention.
+type Loudness struct {
+	IntegratedLUFS float64  `json:"integrated_lufs"`
+	TruePeakDBTP   float64  `json:"true_peak_dbtp"`
+	TrackGainDB    float64  `json:"track_gain_db"`
+	TrackPeak      float64  `json:"track_peak"`
+	AlbumGainDB    *float64 `json:"album_gain_db,omitempty"` // nil without album
+	AlbumPeak      *float64 `json:"album_peak,omitempty"`
+}
+
+// Lyrics sources
+const (
+	LyricsSourceUpload = "upload"
+	LyricsSourceTag    = "tag" // read from the audio file
+)
+
+// LyricsLine is a line of lyrics. TimeMS is when it starts, 0 if not synced.
+type LyricsLine struct {
+	TimeMS int64  `json:"time_ms"`
+	Text   string `json:"text"`
+}
+
+// SongLyrics are the lyrics of a song in one language
+type SongLyrics struct {
+	SongID    string       `json:"song_id"`
+	Language  string       `json:"language"`
+	Synced    bool         `json:"synced"`
+	Lines     []LyricsLine `json:"lines"`
+	Source    string       `json:"source"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
 type CreateSongInput struct {
 	Song      Song
-	AlbumID   *string  // optional album ID
-	ArtistIDs []string // list of artist IDs (first one is primary)
-	GenreIDs  []string // list of genre IDs
+	AlbumID   *string       // optional album ID
+	ArtistIDs []string      // performers (first one is primary)
+	GenreIDs  []string      // list of genre IDs
+	Jobs      []jobs.NewJob // processing jobs, enqueued in the same transaction
+	Explicit  *bool         // nil = unknown, the file's advisory tag may set it
+}
+
+// TagsUpdate is the metadata read from a song's file by the tags job.
+// Names are matched case-insensitively or created.
+type TagsUpdate struct {
+	Title         string   // set only if not empty
+	TrackNumber   int      // set only if the song has none
+	ArtistNames   []string // performers
+	FeaturedNames []string
+	ComposerNames []string
+	LyricistNames []string
+	AlbumTitle    string
+	AlbumYear     int // release year of a newly created album
+	GenreNames    []string
+	Explicit      *bool // set only if the uploader did not
+}
+
+// UpdateSongInput holds the fields to change, nil means unchanged
+type UpdateSongInput struct {
+	Title       *string
+	AlbumID     *string   // "" removes the album
+	ArtistIDs   *[]string // replaces the performers (first one is primary)
+	Credits     *[]Credit // replaces all credits, exclusive with ArtistIDs
+	GenreIDs    *[]string // replaces all genres
+	TrackNumber *int
+	Visibility  *string    // resolved with release.Resolve
+	ReleaseAt   *time.Time // set together with Visibility
+	Territories *territory.Rules
+	Explicit    *bool
+}
+
+// SongSort is the ordering used when listing songs
+type SongSort string
+
+const (
+	SortNewest     SongSort = "newest"
+	SortMostPlayed SongSort = "most_played"
+	SortTitle      SongSort = "title"
+)
+
+// SongCursor is the keyset position of the last song on a page.
+// Besides created_at and id, it carries the value of the active sort key.
+type SongCursor struct {
+	CreatedAt time.Time `json:"c"`
+	ID        string    `json:"i"`
+	PlayCount *int      `json:"p,omitempty"`
+	Title     string    `json:"t,omitempty"`
+}
+
+// ListSongsFilter holds filters, ordering and pagination for listing songs
+type ListSongsFilter struct {
+	GenreID       string
+	ArtistID      string
+	AlbumID       string
+	MinDuration   int // seconds, 0 = no lower bound
+	MaxDuration   int // seconds, 0 = no upper bound
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Sort          SongSort
+	Cursor        *SongCursor
+	Limit         int
+
+	// Unreleased songs are only listed for their uploader and admins
+	ViewerID      string
+	ViewerIsAdmin bool
+	// Songs not licensed in the viewer's country ("" = unknown) are left out,
+	// except for admins
+	Country string
+	// Explicit songs are left out for listeners that do not allow them
+	HideExplicit bool
 }
@@ -0,0 +1,299 @@
+package song
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"spotify-clone/internal/album"
+	"spotify-clone/internal/jobs"
+	"spotify-clone/pkg/artwork"
+	"spotify-clone/pkg/audioduration"
+	"spotify-clone/pkg/audiotag"
+	"spotify-clone/pkg/loudness"
+	"spotify-clone/pkg/storage"
+)
+
+// Processing job kinds, queued by Ingest for every new song
+const (
+	JobDuration = "song.duration"
+	JobTags     = "song.tags"
+	JobArtwork  = "song.artwork" // queued by the tags job when the file has a cover
+	JobLyrics   = "song.lyrics"  // queued by the duration job, lyrics are validated against the duration
+	JobWaveform = "song.waveform"
+	JobLoudness = "song.loudness"
+)
+
+// criticalJobs are the jobs a song cannot be played without: if one of them
+// fails the song is marked failed, other failures leave it ready
+var criticalJobs = []string{JobDuration}
+
+// tagsPayload is the payload of a JobTags job
+type tagsPayload struct {
+	Title bool `json:"title"` // replace the title (none was given at upload)
+}
+
+// processingJobs returns the jobs analysing a new song
+func processingJobs(song Song, titleFromTags bool) []jobs.NewJob {
+	list := []jobs.NewJob{
+		{Kind: JobDuration, SongID: song.ID},
+		{Kind: JobTags, SongID: song.ID, Payload: tagsPayload{Title: titleFromTags}},
+	}
+	if canDecode(song.AudioFormat) {
+		list = append(list,
+			jobs.NewJob{Kind: JobWaveform, SongID: song.ID},
+			jobs.NewJob{Kind: JobLoudness, SongID: song.ID},
+		)
+	}
+	return list
+}
+
+// Processor runs the processing jobs of uploaded songs and updates their
+// status once all jobs have settled
+type Processor struct {
+	repo    *Repository
+	blob    storage.Blob
+	artwork *artwork.Store
+	queue   *jobs.Queue
+}
+
+// NewProcessor creates a processor
+func NewProcessor(repo *Repository, blob storage.Blob, queue *jobs.Queue) *Processor {
+	return &Processor{repo: repo, blob: blob, artwork: artwork.NewStore(blob), queue: queue}
+}
+
+// Register adds the processing job handlers to w
+func (p *Processor) Register(w *jobs.Worker) {
+	w.Register(JobDuration, p.processDuration)
+	w.Register(JobTags, p.processTags)
+	w.Register(JobArtwork, p.processArtwork)
+	w.Register(JobLyrics, p.processLyrics)
+	w.Register(JobWaveform, p.processWaveform)
+	w.Register(JobLoudness, p.processLoudness)
+	w.AddObserver(p)
+}
+
+// JobSettled implements jobs.Observer
+func (p *Processor) JobSettled(ctx context.Context, job jobs.Job) {
+	if job.SongID == "" {
+		return
+	}
+	if _, err := p.repo.RefreshStatus(ctx, job.SongID, criticalJobs); err != nil {
+		log.Println(err)
+	}
+}
+
+// loadSong returns the song of a job, nil if it was deleted meanwhile
+func (p *Processor) loadSong(ctx context.Context, job *jobs.Job) (*Song, error) {
+	song, err := p.repo.GetByID(ctx, job.SongID)
+	if errors.Is(err, ErrSongNotFound) {
+		return nil, nil
+	}
+	return song, err
+}
+
+// withLocalFile downloads the song's audio to a temp file for parsers that
+// need *os.File. Errors of fn are returned as is.
+func (p *Processor) withLocalFile(ctx context.Context, song *Song, fn func(*os.File) error) error {
+	obj, err := p.blob.Open(ctx, song.FileURL)
+	if err != nil {
+		return fmt.Errorf("error opening audio: %w", err)
+	}
+	defer obj.Close()
+
+	tmpFile, err := os.CreateTemp("", "process-*")
+	if err != nil {
+		return fmt.Errorf("error creating temp file: %w", err)
+	}
+	defer os.Remove(tmpFile.Name())
+	defer tmpFile.Close()
+
+	if _, err := io.Copy(tmpFile, obj); err != nil {
+		return fmt.Errorf("error downloading audio: %w", err)
+	}
+	return fn(tmpFile)
+}
+
+// readTags reads the tags of the song's audio file, empty tags if it has none
+func (p *Processor) readTags(ctx context.Context, song *Song) (*audiotag.Tags, error) {
+	obj, err := p.blob.Open(ctx, song.FileURL)
+	if err != nil {
+		return nil, fmt.Errorf("error opening audio: %w", err)
+	}
+	defer obj.Close()
+
+	tags, err := audiotag.Read(obj)
+	if errors.Is(err, audiotag.ErrNoTags) {
+		return &audiotag.Tags{}, nil
+	}
+	if err != nil {
+		// File không đổi nên đọc lại cũng lỗi y như vậy
+		return nil, jobs.Permanent(fmt.Errorf("error reading tags: %w", err))
+	}
+	return tags, nil
+}
+
+func (p *Processor) processDuration(ctx context.Context, job *jobs.Job) error {
+	song, err := p.loadSong(ctx, job)
+	if song == nil {
+		return err
+	}
+
+	var duration float64
+	err = p.withLocalFile(ctx, song, func(f *os.File) error {
+		d, err := audioduration.Duration(f, song.AudioFormat)
+		if err != nil {
+			return jobs.Permanent(fmt.Errorf("error reading duration: %w", err))
+		}
+		if d <= 0 {
+			return jobs.Permanent(errors.New("audio has no duration"))
+		}
+		duration = d
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := p.repo.SetDuration(ctx, song.ID, int(duration)); err != nil {
+		return err
+	}
+
+	// Lyrics nhúng trong file chỉ được kiểm tra khi đã biết duration
+	return p.queue.Enqueue(ctx, jobs.NewJob{Kind: JobLyrics, SongID: song.ID})
+}
+
+func (p *Processor) processTags(ctx context.Context, job *jobs.Job) error {
+	var payload tagsPayload
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return jobs.Permanent(fmt.Errorf("invalid payload: %w", err))
+	}
+	song, err := p.loadSong(ctx, job)
+	if song == nil {
+		return err
+	}
+	tags, err := p.readTags(ctx, song)
+	if err != nil {
+		return err
+	}
+
+	performers, featured := splitFeaturing(tags.Artists)
+	update := TagsUpdate{
+		TrackNumber:   tags.TrackNumber,
+		ArtistNames:   truncateAll(performers, 255),
+		FeaturedNames: truncateAll(featured, 255),
+		ComposerNames: truncateAll(tags.Composers, 255),
+		LyricistNames: truncateAll(tags.Lyricists, 255),
+		AlbumTitle:    truncate(tags.Album, 255),
+		AlbumYear:     tags.Year,
+		GenreNames:    truncateAll(tags.Genres, 100),
+		Explicit:      tags.Explicit,
+	}
+	if payload.Title {
+		update.Title = truncate(tags.Title, 255)
+	}
+	albumID, err := p.repo.ApplyTags(ctx, song.ID, update)
+	if err != nil {
+		return err
+	}
+
+	// Ảnh bìa nhúng trong file -> cover của album (nếu album chưa có cover)
+	if albumID != "" && tags.Cover() != nil {
+		return p.queue.Enqueue(ctx, jobs.NewJob{Kind: JobArtwork, SongID: song.ID})
+	}
+	return nil
+}
+
+func (p *Processor) processLyrics(ctx context.Context, job *jobs.Job) error {
+	song, err := p.loadSong(ctx, job)
+	if song == nil {
+		return err
+	}
+	tags, err := p.readTags(ctx, song)
+	if err != nil {
+		return err
+	}
+	return p.saveTagLyrics(ctx, song, tags.Lyrics)
+}
+
+func (p *Processor) processArtwork(ctx context.Context, job *jobs.Job) error {
+	song, err := p.loadSong(ctx, job)
+	if song == nil {
+		return err
+	}
+	if song.Album == nil {
+		return nil
+	}
+	tags, err := p.readTags(ctx, song)
+	if err != nil {
+		return err
+	}
+	cover := tags.Cover()
+	if cover == nil {
+		return nil
+	}
+
+	name, err := p.artwork.Put(ctx, cover.Data)
+	if errors.Is(err, artwork.ErrUnsupportedImage) || errors.Is(err, artwork.ErrImageTooLarge) {
+		return jobs.Permanent(err)
+	}
+	if err != nil {
+		return err
+	}
+	return p.repo.SetAlbumCoverIfEmpty(ctx, song.Album.ID, album.CoverURL(name))
+}
+
+func (p *Processor) processWaveform(ctx context.Context, job *jobs.Job) error {
+	song, err := p.loadSong(ctx, job)
+	if song == nil {
+		return err
+	}
+	return p.withLocalFile(ctx, song, func(f *os.File) error {
+		peaks, err := computeWaveform(f, song.AudioFormat)
+		if err != nil {
+			return jobs.Permanent(fmt.Errorf("error computing waveform: %w", err))
+		}
+		if peaks == nil {
+			return nil
+		}
+		return p.repo.SaveWaveform(ctx, song.ID, peaks)
+	})
+}
+
+func (p *Processor) processLoudness(ctx context.Context, job *jobs.Job) error {
+	song, err := p.loadSong(ctx, job)
+	if song == nil {
+		return err
+	}
+
+	var res *loudness.Result
+	err = p.withLocalFile(ctx, song, func(f *os.File) error {
+		dec, err := newDecoder(f, song.AudioFormat)
+		if dec == nil {
+			if err != nil {
+				return jobs.Permanent(fmt.Errorf("error decoding audio: %w", err))
+			}
+			return nil
+		}
+		res, err = loudness.Analyze(dec)
+		if errors.Is(err, loudness.ErrSilent) {
+			res = nil // bài im lặng: không có gain để chuẩn hoá
+			return nil
+		}
+		if err != nil {
+			return jobs.Permanent(fmt.Errorf("error measuring loudness: %w", err))
+		}
+		return nil
+	})
+	if err != nil || res == nil {
+		return err
+	}
+	err = p.repo.SaveLoudness(ctx, song.ID, res)
+	if errors.Is(err, ErrSongNotFound) {
+		return nil // bị xoá trong lúc phân tích
+	}
+	return err
+}
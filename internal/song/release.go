@@ -0,0 +1,88 @@
+package song
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"spotify-clone/internal/release"
+)
+
+// Scheduler publishes scheduled songs and albums when their release time
+// has passed and tells observers about each new release
+type Scheduler struct {
+	repo      *Repository
+	observers []release.Observer
+}
+
+// NewScheduler creates a release scheduler
+func NewScheduler(repo *Repository) *Scheduler {
+	return &Scheduler{repo: repo}
+}
+
+// AddObserver registers an observer for new releases.
+// Must be called during startup, before the scheduler runs.
+func (s *Scheduler) AddObserver(o release.Observer) {
+	s.observers = append(s.observers, o)
+}
+
+// Run publishes due releases every interval until ctx is cancelled.
+// interval <= 0 disables the scheduler.
+func (s *Scheduler) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		// Chạy ngay khi start để bắt kịp các release đến hạn lúc server tắt
+		if err := s.PublishDue(ctx); err != nil {
+			log.Println("Failed to publish releases:", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PublishDue publishes the releases whose time has passed
+func (s *Scheduler) PublishDue(ctx context.Context) error {
+	events, err := s.repo.PublishDue(ctx)
+	if err != nil {
+		return err
+	}
+	for _, e := range events {
+		log.Printf("New release: %s %s %q (scheduled %s)", e.Type, e.ID, e.Title, e.ReleaseAt.Format(time.RFC3339))
+		for _, o := range s.observers {
+			o.NewRelease(ctx, e)
+		}
+	}
+	return nil
+}
+
+// SetAlbumRelease validates and applies the visibility of an album and its
+// songs. Releases due now are published immediately, observers are told
+// when a public album is hidden.
+func (s *Scheduler) SetAlbumRelease(ctx context.Context, albumID, visibility string, releaseAt *time.Time) error {
+	visibility, releaseAt, err := release.Resolve(visibility, releaseAt, time.Now())
+	if err != nil {
+		return err
+	}
+	withdrawn, err := s.repo.SetAlbumRelease(ctx, albumID, visibility, releaseAt)
+	if err != nil {
+		return err
+	}
+	if withdrawn != nil {
+		log.Printf("Release withdrawn: %s %s %q", withdrawn.Type, withdrawn.ID, withdrawn.Title)
+		for _, o := range s.observers {
+			o.Withdrawn(ctx, *withdrawn)
+		}
+	}
+	if err := s.PublishDue(ctx); err != nil {
+		log.Println("Failed to publish releases:", err)
+	}
+	return nil
+}
@@ -2,21 +2,72 @@ package song
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"spotify-clone/internal/album"
+	"spotify-clone/internal/jobs"
+	"spotify-clone/internal/release"
+	"spotify-clone/internal/territory"
+	"spotify-clone/pkg/loudness"
+	"spotify-clone/pkg/waveform"
 )
 
+// ErrSongNotFound is returned when a song does not exist
+var ErrSongNotFound = errors.New("song not found")
+
+// ErrWaveformNotFound is returned when a song has no waveform data
+var ErrWaveformNotFound = errors.New("waveform not found")
+
+// ErrLyricsNotFound is returned when a song has no lyrics in a language
+var ErrLyricsNotFound = errors.New("lyrics not found")
+
+// ErrUnknownArtist is returned when a credited artist does not exist
+var ErrUnknownArtist = errors.New("artist not found")
+
+// ErrUnknownGenre is returned when a linked genre does not exist
+var ErrUnknownGenre = errors.New("genre not found")
+
+// isForeignKeyViolation reports whether err is a foreign key violation,
+// i.e. a referenced album, artist or genre does not exist
+func isForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	// PostgreSQL error code 23503 = foreign_key_violation
+	return errors.As(err, &pgErr) && pgErr.Code == "23503"
+}
+
+// SongObserver is notified after songs are written through the repository,
+// e.g. to keep in-memory indexes up to date
+type SongObserver interface {
+	SongCreated(song Song)
+	SongUpdated(song Song)
+	SongDeleted(id string)
+}
+
 type Repository struct {
-	db *pgxpool.Pool
+	db        *pgxpool.Pool
+	observers []SongObserver
 }
 
 func NewRepository(db *pgxpool.Pool) *Repository {
 	return &Repository{db: db}
 }
 
+// AddObserver registers an observer for song changes.
+// Must be called during startup, before the repository is used.
+func (r *Repository) AddObserver(o SongObserver) {
+	r.observers = append(r.observers, o)
+}
+
 // GetByID fetches a song by ID with album, artists, and genres
 func (r *Repository) GetByID(ctx context.Context, id string) (*Song, error) {
 	// 1. Get song with album info
@@ -42,39 +93,53 @@ func (r *Repository) GetByID(ctx context.Context, id string) (*Song, error) {
 	return song, nil
 }
 
-// getSongWithAlbum fetches song with album data using LEFT JOIN
-func (r *Repository) getSongWithAlbum(ctx context.Context, id string) (*Song, error) {
-	query := `
-		SELECT 
-			s.id, s.title, s.duration, s.file_url, s.play_count, 
-			COALESCE(s.track_number, 0), s.created_at,
-			a.id, a.title, a.cover_url
-		FROM songs s
-		LEFT JOIN albums a ON s.album_id = a.id
-		WHERE s.id = $1
-	`
+// songColumns are the columns read by scanSong, songs aliased as s and albums as a
+const songColumns = `
+	s.id, s.title, s.duration, s.file_url, COALESCE(s.file_digest, ''), COALESCE(s.audio_format, 2),
+	COALESCE(s.play_count, 0),
+	COALESCE(s.track_number, 0), COALESCE(s.uploaded_by::text, ''), s.status, s.created_at,
+	s.visibility, s.release_at, s.territory_allow, s.territory_deny, COALESCE(s.explicit, FALSE),
+	s.loudness_lufs, s.true_peak_dbtp, s.track_gain_db, s.track_peak,
+	a.id, a.title, a.cover_url, a.album_gain_db, a.album_peak, a.territory_allow, a.territory_deny`
 
+// scanSong scans a row selected with songColumns
+func scanSong(row pgx.Row) (*Song, error) {
 	var song Song
 	var albumID, albumTitle, albumCoverURL *string
+	var lufs, truePeak, trackGain, trackPeak, albumGain, albumPeak *float64
+	var albumAllow, albumDeny []string
 
-	err := r.db.QueryRow(ctx, query, id).Scan(
+	err := row.Scan(
 		&song.ID,
 		&song.Title,
 		&song.Duration,
 		&song.FileURL,
+		&song.FileDigest,
+		&song.AudioFormat,
 		&song.PlayCount,
 		&song.TrackNumber,
+		&song.UploadedBy,
+		&song.Status,
 		&song.CreatedAt,
+		&song.Visibility,
+		&song.ReleaseAt,
+		&song.Territories.Allow,
+		&song.Territories.Deny,
+		&song.Explicit,
+		&lufs,
+		&truePeak,
+		&trackGain,
+		&trackPeak,
 		&albumID,
 		&albumTitle,
 		&albumCoverURL,
+		&albumGain,
+		&albumPeak,
+		&albumAllow,
+		&albumDeny,
 	)
-
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, fmt.Errorf("song not found")
-		}
-		return nil, fmt.Errorf("error querying song: %w", err)
+		return nil, err
 	}
 
 	// Set album if exists
@@ -83,21 +148,63 @@ func (r *Repository) getSongWithAlbum(ctx context.Context, id string) (*Song, er
 			ID:       *albumID,
 			Title:    stringOrEmpty(albumTitle),
 			CoverURL: stringOrEmpty(albumCoverURL),
+			Territories: territory.Rules{
+				Allow: albumAllow,
+				Deny:  albumDeny,
+			},
+		}
+	}
+
+	// Các cột loudness được ghi cùng lúc bởi job song.loudness
+	if lufs != nil && truePeak != nil && trackGain != nil && trackPeak != nil {
+		song.Loudness = &Loudness{
+			IntegratedLUFS: *lufs,
+			TruePeakDBTP:   *truePeak,
+			TrackGainDB:    *trackGain,
+			TrackPeak:      *trackPeak,
+		}
+		if albumID != nil {
+			song.Loudness.AlbumGainDB = albumGain
+			song.Loudness.AlbumPeak = albumPeak
 		}
 	}
 
 	return &song, nil
 }
 
-// getArtistsBySongID fetches all artists for a song
+// getSongWithAlbum fetches song with album data using LEFT JOIN
+func (r *Repository) getSongWithAlbum(ctx context.Context, id string) (*Song, error) {
+	query := `SELECT ` + songColumns + `
+		FROM songs s
+		LEFT JOIN albums a ON s.album_id = a.id
+		WHERE s.id = $1
+	`
+
+	song, err := scanSong(r.db.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrSongNotFound
+		}
+		return nil, fmt.Errorf("error querying song: %w", err)
+	}
+
+	return song, nil
+}
+
+// creditOrder orders song_artists (aliased sa) by role, then position
+const creditOrder = `array_position(
+	ARRAY['performer', 'featured', 'remixer', 'composer', 'lyricist', 'producer'], sa.role::text),
+	sa.position`
+
+// getArtistsBySongID fetches all credited artists of a song
 func (r *Repository) getArtistsBySongID(ctx context.Context, songID string) ([]SongArtist, error) {
 	query := `
-		SELECT a.id, a.name, COALESCE(a.image_url, ''), sa.is_primary
+		SELECT a.id, a.name, COALESCE(ap.avatar_url, ''), sa.role, sa.position, COALESCE(sa.is_primary, FALSE)
 		FROM artists a
 		INNER JOIN song_artists sa ON a.id = sa.artist_id
+		LEFT JOIN artist_profiles ap ON ap.artist_id = a.id
 		WHERE sa.song_id = $1
-		ORDER BY sa.is_primary DESC, a.name ASC
-	`
+		ORDER BY ` + creditOrder
 
 	rows, err := r.db.Query(ctx, query, songID)
 	if err != nil {
@@ -108,7 +215,7 @@ func (r *Repository) getArtistsBySongID(ctx context.Context, songID string) ([]S
 	var artists []SongArtist
 	for rows.Next() {
 		var artist SongArtist
-		if err := rows.Scan(&artist.ID, &artist.Name, &artist.ImageURL, &artist.IsPrimary); err != nil {
+		if err := rows.Scan(&artist.ID, &artist.Name, &artist.ImageURL, &artist.Role, &artist.Position, &artist.IsPrimary); err != nil {
 			return nil, err
 		}
 		artists = append(artists, artist)
@@ -145,8 +252,214 @@ func (r *Repository) getGenresBySongID(ctx context.Context, songID string) ([]Ge
 	return genres, rows.Err()
 }
 
+// ListSongs returns a page of songs matching the filter, plus the cursor for the
+// next page (nil when there are no more songs)
+func (r *Repository) ListSongs(ctx context.Context, filter ListSongsFilter) ([]Song, *SongCursor, error) {
+	var conditions []string
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	// 1. Filters (song đang xử lý hoặc lỗi không được list)
+	conditions = append(conditions, "s.status = 'ready'")
+	switch {
+	case filter.ViewerIsAdmin:
+	case filter.ViewerID != "":
+		conditions = append(conditions, "(s.visibility = 'public' OR s.uploaded_by = "+arg(filter.ViewerID)+")")
+	default:
+		conditions = append(conditions, "s.visibility = 'public'")
+	}
+	if !filter.ViewerIsAdmin {
+		country := arg(nullIfEmpty(filter.Country)) + "::text"
+		conditions = append(conditions,
+			territory.Condition("s.territory_allow", "s.territory_deny", country),
+			territory.Condition("a.territory_allow", "a.territory_deny", country))
+	}
+	if filter.HideExplicit {
+		conditions = append(conditions, "NOT COALESCE(s.explicit, FALSE)")
+	}
+	if filter.GenreID != "" {
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM song_genres sg WHERE sg.song_id = s.id AND sg.genre_id = "+arg(filter.GenreID)+")")
+	}
+	if filter.ArtistID != "" {
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM song_artists sa WHERE sa.song_id = s.id AND sa.artist_id = "+arg(filter.ArtistID)+")")
+	}
+	if filter.AlbumID != "" {
+		conditions = append(conditions, "s.album_id = "+arg(filter.AlbumID))
+	}
+	if filter.MinDuration > 0 {
+		conditions = append(conditions, "s.duration >= "+arg(filter.MinDuration))
+	}
+	if filter.MaxDuration > 0 {
+		conditions = append(conditions, "s.duration <= "+arg(filter.MaxDuration))
+	}
+	if filter.CreatedAfter != nil {
+		conditions = append(conditions, "s.created_at >= "+arg(*filter.CreatedAfter))
+	}
+	if filter.CreatedBefore != nil {
+		conditions = append(conditions, "s.created_at < "+arg(*filter.CreatedBefore))
+	}
+
+	// 2. Sorting + keyset cursor (row comparison theo đúng thứ tự sort)
+	var orderBy string
+	switch filter.Sort {
+	case SortMostPlayed:
+		orderBy = "COALESCE(s.play_count, 0) DESC, s.created_at DESC, s.id DESC"
+		if c := filter.Cursor; c != nil {
+			conditions = append(conditions, fmt.Sprintf("(COALESCE(s.play_count, 0), s.created_at, s.id) < (%s, %s, %s)",
+				arg(*c.PlayCount), arg(c.CreatedAt), arg(c.ID)))
+		}
+	case SortTitle:
+		orderBy = "s.title ASC, s.created_at ASC, s.id ASC"
+		if c := filter.Cursor; c != nil {
+			conditions = append(conditions, fmt.Sprintf("(s.title, s.created_at, s.id) > (%s, %s, %s)",
+				arg(c.Title), arg(c.CreatedAt), arg(c.ID)))
+		}
+	default:
+		orderBy = "s.created_at DESC, s.id DESC"
+		if c := filter.Cursor; c != nil {
+			conditions = append(conditions, fmt.Sprintf("(s.created_at, s.id) < (%s, %s)",
+				arg(c.CreatedAt), arg(c.ID)))
+		}
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	// Lấy thêm 1 row để biết còn trang sau hay không
+	query := `SELECT ` + songColumns + `
+		FROM songs s
+		LEFT JOIN albums a ON s.album_id = a.id
+		` + where + `
+		ORDER BY ` + orderBy + `
+		LIMIT ` + arg(filter.Limit+1)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error listing songs: %w", err)
+	}
+	defer rows.Close()
+
+	var songs []Song
+	for rows.Next() {
+		song, err := scanSong(rows)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error scanning song: %w", err)
+		}
+		songs = append(songs, *song)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error listing songs: %w", err)
+	}
+
+	var next *SongCursor
+	if len(songs) > filter.Limit {
+		songs = songs[:filter.Limit]
+		last := songs[len(songs)-1]
+		next = &SongCursor{
+			CreatedAt: last.CreatedAt,
+			ID:        last.ID,
+			PlayCount: &last.PlayCount,
+			Title:     last.Title,
+		}
+	}
+
+	// 3. Artists + genres cho cả trang trong 1 query
+	if err := r.loadRelations(ctx, songs); err != nil {
+		return nil, nil, err
+	}
+
+	return songs, next, nil
+}
+
+// loadRelations fills Artists and Genres for all songs with a single query
+// instead of calling getArtistsBySongID/getGenresBySongID per song
+func (r *Repository) loadRelations(ctx context.Context, songs []Song) error {
+	if len(songs) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(songs))
+	for i, song := range songs {
+		ids[i] = song.ID
+	}
+
+	query := `
+		SELECT
+			s.id::text,
+			COALESCE((
+				SELECT json_agg(json_build_object(
+					'id', a.id,
+					'name', a.name,
+					'image_url', COALESCE(ap.avatar_url, ''),
+					'role', sa.role,
+					'position', sa.position,
+					'is_primary', COALESCE(sa.is_primary, FALSE)
+				) ORDER BY ` + creditOrder + `)
+				FROM song_artists sa
+				INNER JOIN artists a ON a.id = sa.artist_id
+				LEFT JOIN artist_profiles ap ON ap.artist_id = a.id
+				WHERE sa.song_id = s.id
+			), '[]'::json),
+			COALESCE((
+				SELECT json_agg(json_build_object('id', g.id, 'name', g.name) ORDER BY g.name ASC)
+				FROM song_genres sg
+				INNER JOIN genres g ON g.id = sg.genre_id
+				WHERE sg.song_id = s.id
+			), '[]'::json)
+		FROM unnest($1::uuid[]) AS s(id)
+	`
+
+	rows, err := r.db.Query(ctx, query, ids)
+	if err != nil {
+		return fmt.Errorf("error fetching song relations: %w", err)
+	}
+	defer rows.Close()
+
+	type relations struct {
+		artists []SongArtist
+		genres  []Genre
+	}
+	byID := make(map[string]relations, len(ids))
+	for rows.Next() {
+		var id string
+		var artistsJSON, genresJSON []byte
+		if err := rows.Scan(&id, &artistsJSON, &genresJSON); err != nil {
+			return fmt.Errorf("error scanning song relations: %w", err)
+		}
+		var rel relations
+		if err := json.Unmarshal(artistsJSON, &rel.artists); err != nil {
+			return fmt.Errorf("error decoding artists: %w", err)
+		}
+		if err := json.Unmarshal(genresJSON, &rel.genres); err != nil {
+			return fmt.Errorf("error decoding genres: %w", err)
+		}
+		byID[id] = rel
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error fetching song relations: %w", err)
+	}
+
+	for i := range songs {
+		rel := byID[songs[i].ID]
+		if len(rel.artists) > 0 {
+			songs[i].Artists = rel.artists
+		}
+		if len(rel.genres) > 0 {
+			songs[i].Genres = rel.genres
+		}
+	}
+
+	return nil
+}
+
 // CreateSong tạo song với related data (album, artists, genres)
-// Sử dụng transaction để đảm bảo data consistency
+// Sử dụng transaction để đảm bảo data consistency. The song's audio blob
+// must already be retained (see RetainBlob).
 func (r *Repository) CreateSong(ctx context.Context, input CreateSongInput) error {
 	// Bắt đầu transaction
 	tx, err := r.db.Begin(ctx)
@@ -157,60 +470,1086 @@ func (r *Repository) CreateSong(ctx context.Context, input CreateSongInput) erro
 
 	// 1. Insert song (với album_id nếu có)
 	songQuery := `
-		INSERT INTO songs (id, title, duration, file_url, play_count, track_number, album_id, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO songs (id, title, duration, file_url, file_digest, audio_format, play_count, track_number, album_id, uploaded_by, status, created_at, visibility, release_at, explicit)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
 	`
+	visibility := input.Song.Visibility
+	if visibility == "" {
+		visibility = release.Public
+	}
 	_, err = tx.Exec(ctx, songQuery,
 		input.Song.ID,
 		input.Song.Title,
 		input.Song.Duration,
 		input.Song.FileURL,
+		nullIfEmpty(input.Song.FileDigest),
+		input.Song.AudioFormat,
 		input.Song.PlayCount,
 		input.Song.TrackNumber,
 		input.AlbumID, // có thể nil
+		nullIfEmpty(input.Song.UploadedBy),
+		input.Song.Status,
 		input.Song.CreatedAt,
+		visibility,
+		input.Song.ReleaseAt,
+		input.Explicit, // nil = chưa biết
 	)
 	if err != nil {
 		return fmt.Errorf("error inserting song: %w", err)
 	}
+	if input.AlbumID != nil {
+		if err = inheritAlbumRelease(ctx, tx, input.Song.ID); err != nil {
+			return err
+		}
+	}
 
 	// 2. Insert song_artists (nếu có)
-	if len(input.ArtistIDs) > 0 {
-		artistQuery := `
-			INSERT INTO song_artists (song_id, artist_id, is_primary)
-			VALUES ($1, $2, $3)
-		`
-		for i, artistID := range input.ArtistIDs {
-			isPrimary := (i == 0) // Artist đầu tiên là primary
-			_, err = tx.Exec(ctx, artistQuery, input.Song.ID, artistID, isPrimary)
-			if err != nil {
-				return fmt.Errorf("error inserting song_artist: %w", err)
+	if err = insertCredits(ctx, tx, input.Song.ID, performerCredits(input.ArtistIDs)); err != nil {
+		return err
+	}
+
+	// 3. Insert song_genres (nếu có)
+	if err = insertSongGenres(ctx, tx, input.Song.ID, input.GenreIDs); err != nil {
+		return err
+	}
+
+	// 4. Queue processing jobs (duration, tags, ...) cùng transaction với song
+	if err = jobs.Enqueue(ctx, tx, input.Jobs...); err != nil {
+		return err
+	}
+
+	// Commit transaction
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	// Song đang xử lý được báo cho observers khi chuyển sang ready (RefreshStatus)
+	if input.Song.Status == StatusReady {
+		r.notifyCreated(ctx, input.Song.ID)
+	}
+
+	return nil
+}
+
+// insertSongArtists links artists to a song, the first one is primary
+func insertCredits(ctx context.Context, tx pgx.Tx, songID string, credits []Credit) error {
+	artistQuery := `
+		INSERT INTO song_artists (song_id, artist_id, role, position, is_primary)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	positions := map[string]int{}
+	seen := map[Credit]bool{}
+	for _, c := range credits {
+		if seen[c] {
+			continue
+		}
+		seen[c] = true
+		position := positions[c.Role]
+		positions[c.Role]++
+		isPrimary := c.Role == RolePerformer && position == 0 // performer đầu tiên là primary
+		if _, err := tx.Exec(ctx, artistQuery, songID, c.ArtistID, c.Role, position, isPrimary); err != nil {
+			if isForeignKeyViolation(err) {
+				return ErrUnknownArtist
 			}
+			return fmt.Errorf("error inserting song_artist: %w", err)
 		}
 	}
+	return nil
+}
 
-	// 3. Insert song_genres (nếu có)
-	if len(input.GenreIDs) > 0 {
-		genreQuery := `
-			INSERT INTO song_genres (song_id, genre_id)
-			VALUES ($1, $2)
-		`
-		for _, genreID := range input.GenreIDs {
-			_, err = tx.Exec(ctx, genreQuery, input.Song.ID, genreID)
-			if err != nil {
-				return fmt.Errorf("error inserting song_genre: %w", err)
+// insertSongGenres links genres to a song
+func insertSongGenres(ctx context.Context, tx pgx.Tx, songID string, genreIDs []string) error {
+	genreQuery := `
+		INSERT INTO song_genres (song_id, genre_id)
+		VALUES ($1, $2)
+	`
+	for _, genreID := range genreIDs {
+		if _, err := tx.Exec(ctx, genreQuery, songID, genreID); err != nil {
+			if isForeignKeyViolation(err) {
+				return ErrUnknownGenre
+			}
+			return fmt.Errorf("error inserting song_genre: %w", err)
+		}
+	}
+	return nil
+}
+
+// lockName serializes find-or-create of the same name across transactions,
+// the lock is released at commit/rollback
+func lockName(ctx context.Context, tx pgx.Tx, kind, name string) error {
+	_, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext($1 || ':' || lower($2)))`, kind, name)
+	if err != nil {
+		return fmt.Errorf("error locking %s %q: %w", kind, name, err)
+	}
+	return nil
+}
+
+// resolveArtists returns artist IDs for names, creating missing artists
+func resolveArtists(ctx context.Context, tx pgx.Tx, names []string) ([]string, error) {
+	ids := make([]string, 0, len(names))
+	for _, name := range names {
+		if err := lockName(ctx, tx, "artist", name); err != nil {
+			return nil, err
+		}
+		var id string
+		err := tx.QueryRow(ctx, `
+			SELECT id::text FROM artists
+			WHERE lower(name) = lower($1)
+			ORDER BY created_at ASC
+			LIMIT 1
+		`, name).Scan(&id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = tx.QueryRow(ctx, `INSERT INTO artists (name) VALUES ($1) RETURNING id::text`, name).Scan(&id)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error resolving artist %q: %w", name, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// resolveAlbum returns the ID of the album with this title by artistID owned
+// by ownerID, creating it if needed: tags never add a song to an album of
+// another user. artistID and ownerID may be "" when unknown.
+func resolveAlbum(ctx context.Context, tx pgx.Tx, title, artistID, ownerID string, year int) (string, error) {
+	if err := lockName(ctx, tx, "album", title); err != nil {
+		return "", err
+	}
+
+	var id string
+	err := tx.QueryRow(ctx, `
+		SELECT id::text FROM albums
+		WHERE lower(title) = lower($1)
+		  AND artist_id IS NOT DISTINCT FROM $2::uuid
+		  AND owned_by IS NOT DISTINCT FROM $3::uuid
+		ORDER BY created_at ASC
+		LIMIT 1
+	`, title, nullIfEmpty(artistID), nullIfEmpty(ownerID)).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		var releaseDate *time.Time
+		if year > 0 {
+			d := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+			releaseDate = &d
+		}
+		err = tx.QueryRow(ctx, `
+			INSERT INTO albums (artist_id, title, release_date, owned_by)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id::text
+		`, nullIfEmpty(artistID), title, releaseDate, nullIfEmpty(ownerID)).Scan(&id)
+	}
+	if err != nil {
+		return "", fmt.Errorf("error resolving album %q: %w", title, err)
+	}
+	return id, nil
+}
+
+// resolveGenres returns genre IDs for names, creating missing genres
+func resolveGenres(ctx context.Context, tx pgx.Tx, names []string) ([]string, error) {
+	ids := make([]string, 0, len(names))
+	for _, name := range names {
+		if err := lockName(ctx, tx, "genre", name); err != nil {
+			return nil, err
+		}
+		var id string
+		err := tx.QueryRow(ctx, `
+			SELECT id::text FROM genres
+			WHERE lower(name) = lower($1)
+			LIMIT 1
+		`, name).Scan(&id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = tx.QueryRow(ctx, `INSERT INTO genres (name) VALUES ($1) RETURNING id::text`, name).Scan(&id)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error resolving genre %q: %w", name, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// UpdateSong updates song fields and replaces artist/genre links in one
+// transaction. Only non-nil fields of input are changed.
+func (r *Repository) UpdateSong(ctx context.Context, id string, input UpdateSongInput) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Album cũ, để tính lại loudness của album khi bài hát chuyển album
+	var oldAlbumID *string
+	if input.AlbumID != nil {
+		err = tx.QueryRow(ctx, `SELECT album_id::text FROM songs WHERE id = $1 FOR UPDATE`, id).Scan(&oldAlbumID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrSongNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("error querying song: %w", err)
+		}
+	}
+
+	// 1. Update các cột của songs (luôn chạy để kiểm tra song tồn tại và khóa row)
+	sets := []string{"id = id"}
+	args := []any{id}
+	set := func(column string, value any) {
+		args = append(args, value)
+		sets = append(sets, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+	if input.Title != nil {
+		set("title", *input.Title)
+	}
+	if input.AlbumID != nil {
+		set("album_id", nullIfEmpty(*input.AlbumID)) // "" = bỏ album
+	}
+	if input.TrackNumber != nil {
+		set("track_number", *input.TrackNumber)
+	}
+	if input.Visibility != nil {
+		set("visibility", *input.Visibility)
+		set("release_at", input.ReleaseAt)
+	}
+	if input.Territories != nil {
+		set("territory_allow", input.Territories.Allow)
+		set("territory_deny", input.Territories.Deny)
+	}
+	if input.Explicit != nil {
+		set("explicit", *input.Explicit)
+	}
+
+	tag, err := tx.Exec(ctx, `UPDATE songs SET `+strings.Join(sets, ", ")+` WHERE id = $1`, args...)
+	if err != nil {
+		// Album bị xóa sau khi CheckAlbumAccess
+		if isForeignKeyViolation(err) {
+			return album.ErrAlbumNotFound
+		}
+		return fmt.Errorf("error updating song: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrSongNotFound
+	}
+
+	// 2. Thay toàn bộ credits, hoặc chỉ các performer (artist đầu tiên là primary)
+	switch {
+	case input.Credits != nil:
+		if _, err = tx.Exec(ctx, `DELETE FROM song_artists WHERE song_id = $1`, id); err != nil {
+			return fmt.Errorf("error deleting song_artists: %w", err)
+		}
+		if err = insertCredits(ctx, tx, id, *input.Credits); err != nil {
+			return err
+		}
+	case input.ArtistIDs != nil:
+		_, err = tx.Exec(ctx, `DELETE FROM song_artists WHERE song_id = $1 AND role = $2`, id, RolePerformer)
+		if err != nil {
+			return fmt.Errorf("error deleting song_artists: %w", err)
+		}
+		if err = insertCredits(ctx, tx, id, performerCredits(*input.ArtistIDs)); err != nil {
+			return err
+		}
+	}
+
+	// 3. Thay toàn bộ song_genres
+	if input.GenreIDs != nil {
+		if _, err = tx.Exec(ctx, `DELETE FROM song_genres WHERE song_id = $1`, id); err != nil {
+			return fmt.Errorf("error deleting song_genres: %w", err)
+		}
+		if err = insertSongGenres(ctx, tx, id, *input.GenreIDs); err != nil {
+			return err
+		}
+	}
+
+	// 4. Album gain của album cũ và mới; song chuyển vào album chưa phát hành
+	// thì ẩn theo album (trừ khi visibility được đặt cùng lúc)
+	if input.AlbumID != nil && stringOrEmpty(oldAlbumID) != *input.AlbumID {
+		for _, albumID := range []string{stringOrEmpty(oldAlbumID), *input.AlbumID} {
+			if err = updateAlbumLoudness(ctx, tx, albumID); err != nil {
+				return err
+			}
+		}
+		if input.Visibility == nil {
+			if err = inheritAlbumRelease(ctx, tx, id); err != nil {
+				return err
 			}
 		}
 	}
 
-	// Commit transaction
 	if err = tx.Commit(ctx); err != nil {
 		return fmt.Errorf("error committing transaction: %w", err)
 	}
 
+	r.notifyUpdated(ctx, id)
+
 	return nil
 }
 
+// FindSongIDByDigest reports whether a song's audio file has the given
+// SHA-256 digest and returns the ID of such a song the viewer may see
+// (public, uploaded by viewerID, or any for admins), "" if none is visible
+func (r *Repository) FindSongIDByDigest(ctx context.Context, digest, viewerID string, viewerIsAdmin bool) (id string, exists bool, err error) {
+	var visible bool
+	err = r.db.QueryRow(ctx, `
+		SELECT id::text, (visibility = 'public' OR $3::boolean OR COALESCE(uploaded_by::text = $2, FALSE)) AS visible
+		FROM songs
+		WHERE file_digest = $1
+		ORDER BY visible DESC, created_at ASC
+		LIMIT 1
+	`, digest, viewerID, viewerIsAdmin).Scan(&id, &visible)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("error querying song by digest: %w", err)
+	}
+	if !visible {
+		return "", true, nil
+	}
+	return id, true, nil
+}
+
+// GetWaveform returns the stored waveform peaks of a song
+func (r *Repository) GetWaveform(ctx context.Context, songID string) (*waveform.Waveform, error) {
+	var w waveform.Waveform
+	err := r.db.QueryRow(ctx, `
+		SELECT sample_rate, samples_per_pixel, peaks
+		FROM song_waveforms
+		WHERE song_id = $1
+	`, songID).Scan(&w.SampleRate, &w.SamplesPerPixel, &w.Data)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrWaveformNotFound
+		}
+		return nil, fmt.Errorf("error querying waveform: %w", err)
+	}
+	return &w, nil
+}
+
+// ListLyricsLanguages returns the languages a song has lyrics in
+func (r *Repository) ListLyricsLanguages(ctx context.Context, songID string) ([]string, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT language FROM song_lyrics
+		WHERE song_id = $1
+		ORDER BY language
+	`, songID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying lyrics languages: %w", err)
+	}
+	defer rows.Close()
+
+	var languages []string
+	for rows.Next() {
+		var language string
+		if err := rows.Scan(&language); err != nil {
+			return nil, fmt.Errorf("error scanning lyrics language: %w", err)
+		}
+		languages = append(languages, language)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating lyrics languages: %w", err)
+	}
+	return languages, nil
+}
+
+// GetLyrics returns the lyrics of a song in a language
+func (r *Repository) GetLyrics(ctx context.Context, songID, language string) (*SongLyrics, error) {
+	l := SongLyrics{SongID: songID}
+	err := r.db.QueryRow(ctx, `
+		SELECT language, synced, lines, source, updated_at
+		FROM song_lyrics
+		WHERE song_id = $1 AND language = $2
+	`, songID, language).Scan(&l.Language, &l.Synced, &l.Lines, &l.Source, &l.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrLyricsNotFound
+		}
+		return nil, fmt.Errorf("error querying lyrics: %w", err)
+	}
+	return &l, nil
+}
+
+// SaveLyrics stores lyrics of a song. Existing lyrics in the same language
+// are replaced if replace is true, kept otherwise.
+func (r *Repository) SaveLyrics(ctx context.Context, l *SongLyrics, replace bool) error {
+	conflict := `DO NOTHING`
+	if replace {
+		conflict = `DO UPDATE SET
+			synced = EXCLUDED.synced,
+			lines = EXCLUDED.lines,
+			source = EXCLUDED.source,
+			updated_at = CURRENT_TIMESTAMP`
+	}
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO song_lyrics (song_id, language, synced, lines, source)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (song_id, language) `+conflict,
+		l.SongID, l.Language, l.Synced, l.Lines, l.Source)
+	if err != nil {
+		return fmt.Errorf("error saving lyrics: %w", err)
+	}
+	return nil
+}
+
+// DeleteLyrics removes the lyrics of a song in a language
+func (r *Repository) DeleteLyrics(ctx context.Context, songID, language string) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM song_lyrics WHERE song_id = $1 AND language = $2`, songID, language)
+	if err != nil {
+		return fmt.Errorf("error deleting lyrics: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrLyricsNotFound
+	}
+	return nil
+}
+
+// RetainBlob adds a reference to the audio blob with the given digest,
+// creating it if needed. It must be called before the file is stored or
+// reused so a concurrent PurgeBlob cannot remove it; the caller stores the
+// file if it is missing and releases the reference if the song is not created.
+func (r *Repository) RetainBlob(ctx context.Context, digest, storageKey string, size int64) error {
+	// Upsert khóa row của blob: PurgeBlob đang xóa file sẽ chặn tới khi commit
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO audio_blobs (digest, storage_key, size, ref_count)
+		VALUES ($1, $2, $3, 1)
+		ON CONFLICT (digest) DO UPDATE SET ref_count = audio_blobs.ref_count + 1
+	`, digest, storageKey, size)
+	if err != nil {
+		return fmt.Errorf("error referencing audio blob: %w", err)
+	}
+	return nil
+}
+
+// ReleaseBlob removes a reference taken by RetainBlob and reports whether
+// no reference is left; the caller then removes the file with PurgeBlob
+func (r *Repository) ReleaseBlob(ctx context.Context, digest string) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	unreferenced, err := releaseBlob(ctx, tx, digest)
+	if err != nil {
+		return false, err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return unreferenced, nil
+}
+
+// releaseBlob decrements the reference count of a blob and reports whether
+// it reached 0. The row is kept until PurgeBlob removes the file, which is
+// only done after the transaction commits.
+func releaseBlob(ctx context.Context, tx pgx.Tx, digest string) (bool, error) {
+	var refCount int
+	err := tx.QueryRow(ctx, `
+		UPDATE audio_blobs SET ref_count = ref_count - 1
+		WHERE digest = $1
+		RETURNING ref_count
+	`, digest).Scan(&refCount)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error releasing audio blob: %w", err)
+	}
+	return refCount <= 0, nil
+}
+
+// PurgeBlob deletes the file and the row of a blob left without references
+// and reports whether it did. The reference count is checked again under
+// the row lock: a blob retained in the meantime is kept, and a RetainBlob
+// waiting for the lock finds the file missing and stores it again.
+func (r *Repository) PurgeBlob(ctx context.Context, digest string, deleteFile func(ctx context.Context, key string) error) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var storageKey string
+	err = tx.QueryRow(ctx, `
+		SELECT storage_key FROM audio_blobs WHERE digest = $1 AND ref_count <= 0 FOR UPDATE
+	`, digest).Scan(&storageKey)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil // đã được retain lại hoặc đã bị xóa
+	}
+	if err != nil {
+		return false, fmt.Errorf("error locking audio blob: %w", err)
+	}
+
+	// Xóa file trước row: commit lỗi thì row còn lại, RetainBlob sau đó sẽ lưu lại file
+	if err = deleteFile(ctx, storageKey); err != nil {
+		return false, fmt.Errorf("error deleting audio file: %w", err)
+	}
+	if _, err = tx.Exec(ctx, `DELETE FROM audio_blobs WHERE digest = $1`, digest); err != nil {
+		return false, fmt.Errorf("error deleting audio blob: %w", err)
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return true, nil
+}
+
+// DeleteSong deletes a song and releases its audio blob. It reports whether
+// the blob has no reference left; the file is not deleted here, the caller
+// removes it with PurgeBlob once the song is gone.
+// Links in song_artists, song_genres, ... cascade.
+func (r *Repository) DeleteSong(ctx context.Context, id string) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var digest, albumID *string
+	err = tx.QueryRow(ctx, `
+		DELETE FROM songs WHERE id = $1
+		RETURNING file_digest, album_id::text
+	`, id).Scan(&digest, &albumID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, ErrSongNotFound
+		}
+		return false, fmt.Errorf("error deleting song: %w", err)
+	}
+
+	if err = updateAlbumLoudness(ctx, tx, stringOrEmpty(albumID)); err != nil {
+		return false, err
+	}
+
+	var unreferenced bool
+	if digest != nil {
+		if unreferenced, err = releaseBlob(ctx, tx, *digest); err != nil {
+			return false, err
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	for _, o := range r.observers {
+		o.SongDeleted(id)
+	}
+
+	return unreferenced, nil
+}
+
+// SetDuration stores the duration computed by the duration job
+func (r *Repository) SetDuration(ctx context.Context, id string, seconds int) error {
+	if _, err := r.db.Exec(ctx, `UPDATE songs SET duration = $2 WHERE id = $1`, id, seconds); err != nil {
+		return fmt.Errorf("error updating duration: %w", err)
+	}
+	return nil
+}
+
+// SaveWaveform stores (or replaces) the waveform peaks of a song
+func (r *Repository) SaveWaveform(ctx context.Context, id string, w *waveform.Waveform) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO song_waveforms (song_id, sample_rate, samples_per_pixel, peaks)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (song_id) DO UPDATE SET
+			sample_rate = EXCLUDED.sample_rate,
+			samples_per_pixel = EXCLUDED.samples_per_pixel,
+			peaks = EXCLUDED.peaks,
+			created_at = CURRENT_TIMESTAMP
+	`, id, w.SampleRate, w.SamplesPerPixel, w.Data)
+	if err != nil {
+		return fmt.Errorf("error saving waveform: %w", err)
+	}
+	return nil
+}
+
+// SaveLoudness stores the loudness analysis of a song and recomputes the
+// album gain of its album
+func (r *Repository) SaveLoudness(ctx context.Context, id string, res *loudness.Result) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var albumID *string
+	err = tx.QueryRow(ctx, `
+		UPDATE songs SET
+			loudness_lufs = $2,
+			true_peak_dbtp = $3,
+			track_gain_db = $4,
+			track_peak = $5
+		WHERE id = $1
+		RETURNING album_id::text
+	`, id, res.Integrated, res.TruePeakDBTP(), loudness.Gain(res.Integrated), res.TruePeak).Scan(&albumID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrSongNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("error updating loudness: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO song_loudness_histograms (song_id, histogram)
+		VALUES ($1, $2)
+		ON CONFLICT (song_id) DO UPDATE SET
+			histogram = EXCLUDED.histogram,
+			created_at = CURRENT_TIMESTAMP
+	`, id, res.Histogram.Counts())
+	if err != nil {
+		return fmt.Errorf("error saving loudness histogram: %w", err)
+	}
+
+	if err = updateAlbumLoudness(ctx, tx, stringOrEmpty(albumID)); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// updateAlbumLoudness recomputes the album loudness, gain and peak from the
+// histograms of its analysed tracks, as if the album was one programme.
+// It does nothing for albumID "".
+func updateAlbumLoudness(ctx context.Context, tx pgx.Tx, albumID string) error {
+	if albumID == "" {
+		return nil
+	}
+
+	// Khóa album: các job loudness của cùng album chạy song song
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM albums WHERE id = $1 FOR UPDATE`, albumID); err != nil {
+		return fmt.Errorf("error locking album: %w", err)
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT h.histogram, COALESCE(s.track_peak, 0)
+		FROM songs s
+		INNER JOIN song_loudness_histograms h ON h.song_id = s.id
+		WHERE s.album_id = $1
+	`, albumID)
+	if err != nil {
+		return fmt.Errorf("error querying loudness histograms: %w", err)
+	}
+	defer rows.Close()
+
+	var total loudness.Histogram
+	var peak float64
+	for rows.Next() {
+		var counts []int32
+		var trackPeak float64
+		if err := rows.Scan(&counts, &trackPeak); err != nil {
+			return fmt.Errorf("error scanning loudness histogram: %w", err)
+		}
+		h := loudness.HistogramFromCounts(counts)
+		total.Merge(&h)
+		peak = max(peak, trackPeak)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating loudness histograms: %w", err)
+	}
+
+	// Không còn track nào đã phân tích -> xoá giá trị cũ
+	var lufs, gain, albumPeak *float64
+	if l, ok := total.Loudness(); ok {
+		g := loudness.Gain(l)
+		lufs, gain, albumPeak = &l, &g, &peak
+	}
+	_, err = tx.Exec(ctx, `
+		UPDATE albums SET loudness_lufs = $2, album_gain_db = $3, album_peak = $4
+		WHERE id = $1
+	`, albumID, lufs, gain, albumPeak)
+	if err != nil {
+		return fmt.Errorf("error updating album loudness: %w", err)
+	}
+	return nil
+}
+
+// GetAlbumTerritories returns the licensing rules of an album
+func (r *Repository) GetAlbumTerritories(ctx context.Context, albumID string) (territory.Rules, error) {
+	var rules territory.Rules
+	err := r.db.QueryRow(ctx, `
+		SELECT territory_allow, territory_deny FROM albums WHERE id = $1
+	`, albumID).Scan(&rules.Allow, &rules.Deny)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return rules, album.ErrAlbumNotFound
+		}
+		return rules, fmt.Errorf("error querying album territories: %w", err)
+	}
+	return rules, nil
+}
+
+// CheckAlbumAccess returns album.ErrAlbumNotFound if the album does not
+// exist and album.ErrAlbumNotOwned if userID may not add songs to it: only
+// its owner and admins can
+func (r *Repository) CheckAlbumAccess(ctx context.Context, albumID, userID string, isAdmin bool) error {
+	if uuid.Validate(albumID) != nil {
+		return album.ErrAlbumNotFound
+	}
+	var owner string
+	err := r.db.QueryRow(ctx, `SELECT COALESCE(owned_by::text, '') FROM albums WHERE id = $1`, albumID).Scan(&owner)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return album.ErrAlbumNotFound
+		}
+		return fmt.Errorf("error querying album owner: %w", err)
+	}
+	if !isAdmin && (owner == "" || owner != userID) {
+		return album.ErrAlbumNotOwned
+	}
+	return nil
+}
+
+// CheckArtists returns ErrUnknownArtist unless every ID is an existing artist
+func (r *Repository) CheckArtists(ctx context.Context, artistIDs []string) error {
+	if len(artistIDs) == 0 {
+		return nil
+	}
+	for _, id := range artistIDs {
+		if uuid.Validate(id) != nil {
+			return ErrUnknownArtist
+		}
+	}
+	var missing bool
+	err := r.db.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM unnest($1::uuid[]) AS ids(id)
+			WHERE NOT EXISTS (SELECT 1 FROM artists a WHERE a.id = ids.id)
+		)
+	`, artistIDs).Scan(&missing)
+	if err != nil {
+		return fmt.Errorf("error checking artists: %w", err)
+	}
+	if missing {
+		return ErrUnknownArtist
+	}
+	return nil
+}
+
+// inheritAlbumRelease hides a public song in an album that is not released
+// yet: it takes the visibility and release time of the album
+func inheritAlbumRelease(ctx context.Context, tx pgx.Tx, songID string) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE songs s SET visibility = a.visibility, release_at = a.release_at
+		FROM albums a
+		WHERE s.id = $1 AND a.id = s.album_id
+		  AND s.visibility = 'public' AND a.visibility <> 'public'
+	`, songID)
+	if err != nil {
+		return fmt.Errorf("error applying album release: %w", err)
+	}
+	return nil
+}
+
+// ApplyTags fills the song's empty fields from its file's tags: credits of a
+// role, album and genres are only set when the song has none, so running it
+// twice changes nothing. It returns the song's album ID ("" if none).
+func (r *Repository) ApplyTags(ctx context.Context, id string, tags TagsUpdate) (string, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return "", fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var albumID *string
+	var uploadedBy string
+	var roles []string // các role đã có credit
+	var hasGenres bool
+	err = tx.QueryRow(ctx, `
+		SELECT album_id::text, COALESCE(uploaded_by::text, ''),
+			ARRAY(SELECT DISTINCT role::text FROM song_artists WHERE song_id = s.id),
+			EXISTS (SELECT 1 FROM song_genres WHERE song_id = s.id)
+		FROM songs s WHERE id = $1
+		FOR UPDATE
+	`, id).Scan(&albumID, &uploadedBy, &roles, &hasGenres)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrSongNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("error querying song: %w", err)
+	}
+
+	// Match hoặc tạo artist/album/genre theo tên lấy từ tag của file.
+	// Mỗi role chỉ được điền khi song chưa có credit nào với role đó.
+	var artistIDs []string // performers
+	for _, group := range []struct {
+		role  string
+		names []string
+	}{
+		{RolePerformer, tags.ArtistNames},
+		{RoleFeatured, tags.FeaturedNames},
+		{RoleComposer, tags.ComposerNames},
+		{RoleLyricist, tags.LyricistNames},
+	} {
+		if len(group.names) == 0 || slices.Contains(roles, group.role) {
+			continue
+		}
+		ids, err := resolveArtists(ctx, tx, group.names)
+		if err != nil {
+			return "", err
+		}
+		credits := make([]Credit, len(ids))
+		for i, artistID := range ids {
+			credits[i] = Credit{ArtistID: artistID, Role: group.role}
+		}
+		if err = insertCredits(ctx, tx, id, credits); err != nil {
+			return "", err
+		}
+		if group.role == RolePerformer {
+			artistIDs = ids
+		}
+	}
+	newAlbum := albumID == nil && tags.AlbumTitle != ""
+	if newAlbum {
+		artistID := ""
+		if len(artistIDs) > 0 {
+			artistID = artistIDs[0]
+		}
+		resolved, err := resolveAlbum(ctx, tx, tags.AlbumTitle, artistID, uploadedBy, tags.AlbumYear)
+		if err != nil {
+			return "", err
+		}
+		albumID = &resolved
+	}
+	if !hasGenres && len(tags.GenreNames) > 0 {
+		genreIDs, err := resolveGenres(ctx, tx, tags.GenreNames)
+		if err != nil {
+			return "", err
+		}
+		if err = insertSongGenres(ctx, tx, id, genreIDs); err != nil {
+			return "", err
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE songs SET
+			title = COALESCE(NULLIF($2, ''), title),
+			track_number = COALESCE(NULLIF(track_number, 0), NULLIF($3, 0)),
+			album_id = $4,
+			explicit = COALESCE(explicit, $5)
+		WHERE id = $1
+	`, id, tags.Title, tags.TrackNumber, albumID, tags.Explicit)
+	if err != nil {
+		return "", fmt.Errorf("error updating song: %w", err)
+	}
+	if newAlbum {
+		if err = updateAlbumLoudness(ctx, tx, *albumID); err != nil {
+			return "", err
+		}
+		if err = inheritAlbumRelease(ctx, tx, id); err != nil {
+			return "", err
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return "", fmt.Errorf("error committing transaction: %w", err)
+	}
+	return stringOrEmpty(albumID), nil
+}
+
+// SetAlbumCoverIfEmpty sets the cover of an album that has none
+func (r *Repository) SetAlbumCoverIfEmpty(ctx context.Context, albumID, coverURL string) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE albums SET cover_url = $2
+		WHERE id = $1 AND COALESCE(cover_url, '') = ''
+	`, albumID, coverURL)
+	if err != nil {
+		return fmt.Errorf("error setting album cover: %w", err)
+	}
+	return nil
+}
+
+// RefreshStatus derives a processing song's status from its jobs: still
+// processing while jobs are pending or running, failed if one of the
+// critical job kinds failed, ready otherwise. Observers are told about the
+// song when it becomes ready.
+func (r *Repository) RefreshStatus(ctx context.Context, id string, critical []string) (string, error) {
+	var status string
+	err := r.db.QueryRow(ctx, `
+		UPDATE songs s SET status = CASE
+			WHEN EXISTS (SELECT 1 FROM jobs j WHERE j.song_id = s.id AND j.status IN ('pending', 'running'))
+				THEN 'processing'
+			WHEN EXISTS (SELECT 1 FROM jobs j WHERE j.song_id = s.id AND j.status = 'failed' AND j.kind = ANY($2))
+				THEN 'failed'
+			ELSE 'ready'
+		END
+		WHERE s.id = $1 AND s.status = 'processing'
+		RETURNING s.status
+	`, id, critical).Scan(&status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil // không còn ở trạng thái processing (hoặc đã bị xoá)
+	}
+	if err != nil {
+		return "", fmt.Errorf("error refreshing song status: %w", err)
+	}
+
+	if status == StatusReady {
+		r.notifyCreated(ctx, id)
+	}
+	return status, nil
+}
+
+// PublishDue makes scheduled albums and songs whose release time has passed
+// public and returns one event per release. Songs of an album released in
+// the same pass are covered by the album's event. Each row is flipped by a
+// single UPDATE, so concurrent schedulers never report a release twice.
+func (r *Repository) PublishDue(ctx context.Context) ([]release.Event, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var events []release.Event
+	albums := map[string]bool{}
+	rows, err := tx.Query(ctx, `
+		UPDATE albums SET visibility = 'public'
+		WHERE visibility = 'scheduled' AND release_at <= now()
+		RETURNING id::text, title, release_at
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("error publishing albums: %w", err)
+	}
+	for rows.Next() {
+		e := release.Event{Type: release.TypeAlbum}
+		if err := rows.Scan(&e.ID, &e.Title, &e.ReleaseAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("error scanning album release: %w", err)
+		}
+		albums[e.ID] = true
+		events = append(events, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error publishing albums: %w", err)
+	}
+
+	var songIDs []string
+	rows, err = tx.Query(ctx, `
+		UPDATE songs SET visibility = 'public'
+		WHERE visibility = 'scheduled' AND release_at <= now()
+		RETURNING id::text, title, COALESCE(album_id::text, ''), release_at, status
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("error publishing songs: %w", err)
+	}
+	for rows.Next() {
+		e := release.Event{Type: release.TypeSong}
+		var status string
+		if err := rows.Scan(&e.ID, &e.Title, &e.AlbumID, &e.ReleaseAt, &status); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("error scanning song release: %w", err)
+		}
+		songIDs = append(songIDs, e.ID)
+		// Song chưa xử lý xong không được thông báo
+		if status == StatusReady && !albums[e.AlbumID] {
+			events = append(events, e)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error publishing songs: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	for _, id := range songIDs {
+		r.notifyUpdated(ctx, id)
+	}
+	return events, nil
+}
+
+// SetAlbumRelease changes the visibility and release time of an album and
+// all its songs. Making hidden content public schedules it now, so it is
+// published (and announced) by PublishDue. It returns a withdrawn event when
+// a public album was hidden, nil otherwise.
+func (r *Repository) SetAlbumRelease(ctx context.Context, albumID, visibility string, releaseAt *time.Time) (*release.Event, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var current, title string
+	err = tx.QueryRow(ctx, `SELECT visibility, title FROM albums WHERE id = $1 FOR UPDATE`, albumID).Scan(&current, &title)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, album.ErrAlbumNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error querying album: %w", err)
+	}
+	visibility, releaseAt = release.ThroughScheduler(visibility, releaseAt, current, time.Now())
+
+	_, err = tx.Exec(ctx, `UPDATE albums SET visibility = $2, release_at = $3 WHERE id = $1`, albumID, visibility, releaseAt)
+	if err != nil {
+		return nil, fmt.Errorf("error updating album release: %w", err)
+	}
+
+	rows, err := tx.Query(ctx, `
+		UPDATE songs SET visibility = $2, release_at = $3
+		WHERE album_id = $1
+		RETURNING id::text
+	`, albumID, visibility, releaseAt)
+	if err != nil {
+		return nil, fmt.Errorf("error updating song releases: %w", err)
+	}
+	var songIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("error scanning song: %w", err)
+		}
+		songIDs = append(songIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error updating song releases: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	for _, id := range songIDs {
+		r.notifyUpdated(ctx, id)
+	}
+	if current == release.Public && visibility != release.Public {
+		return &release.Event{Type: release.TypeAlbum, ID: albumID, Title: title}, nil
+	}
+	return nil, nil
+}
+
+// notifyCreated loads the committed song (with artists, album) and passes it
+// to observers. Errors are not returned: the song is already saved.
+func (r *Repository) notifyCreated(ctx context.Context, id string) {
+	if len(r.observers) == 0 {
+		return
+	}
+	song, err := r.GetByID(ctx, id)
+	if err != nil {
+		return
+	}
+	for _, o := range r.observers {
+		o.SongCreated(*song)
+	}
+}
+
+// notifyUpdated passes the updated song to observers
+func (r *Repository) notifyUpdated(ctx context.Context, id string) {
+	if len(r.observers) == 0 {
+		return
+	}
+	song, err := r.GetByID(ctx, id)
+	if err != nil {
+		return
+	}
+	for _, o := range r.observers {
+		o.SongUpdated(*song)
+	}
+}
+
 // Helper function
 func stringOrEmpty(s *string) string {
 	if s == nil {
@@ -218,3 +1557,11 @@ func stringOrEmpty(s *string) string {
 	}
 	return *s
 }
+
+// nullIfEmpty returns nil for "" so it is stored as NULL
+func nullIfEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
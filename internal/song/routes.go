@@ -5,11 +5,26 @@ import (
 )
 
 // RegisterRoutes registers all song routes to the given router group
-func RegisterRoutes(rg *gin.RouterGroup, h *Handler) {
+func RegisterRoutes(rg *gin.RouterGroup, h *Handler, authMiddleware, optionalAuthMiddleware, adminMiddleware gin.HandlerFunc) {
 	songGroup := rg.Group("/songs")
 	{
-		songGroup.GET("/:id", h.GetSong)
+		// Public, song chưa phát hành chỉ hiện với uploader/admin
+		songGroup.GET("", optionalAuthMiddleware, adminMiddleware, h.ListSongs)
+		songGroup.GET("/:id", optionalAuthMiddleware, adminMiddleware, h.GetSong)
+		songGroup.GET("/:id/waveform", optionalAuthMiddleware, adminMiddleware, h.GetWaveform)
+		songGroup.GET("/:id/processing", authMiddleware, adminMiddleware, h.GetProcessing)
+		songGroup.GET("/:id/lyrics", optionalAuthMiddleware, adminMiddleware, h.GetLyrics)
+		// Stream yêu cầu URL đã ký (lấy từ /stream-url), limits theo tier của user trong URL
+		songGroup.GET("/:id/stream-url", authMiddleware, h.GetStreamURL)
 		songGroup.GET("/:id/stream", h.StreamSong)
-		songGroup.POST("/upload", h.UploadSong)
+		// HLS dùng cùng query đã ký như /stream
+		songGroup.GET("/:id/hls/index.m3u8", h.GetHLSPlaylist)
+		songGroup.GET("/:id/hls/segments/:segment", h.GetHLSSegment)
+		// Protected routes - uploader is recorded, only uploader/admin can edit or delete
+		songGroup.POST("/upload", authMiddleware, adminMiddleware, h.UploadSong)
+		songGroup.PATCH("/:id", authMiddleware, adminMiddleware, h.UpdateSong)
+		songGroup.DELETE("/:id", authMiddleware, adminMiddleware, h.DeleteSong)
+		songGroup.PUT("/:id/lyrics", authMiddleware, adminMiddleware, h.PutLyrics)
+		songGroup.DELETE("/:id/lyrics", authMiddleware, adminMiddleware, h.DeleteLyrics)
 	}
 }
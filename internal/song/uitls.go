@@ -1,11 +1,17 @@
 package song
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
+
+	"spotify-clone/pkg/audioduration"
 )
 
 // getFileExtension returns the file extension with dot (e.g., ".mp3")
@@ -14,6 +20,116 @@ func getFileExtension(filename string) string {
 	return strings.ToLower(ext)
 }
 
+// detectAudioType detects the audio format from the first bytes of a file
+// using magic bytes. Returns -1 if the format is not supported.
+func detectAudioType(buffer []byte) int {
+	// Check for MP3 (ID3 tag or MP3 frame sync)
+	if (len(buffer) >= 3 && buffer[0] == 0x49 && buffer[1] == 0x44 && buffer[2] == 0x33) || // ID3v2 tag
+		(len(buffer) >= 2 && buffer[0] == 0xFF && (buffer[1]&0xE0) == 0xE0) { // MP3 frame sync
+		return audioduration.TypeMp3
+	}
+
+	// Check for OGG (magic: OggS)
+	if len(buffer) >= 4 && buffer[0] == 0x4F && buffer[1] == 0x67 && buffer[2] == 0x67 && buffer[3] == 0x53 {
+		return audioduration.TypeOgg
+	}
+
+	// Check for FLAC (magic: fLaC)
+	if len(buffer) >= 4 && buffer[0] == 0x66 && buffer[1] == 0x4C && buffer[2] == 0x61 && buffer[3] == 0x43 {
+		return audioduration.TypeFlac
+	}
+
+	// Check for WAV (magic: RIFF....WAVE)
+	if len(buffer) >= 12 && string(buffer[0:4]) == "RIFF" && string(buffer[8:12]) == "WAVE" {
+		return audioduration.TypeWav
+	}
+
+	return -1
+}
+
+// audioExtension returns the file extension (with dot) of a detected audio format
+func audioExtension(audioType int) string {
+	switch audioType {
+	case audioduration.TypeMp3:
+		return ".mp3"
+	case audioduration.TypeOgg:
+		return ".ogg"
+	case audioduration.TypeFlac:
+		return ".flac"
+	case audioduration.TypeWav:
+		return ".wav"
+	default:
+		return ""
+	}
+}
+
+// audioContentType returns the MIME type of a detected audio format
+func audioContentType(audioType int) string {
+	switch audioType {
+	case audioduration.TypeMp3:
+		return "audio/mpeg"
+	case audioduration.TypeOgg:
+		return "audio/ogg"
+	case audioduration.TypeFlac:
+		return "audio/flac"
+	case audioduration.TypeWav:
+		return "audio/wav"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// truncate cuts s to at most n runes (column limits of VARCHAR(n))
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
+
+// truncateAll truncates every value of list
+func truncateAll(list []string, n int) []string {
+	out := make([]string, len(list))
+	for i, s := range list {
+		out[i] = truncate(s, n)
+	}
+	return out
+}
+
+// featuringSeparator matches "feat.", "ft." and "featuring" in artist tags
+var featuringSeparator = regexp.MustCompile(`(?i)\s+[(\[]?(?:feat\.?|ft\.|featuring)\s+`)
+
+// splitFeaturing splits artist names like "A feat. B & C" into performers
+// ("A") and featured artists ("B", "C"), keeping the order of first appearance
+func splitFeaturing(names []string) (performers, featured []string) {
+	for _, name := range names {
+		parts := featuringSeparator.Split(name, 2)
+		performers = appendName(performers, parts[0])
+		if len(parts) == 2 {
+			guests := strings.TrimRight(parts[1], ")] ")
+			for _, guest := range strings.FieldsFunc(guests, func(r rune) bool { return r == ',' || r == '&' }) {
+				featured = appendName(featured, guest)
+			}
+		}
+	}
+	return performers, featured
+}
+
+// appendName appends a trimmed, non-empty name that is not in list yet
+func appendName(list []string, name string) []string {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return list
+	}
+	for _, existing := range list {
+		if strings.EqualFold(existing, name) {
+			return list
+		}
+	}
+	return append(list, name)
+}
+
 // generateUUID generates a new UUID v7 string
 func generateUUID() string {
 	return uuid.Must(uuid.NewV7()).String()
@@ -23,3 +139,41 @@ func generateUUID() string {
 func getCurrentTime() time.Time {
 	return time.Now()
 }
+
+// EncodeCursor encodes a keyset cursor as an opaque URL-safe string
+func EncodeCursor(cursor SongCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// ErrInvalidCursor is returned for a cursor that was not produced by
+// EncodeCursor for the requested sort
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// DecodeCursor decodes a cursor produced by EncodeCursor and checks that it
+// has the keyset values needed by sort
+func DecodeCursor(s string, sort SongSort) (*SongCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor SongCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	// Giá trị sai sẽ làm query keyset lỗi (500) thay vì trả về 400
+	if cursor.CreatedAt.IsZero() || uuid.Validate(cursor.ID) != nil {
+		return nil, ErrInvalidCursor
+	}
+	switch sort {
+	case SortMostPlayed:
+		if cursor.PlayCount == nil || *cursor.PlayCount < 0 {
+			return nil, ErrInvalidCursor
+		}
+	case SortTitle:
+		if cursor.Title == "" {
+			return nil, ErrInvalidCursor
+		}
+	}
+	return &cursor, nil
+}
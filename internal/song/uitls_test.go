@@ -0,0 +1,60 @@
+package song
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDecodeCursorRoundTrip(t *testing.T) {
+	playCount := 0
+	cursor := SongCursor{
+		CreatedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		ID:        "0190c3b2-7d4e-7a9b-8c1d-2e3f4a5b6c7d",
+		PlayCount: &playCount,
+		Title:     "Song",
+	}
+	encoded := EncodeCursor(cursor)
+
+	for _, sort := range []SongSort{SortNewest, SortMostPlayed, SortTitle} {
+		decoded, err := DecodeCursor(encoded, sort)
+		if err != nil {
+			t.Fatalf("DecodeCursor(%s): %v", sort, err)
+		}
+		if !decoded.CreatedAt.Equal(cursor.CreatedAt) || decoded.ID != cursor.ID ||
+			decoded.PlayCount == nil || *decoded.PlayCount != 0 || decoded.Title != cursor.Title {
+			t.Fatalf("DecodeCursor(%s) = %+v, want %+v", sort, decoded, cursor)
+		}
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	const id = "0190c3b2-7d4e-7a9b-8c1d-2e3f4a5b6c7d"
+	encode := func(json string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(json))
+	}
+
+	tests := []struct {
+		name   string
+		cursor string
+		sort   SongSort
+	}{
+		{"not base64", "!!!", SortNewest},
+		{"not json", encode("not json"), SortNewest},
+		{"non UUID id", encode(`{"c":"2024-05-01T12:00:00Z","i":"x"}`), SortNewest},
+		{"missing id", encode(`{"c":"2024-05-01T12:00:00Z"}`), SortNewest},
+		{"missing created_at", encode(`{"i":"` + id + `"}`), SortNewest},
+		{"zero created_at", encode(`{"c":"0001-01-01T00:00:00Z","i":"` + id + `"}`), SortNewest},
+		{"missing play count", encode(`{"c":"2024-05-01T12:00:00Z","i":"` + id + `"}`), SortMostPlayed},
+		{"negative play count", encode(`{"c":"2024-05-01T12:00:00Z","i":"` + id + `","p":-1}`), SortMostPlayed},
+		{"missing title", encode(`{"c":"2024-05-01T12:00:00Z","i":"` + id + `","p":3}`), SortTitle},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeCursor(tt.cursor, tt.sort); !errors.Is(err, ErrInvalidCursor) {
+				t.Fatalf("DecodeCursor = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package song
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+
+	"spotify-clone/internal/release"
+	"spotify-clone/pkg/audioduration"
+	"spotify-clone/pkg/pcm"
+	"spotify-clone/pkg/waveform"
+)
+
+const (
+	// waveformSamplesPerPixel is the resolution peaks are first computed at
+	waveformSamplesPerPixel = 256
+	// maxWaveformPoints is how many (min, max) pairs are stored per song
+	maxWaveformPoints = 4000
+	// defaultWaveformPoints is returned when the client does not ask for a size
+	defaultWaveformPoints = 1000
+)
+
+// canDecode reports whether the format can be decoded to PCM, which the
+// waveform and loudness analyses need
+func canDecode(audioType int) bool {
+	return audioType == audioduration.TypeWav || audioType == audioduration.TypeFlac
+}
+
+// newDecoder returns a PCM decoder reading file from the start, or nil for
+// formats that cannot be decoded to PCM yet
+func newDecoder(file *os.File, audioType int) (pcm.Decoder, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	switch audioType {
+	case audioduration.TypeWav:
+		return pcm.NewWAVDecoder(file)
+	case audioduration.TypeFlac:
+		return pcm.NewFLACDecoder(file)
+	}
+	return nil, nil
+}
+
+// computeWaveform decodes a WAV or FLAC file and returns its peaks, or nil for
+// formats that cannot be decoded to PCM yet
+func computeWaveform(file *os.File, audioType int) (*waveform.Waveform, error) {
+	dec, err := newDecoder(file, audioType)
+	if dec == nil {
+		return nil, err
+	}
+
+	w, err := waveform.Generate(dec, waveformSamplesPerPixel)
+	if err != nil {
+		return nil, err
+	}
+	return w.Resample(maxWaveformPoints), nil
+}
+
+// GetWaveform returns waveform peaks of a song as audiowaveform JSON
+// or binary .dat (format=dat), downsampled to at most `points` pairs
+func (h *Handler) GetWaveform(c *gin.Context) {
+	var req WaveformRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query: " + err.Error()})
+		return
+	}
+	if req.Points == 0 {
+		req.Points = defaultWaveformPoints
+	}
+
+	song, err := h.repo.GetByID(c.Request.Context(), c.Param("id"))
+	if err != nil || !canView(c, song) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Song not found"})
+		return
+	}
+	if !h.requireAllowed(c, song) {
+		return
+	}
+
+	w, err := h.repo.GetWaveform(c.Request.Context(), song.ID)
+	if err != nil {
+		if errors.Is(err, ErrWaveformNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Waveform not available for this song"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load waveform"})
+		return
+	}
+	w = w.Resample(req.Points)
+
+	// Peaks không đổi sau khi upload; song chưa phát hành không được cache chung
+	if song.Visibility == release.Public {
+		c.Header("Cache-Control", "public, max-age=86400")
+	} else {
+		c.Header("Cache-Control", "private, no-cache")
+	}
+
+	if req.Format == "dat" {
+		var buf bytes.Buffer
+		if err := w.WriteDat(&buf); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode waveform"})
+			return
+		}
+		c.Data(http.StatusOK, "application/octet-stream", buf.Bytes())
+		return
+	}
+	c.JSON(http.StatusOK, w)
+}
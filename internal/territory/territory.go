@@ -0,0 +1,129 @@
+// Package territory restricts songs and albums to the countries they are
+// licensed in and resolves the country of a listener.
+package territory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"spotify-clone/pkg/geoip"
+)
+
+// ErrInvalidCode is returned for a territory that is not an ISO 3166-1
+// alpha-2 country code
+var ErrInvalidCode = errors.New("invalid territory code")
+
+var countryCode = regexp.MustCompile(`^[A-Z]{2}$`)
+
+// Rules are the territories a song or album may be played in: if Allow is
+// not empty, only in these; never in Deny. Codes are ISO 3166-1 alpha-2.
+type Rules struct {
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
+}
+
+// Restricted reports whether the rules limit availability at all
+func (r Rules) Restricted() bool {
+	return len(r.Allow) > 0 || len(r.Deny) > 0
+}
+
+// UnavailableError explains why content cannot be played in a country
+type UnavailableError struct {
+	Country string // "" when the listener's country is unknown
+	Reason  string
+}
+
+func (e *UnavailableError) Error() string {
+	return e.Reason
+}
+
+// Check returns an *UnavailableError if the rules exclude country.
+// Listeners of unknown country only get content without an allow list.
+func (r Rules) Check(country, what string) error {
+	switch {
+	case len(r.Allow) > 0 && country == "":
+		return &UnavailableError{Reason: fmt.Sprintf("This %s is only available in some countries and your country could not be determined", what)}
+	case len(r.Allow) > 0 && !slices.Contains(r.Allow, country):
+		return &UnavailableError{Country: country, Reason: fmt.Sprintf("This %s is not licensed in %s", what, country)}
+	case slices.Contains(r.Deny, country):
+		return &UnavailableError{Country: country, Reason: fmt.Sprintf("This %s is not available in %s", what, country)}
+	}
+	return nil
+}
+
+// Normalize upper-cases, validates, sorts and de-duplicates country codes
+func Normalize(codes []string) ([]string, error) {
+	out := make([]string, 0, len(codes))
+	for _, code := range codes {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if !countryCode.MatchString(code) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidCode, code)
+		}
+		out = append(out, code)
+	}
+	slices.Sort(out)
+	return slices.Compact(out), nil
+}
+
+// NormalizeRules normalizes both lists; a country cannot be in both
+func NormalizeRules(r Rules) (Rules, error) {
+	allow, err := Normalize(r.Allow)
+	if err != nil {
+		return Rules{}, err
+	}
+	deny, err := Normalize(r.Deny)
+	if err != nil {
+		return Rules{}, err
+	}
+	for _, code := range deny {
+		if slices.Contains(allow, code) {
+			return Rules{}, fmt.Errorf("%w: %s is both allowed and denied", ErrInvalidCode, code)
+		}
+	}
+	return Rules{Allow: allow, Deny: deny}, nil
+}
+
+// Condition returns a SQL condition that is true when the allow/deny array
+// columns admit the country expression (NULL = unknown country). NULL
+// arrays, e.g. of a missing LEFT JOIN row, admit everyone.
+func Condition(allowColumn, denyColumn, country string) string {
+	return fmt.Sprintf(`((COALESCE(cardinality(%[1]s), 0) = 0 OR COALESCE(%[3]s = ANY(%[1]s), FALSE))
+		AND NOT COALESCE(%[3]s = ANY(%[2]s), FALSE))`, allowColumn, denyColumn, country)
+}
+
+// ProfileCountries looks up the country a user set in their profile
+type ProfileCountries interface {
+	FindCountry(ctx context.Context, id uuid.UUID) (string, error)
+}
+
+// Resolver finds the country of a listener: the country of their profile
+// if it is a valid code, otherwise the GeoIP country of their address
+type Resolver struct {
+	profiles ProfileCountries
+	geo      *geoip.DB // nil disables GeoIP
+}
+
+// NewResolver creates a resolver, geo may be nil
+func NewResolver(profiles ProfileCountries, geo *geoip.DB) *Resolver {
+	return &Resolver{profiles: profiles, geo: geo}
+}
+
+// Country returns the listener's country code, "" if unknown.
+// userID may be "" for anonymous listeners.
+func (r *Resolver) Country(ctx context.Context, userID, ip string) string {
+	if id, err := uuid.Parse(userID); err == nil {
+		// Profile có thể lưu tên nước tự do, chỉ dùng khi là mã 2 ký tự
+		if country, err := r.profiles.FindCountry(ctx, id); err == nil {
+			if code, err := Normalize([]string{country}); err == nil {
+				return code[0]
+			}
+		}
+	}
+	return r.geo.LookupString(ip)
+}
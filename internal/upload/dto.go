@@ -0,0 +1,15 @@
+package upload
+
+import "time"
+
+// UploadStatusResponse describes a resumable upload and, once the file has
+// been processed, the created song
+type UploadStatusResponse struct {
+	ID        string            `json:"id"`
+	Offset    int64             `json:"offset"`
+	Length    int64             `json:"length"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	ExpiresAt time.Time         `json:"expires_at"`
+	Completed bool              `json:"completed"`
+	SongID    string            `json:"song_id,omitempty"`
+}
@@ -0,0 +1,346 @@
+package upload
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+
+	"spotify-clone/internal/middleware"
+	"spotify-clone/internal/release"
+	"spotify-clone/internal/song"
+	"spotify-clone/pkg/tus"
+)
+
+// songIDHeader carries the created song ID on the PATCH that completes an upload
+const songIDHeader = "X-Song-Id"
+
+// Handler implements the tus 1.0 protocol for song uploads. A completed
+// upload goes through the same Ingestor as POST /api/songs/upload.
+//
+// Upload-Metadata keys: filename, title, album_id, artist_ids and genre_ids
+// (comma separated), link_as_new_release ("true"), visibility and
+// release_at (RFC 3339).
+type Handler struct {
+	store    *tus.Store
+	ingestor *song.Ingestor
+	maxSize  int64
+}
+
+// NewHandler creates a new upload handler
+func NewHandler(store *tus.Store, ingestor *song.Ingestor, maxSize int64) *Handler {
+	return &Handler{store: store, ingestor: ingestor, maxSize: maxSize}
+}
+
+// tusHeaders adds Tus-Resumable to every response and rejects requests
+// made with another protocol version (OPTIONS excepted)
+func tusHeaders(c *gin.Context) {
+	c.Header("Tus-Resumable", tus.Version)
+	if c.Request.Method != http.MethodOptions && c.GetHeader("Tus-Resumable") != tus.Version {
+		c.Header("Tus-Version", tus.Version)
+		c.AbortWithStatusJSON(http.StatusPreconditionFailed, gin.H{"error": "Unsupported Tus-Resumable version"})
+		return
+	}
+	c.Next()
+}
+
+// Options advertises the protocol version, extensions and maximum size
+func (h *Handler) Options(c *gin.Context) {
+	c.Header("Tus-Version", tus.Version)
+	c.Header("Tus-Extension", tus.Extensions)
+	c.Header("Tus-Max-Size", strconv.FormatInt(h.maxSize, 10))
+	c.Status(http.StatusNoContent)
+}
+
+// Create starts an upload (creation extension)
+func (h *Handler) Create(c *gin.Context) {
+	length, err := strconv.ParseInt(c.GetHeader("Upload-Length"), 10, 64)
+	if err != nil || length <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Upload-Length must be a positive integer"})
+		return
+	}
+	if length > h.maxSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File exceeds Tus-Max-Size"})
+		return
+	}
+
+	meta, err := tus.ParseMetadata(c.GetHeader("Upload-Metadata"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Upload-Metadata"})
+		return
+	}
+	if utf8.RuneCountInString(meta["title"]) > 255 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Title must be at most 255 characters"})
+		return
+	}
+	// Kiểm tra release trước khi nhận dữ liệu, Ingest sẽ resolve lại lúc hoàn tất
+	releaseAt, err := parseReleaseAt(meta["release_at"])
+	if err == nil {
+		_, _, err = release.Resolve(meta["visibility"], releaseAt, time.Now())
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := parseExplicit(meta["explicit"]); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := middleware.GetUserID(c)
+	if meta["album_id"] != "" {
+		if err := h.ingestor.CheckAlbum(c.Request.Context(), meta["album_id"], userID, middleware.IsAdmin(c)); err != nil {
+			c.JSON(song.IngestErrorResponse(err))
+			return
+		}
+	}
+	if err := h.ingestor.CheckArtists(c.Request.Context(), splitIDs(meta["artist_ids"])); err != nil {
+		c.JSON(song.IngestErrorResponse(err))
+		return
+	}
+	info, err := h.store.Create(userID, length, meta)
+	if err != nil {
+		log.Println("tus:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create upload"})
+		return
+	}
+
+	c.Header("Location", strings.TrimSuffix(c.FullPath(), "/")+"/"+info.ID)
+	c.Header("Upload-Expires", info.ExpiresAt.UTC().Format(http.TimeFormat))
+	c.Status(http.StatusCreated)
+}
+
+// Head returns the current offset so the client can resume
+func (h *Handler) Head(c *gin.Context) {
+	info, ok := h.getOwned(c)
+	if !ok {
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Header("Upload-Offset", strconv.FormatInt(info.Offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(info.Length, 10))
+	if len(info.Metadata) > 0 {
+		c.Header("Upload-Metadata", tus.EncodeMetadata(info.Metadata))
+	}
+	c.Header("Upload-Expires", info.ExpiresAt.UTC().Format(http.TimeFormat))
+	c.Status(http.StatusOK)
+}
+
+// Patch appends a chunk at Upload-Offset. The PATCH receiving the last byte
+// also creates the song; a PATCH with an empty body at the final offset
+// retries processing if it failed before, or returns the created song again
+// if it succeeded.
+func (h *Handler) Patch(c *gin.Context) {
+	if c.ContentType() != tus.ContentType {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Content-Type must be " + tus.ContentType})
+		return
+	}
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Upload-Offset must be a non-negative integer"})
+		return
+	}
+
+	// Một upload chỉ được một request ghi/xử lý tại một thời điểm
+	unlock, err := h.store.Lock(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusLocked, gin.H{"error": "Upload is in use by another request"})
+		return
+	}
+	defer unlock()
+
+	current, ok := h.getOwned(c)
+	if !ok {
+		return
+	}
+	// PATCH cuối được gửi lại sau khi đã tạo song (mất response): data đã bị xóa, trả lại kết quả cũ
+	if current.Result != "" && offset == current.Length {
+		c.Header("Upload-Offset", strconv.FormatInt(current.Offset, 10))
+		c.Header("Upload-Expires", current.ExpiresAt.UTC().Format(http.TimeFormat))
+		c.Header(songIDHeader, current.Result)
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	info, err := h.store.Append(c.Param("id"), offset, c.Request.Body)
+	if info != nil {
+		c.Header("Upload-Offset", strconv.FormatInt(info.Offset, 10))
+		c.Header("Upload-Expires", info.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+	switch {
+	case errors.Is(err, tus.ErrOffsetMismatch):
+		c.JSON(http.StatusConflict, gin.H{"error": "Upload-Offset does not match the current offset"})
+		return
+	case errors.Is(err, tus.ErrTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Data exceeds Upload-Length"})
+		return
+	case errors.Is(err, tus.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Upload not found"})
+		return
+	case err != nil && info != nil:
+		// Kết nối bị ngắt giữa chừng: phần đã nhận được giữ lại, client HEAD rồi gửi tiếp
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Upload interrupted, resume from Upload-Offset"})
+		return
+	case err != nil:
+		log.Println("tus:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store chunk"})
+		return
+	}
+
+	if info.Complete() {
+		if info.Result == "" {
+			var ok bool
+			if info, ok = h.process(c, info); !ok {
+				return
+			}
+		}
+		c.Header(songIDHeader, info.Result)
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// process ingests a completed upload. Files rejected by validation
+// (format, duplicate, missing title) are removed; other errors keep the
+// upload so processing can be retried.
+func (h *Handler) process(c *gin.Context, info *tus.Info) (*tus.Info, bool) {
+	file, err := h.store.Open(info.ID)
+	if err != nil {
+		log.Println("tus:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Cannot read upload"})
+		return nil, false
+	}
+	defer file.Close()
+
+	meta := info.Metadata
+	releaseAt, _ := parseReleaseAt(meta["release_at"]) // đã kiểm tra ở Create
+	explicit, _ := parseExplicit(meta["explicit"])
+	created, err := h.ingestor.Ingest(c.Request.Context(), song.IngestInput{
+		File:           file,
+		Filename:       meta["filename"],
+		Title:          meta["title"],
+		AlbumID:        meta["album_id"],
+		ArtistIDs:      splitIDs(meta["artist_ids"]),
+		GenreIDs:       splitIDs(meta["genre_ids"]),
+		UploadedBy:     info.Owner,
+		IsAdmin:        middleware.IsAdmin(c),
+		Visibility:     meta["visibility"],
+		ReleaseAt:      releaseAt,
+		Explicit:       explicit,
+		AllowDuplicate: meta["link_as_new_release"] == "true",
+	})
+	if err != nil {
+		status, body := song.IngestErrorResponse(err)
+		if status < http.StatusInternalServerError {
+			h.store.Delete(info.ID)
+		}
+		c.JSON(status, body)
+		return nil, false
+	}
+
+	info, err = h.store.Finish(info.ID, created.ID)
+	if err != nil {
+		log.Println("tus:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to finish upload"})
+		return nil, false
+	}
+	return info, true
+}
+
+// Delete cancels an upload (termination extension)
+func (h *Handler) Delete(c *gin.Context) {
+	unlock, err := h.store.Lock(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusLocked, gin.H{"error": "Upload is in use by another request"})
+		return
+	}
+	defer unlock()
+
+	if _, ok := h.getOwned(c); !ok {
+		return
+	}
+	if err := h.store.Delete(c.Param("id")); err != nil && !errors.Is(err, tus.ErrNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete upload"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetStatus returns the upload as JSON, including the created song ID
+func (h *Handler) GetStatus(c *gin.Context) {
+	info, ok := h.getOwned(c)
+	if !ok {
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, UploadStatusResponse{
+		ID:        info.ID,
+		Offset:    info.Offset,
+		Length:    info.Length,
+		Metadata:  info.Metadata,
+		ExpiresAt: info.ExpiresAt,
+		Completed: info.Result != "",
+		SongID:    info.Result,
+	})
+}
+
+// getOwned loads the upload of the :id param. Uploads of other users are
+// reported as not found.
+func (h *Handler) getOwned(c *gin.Context) (*tus.Info, bool) {
+	info, err := h.store.Get(c.Param("id"))
+	if err != nil {
+		if errors.Is(err, tus.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Upload not found"})
+			return nil, false
+		}
+		log.Println("tus:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load upload"})
+		return nil, false
+	}
+	userID, _ := middleware.GetUserID(c)
+	if info.Owner != userID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Upload not found"})
+		return nil, false
+	}
+	return info, true
+}
+
+// splitIDs splits a comma separated metadata value
+func splitIDs(s string) []string {
+	var ids []string
+	for _, id := range strings.Split(s, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// parseReleaseAt parses the release_at metadata value, nil when empty
+func parseReleaseAt(s string) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: release_at must be an RFC 3339 time", release.ErrInvalid)
+	}
+	return &t, nil
+}
+
+// parseExplicit parses the explicit metadata value, nil when empty
+func parseExplicit(s string) (*bool, error) {
+	if s == "" {
+		return nil, nil
+	}
+	explicit, err := strconv.ParseBool(s)
+	if err != nil {
+		return nil, errors.New("explicit must be true or false")
+	}
+	return &explicit, nil
+}
@@ -0,0 +1,25 @@
+package upload
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRoutes registers the tus upload endpoint to the given router group
+func RegisterRoutes(rg *gin.RouterGroup, h *Handler, authMiddleware, adminMiddleware gin.HandlerFunc) {
+	uploadGroup := rg.Group("/uploads", tusHeaders)
+	{
+		// Public - tus clients discover the server's capabilities
+		uploadGroup.OPTIONS("", h.Options)
+		uploadGroup.OPTIONS("/", h.Options)
+		uploadGroup.OPTIONS("/:id", h.Options)
+
+		// Protected - uploads are only visible to the user who created them,
+		// admins may add songs to any album
+		uploadGroup.POST("", authMiddleware, adminMiddleware, h.Create)
+		uploadGroup.POST("/", authMiddleware, adminMiddleware, h.Create)
+		uploadGroup.HEAD("/:id", authMiddleware, h.Head)
+		uploadGroup.PATCH("/:id", authMiddleware, adminMiddleware, h.Patch)
+		uploadGroup.DELETE("/:id", authMiddleware, h.Delete)
+		uploadGroup.GET("/:id", authMiddleware, h.GetStatus)
+	}
+}
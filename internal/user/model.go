@@ -7,12 +7,21 @@ import (
 	"github.com/google/uuid"
 )
 
+// Tier is the subscription level of a user
+type Tier string
+
+const (
+	TierFree    Tier = "free"
+	TierPremium Tier = "premium"
+)
+
 // Core authentication
 type User struct {
 	ID        uuid.UUID `json:"id"`
 	Email     string    `json:"email"`
 	Username  string    `json:"username"`
 	Password  string    `json:"-"`
+	Tier      Tier      `json:"tier"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -28,6 +37,12 @@ type UserProfile struct {
 	Country     string    `json:"country"`
 }
 
+// ExplicitSetting is what decides whether a user may play explicit content
+type ExplicitSetting struct {
+	Birthday      *time.Time // nil if not in the profile
+	AllowExplicit *bool      // nil = default
+}
+
 func (p *User) Validate() error {
 	if p.Username == "" {
 		return errors.New("username required")
@@ -23,6 +23,9 @@ type UserRepository interface {
 	FindByID(ctx context.Context, id uuid.UUID) (*User, error)
 	FindByEmail(ctx context.Context, email string) (*User, error)
 	FindByUsername(ctx context.Context, username string) (*User, error)
+	FindCountry(ctx context.Context, id uuid.UUID) (string, error)
+	FindExplicitSetting(ctx context.Context, id uuid.UUID) (*ExplicitSetting, error)
+	SetAllowExplicit(ctx context.Context, id uuid.UUID, allow *bool) error
 	Update(ctx context.Context, user *User) error
 	Delete(ctx context.Context, id uuid.UUID) error
 }
@@ -60,10 +63,10 @@ func (r *userRepository) Create(ctx context.Context, user *User) error {
 }
 
 func (r *userRepository) FindByID(ctx context.Context, id uuid.UUID) (*User, error) {
-	query := `SELECT id, email, username FROM users WHERE id = $1`
+	query := `SELECT id, email, username, tier FROM users WHERE id = $1`
 
 	user := &User{}
-	err := r.db.QueryRow(ctx, query, id).Scan(&user.ID, &user.Email, &user.Username)
+	err := r.db.QueryRow(ctx, query, id).Scan(&user.ID, &user.Email, &user.Username, &user.Tier)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrUserNotFound
@@ -75,10 +78,10 @@ func (r *userRepository) FindByID(ctx context.Context, id uuid.UUID) (*User, err
 }
 
 func (r *userRepository) FindByEmail(ctx context.Context, email string) (*User, error) {
-	query := `SELECT id, email, password, username, created_at, updated_at FROM users WHERE email = $1`
+	query := `SELECT id, email, password, username, tier, created_at, updated_at FROM users WHERE email = $1`
 
 	user := &User{}
-	err := r.db.QueryRow(ctx, query, email).Scan(&user.ID, &user.Email, &user.Password, &user.Username, &user.CreatedAt, &user.UpdatedAt)
+	err := r.db.QueryRow(ctx, query, email).Scan(&user.ID, &user.Email, &user.Password, &user.Username, &user.Tier, &user.CreatedAt, &user.UpdatedAt)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrUserNotFound
@@ -90,10 +93,10 @@ func (r *userRepository) FindByEmail(ctx context.Context, email string) (*User,
 }
 
 func (r *userRepository) FindByUsername(ctx context.Context, username string) (*User, error) {
-	query := `SELECT id, email, password, username, created_at, updated_at FROM users WHERE username = $1`
+	query := `SELECT id, email, password, username, tier, created_at, updated_at FROM users WHERE username = $1`
 
 	user := &User{}
-	err := r.db.QueryRow(ctx, query, username).Scan(&user.ID, &user.Email, &user.Password, &user.Username, &user.CreatedAt, &user.UpdatedAt)
+	err := r.db.QueryRow(ctx, query, username).Scan(&user.ID, &user.Email, &user.Password, &user.Username, &user.Tier, &user.CreatedAt, &user.UpdatedAt)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrUserNotFound
@@ -104,6 +107,55 @@ func (r *userRepository) FindByUsername(ctx context.Context, username string) (*
 	return user, nil
 }
 
+// FindCountry returns the country of the user's profile, "" if not set
+func (r *userRepository) FindCountry(ctx context.Context, id uuid.UUID) (string, error) {
+	query := `SELECT COALESCE(country, '') FROM user_profiles WHERE user_id = $1`
+
+	var country string
+	err := r.db.QueryRow(ctx, query, id).Scan(&country)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil
+		}
+		return "", fmt.Errorf("unable to query user profile: %w", err)
+	}
+
+	return country, nil
+}
+
+// FindExplicitSetting returns the birthday and explicit content preference
+// of the user's profile, both nil without a profile
+func (r *userRepository) FindExplicitSetting(ctx context.Context, id uuid.UUID) (*ExplicitSetting, error) {
+	query := `SELECT birthday, allow_explicit FROM user_profiles WHERE user_id = $1`
+
+	setting := &ExplicitSetting{}
+	err := r.db.QueryRow(ctx, query, id).Scan(&setting.Birthday, &setting.AllowExplicit)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return &ExplicitSetting{}, nil
+		}
+		return nil, fmt.Errorf("unable to query user profile: %w", err)
+	}
+
+	return setting, nil
+}
+
+// SetAllowExplicit stores the explicit content preference, nil resets it to
+// the default. A profile is created if the user has none.
+func (r *userRepository) SetAllowExplicit(ctx context.Context, id uuid.UUID, allow *bool) error {
+	query := `
+        INSERT INTO user_profiles (user_id, allow_explicit)
+        VALUES ($1, $2)
+        ON CONFLICT (user_id) DO UPDATE
+        SET allow_explicit = EXCLUDED.allow_explicit, updated_at = CURRENT_TIMESTAMP
+    `
+	_, err := r.db.Exec(ctx, query, id, allow)
+	if err != nil {
+		return fmt.Errorf("unable to update user profile: %w", err)
+	}
+	return nil
+}
+
 func (r *userRepository) Update(ctx context.Context, user *User) error {
 	query := `
         UPDATE users
@@ -0,0 +1,3 @@
+-- Rollback 007_add_user_tier
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_tier_check;
+ALTER TABLE users DROP COLUMN IF EXISTS tier;
@@ -0,0 +1,5 @@
+-- migrations/007_add_user_tier.sql
+-- Add subscription tier to users (free / premium)
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS tier VARCHAR(20) DEFAULT 'free' NOT NULL;
+ALTER TABLE users ADD CONSTRAINT users_tier_check CHECK (tier IN ('free', 'premium'));
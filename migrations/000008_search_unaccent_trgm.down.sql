@@ -0,0 +1,13 @@
+-- Rollback 008_search_unaccent_trgm
+DROP INDEX IF EXISTS idx_songs_title_trgm;
+DROP INDEX IF EXISTS idx_artists_name_trgm;
+DROP INDEX IF EXISTS idx_albums_title_trgm;
+DROP INDEX IF EXISTS idx_playlists_name_trgm;
+DROP FUNCTION IF EXISTS search_normalize(text);
+DROP INDEX IF EXISTS idx_songs_title_search_unaccent;
+DROP INDEX IF EXISTS idx_artists_name_search_unaccent;
+DROP INDEX IF EXISTS idx_albums_title_search_unaccent;
+DROP INDEX IF EXISTS idx_playlists_name_search_unaccent;
+DROP TEXT SEARCH CONFIGURATION IF EXISTS simple_unaccent;
+DROP EXTENSION IF EXISTS pg_trgm;
+DROP EXTENSION IF EXISTS unaccent;
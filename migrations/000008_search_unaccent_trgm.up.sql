@@ -0,0 +1,33 @@
+-- migrations/008_search_unaccent_trgm.sql
+-- Accent-insensitive and typo-tolerant search
+
+CREATE EXTENSION IF NOT EXISTS unaccent;
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- ============================================
+-- ACCENT-INSENSITIVE FULL TEXT SEARCH
+-- ============================================
+
+-- Text search config that strips diacritics before indexing ("Sơn Tùng" -> "son tung")
+CREATE TEXT SEARCH CONFIGURATION simple_unaccent (COPY = simple);
+ALTER TEXT SEARCH CONFIGURATION simple_unaccent
+    ALTER MAPPING FOR hword, hword_part, word WITH unaccent, simple;
+
+CREATE INDEX idx_songs_title_search_unaccent ON songs USING gin(to_tsvector('simple_unaccent', title));
+CREATE INDEX idx_artists_name_search_unaccent ON artists USING gin(to_tsvector('simple_unaccent', name));
+CREATE INDEX idx_albums_title_search_unaccent ON albums USING gin(to_tsvector('simple_unaccent', title));
+CREATE INDEX idx_playlists_name_search_unaccent ON playlists USING gin(to_tsvector('simple_unaccent', name));
+
+-- ============================================
+-- TRIGRAM (FUZZY) SEARCH
+-- ============================================
+
+-- unaccent() is only STABLE, so wrap it to be usable in index expressions
+CREATE OR REPLACE FUNCTION search_normalize(text) RETURNS text AS $$
+    SELECT lower(public.unaccent('public.unaccent'::regdictionary, $1))
+$$ LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT;
+
+CREATE INDEX idx_songs_title_trgm ON songs USING gin(search_normalize(title) gin_trgm_ops);
+CREATE INDEX idx_artists_name_trgm ON artists USING gin(search_normalize(name) gin_trgm_ops);
+CREATE INDEX idx_albums_title_trgm ON albums USING gin(search_normalize(title) gin_trgm_ops);
+CREATE INDEX idx_playlists_name_trgm ON playlists USING gin(search_normalize(name) gin_trgm_ops);
@@ -0,0 +1,3 @@
+-- Rollback 009_add_song_uploader
+DROP INDEX IF EXISTS idx_songs_uploaded_by;
+ALTER TABLE songs DROP COLUMN IF EXISTS uploaded_by;
@@ -0,0 +1,5 @@
+-- migrations/009_add_song_uploader.sql
+-- Track who uploaded each song (only the uploader or an admin can edit/delete it)
+
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS uploaded_by UUID REFERENCES users(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_songs_uploaded_by ON songs(uploaded_by);
@@ -0,0 +1,4 @@
+-- Rollback 010_song_storage_keys
+UPDATE songs
+SET file_url = './assets/audio/' || regexp_replace(file_url, '^audio/', '')
+WHERE file_url LIKE 'audio/%';
@@ -0,0 +1,9 @@
+-- migrations/010_song_storage_keys.sql
+-- songs.file_url now stores an opaque storage key ("audio/<uuid>.mp3")
+-- instead of a filesystem path ("./assets/audio/<uuid>.mp3").
+-- Existing files must be moved to <MUSIC_PATH>/audio/ (local) or uploaded
+-- to the bucket under audio/ (s3).
+
+UPDATE songs
+SET file_url = 'audio/' || regexp_replace(file_url, '^.*/', '')
+WHERE file_url NOT LIKE 'audio/%';
@@ -0,0 +1,4 @@
+-- Rollback 011_content_addressed_audio
+DROP INDEX IF EXISTS idx_songs_file_digest;
+ALTER TABLE songs DROP COLUMN IF EXISTS file_digest;
+DROP TABLE IF EXISTS audio_blobs CASCADE;
@@ -0,0 +1,13 @@
+-- migrations/011_content_addressed_audio.sql
+-- Audio files are stored once per SHA-256 digest and reference-counted by songs
+
+CREATE TABLE audio_blobs (
+    digest CHAR(64) PRIMARY KEY,           -- hex SHA-256 of the file
+    storage_key TEXT NOT NULL,
+    size BIGINT NOT NULL,
+    ref_count INT NOT NULL DEFAULT 0,      -- number of songs using this blob
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS file_digest CHAR(64) REFERENCES audio_blobs(digest);
+CREATE INDEX IF NOT EXISTS idx_songs_file_digest ON songs(file_digest);
@@ -0,0 +1,2 @@
+-- Rollback 012_add_song_audio_format
+ALTER TABLE songs DROP COLUMN IF EXISTS audio_format;
@@ -0,0 +1,14 @@
+-- migrations/012_add_song_audio_format.sql
+-- Persist the detected audio format (audioduration.Type* constants:
+-- 0 = FLAC, 2 = MP3, 3 = OGG) so streams get the right Content-Type
+
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS audio_format SMALLINT;
+
+-- Backfill existing songs from the file extension
+UPDATE songs
+SET audio_format = CASE
+    WHEN file_url ILIKE '%.flac' THEN 0
+    WHEN file_url ILIKE '%.ogg' THEN 3
+    ELSE 2
+END
+WHERE audio_format IS NULL;
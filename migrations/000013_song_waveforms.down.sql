@@ -0,0 +1,2 @@
+-- Rollback 013_song_waveforms
+DROP TABLE IF EXISTS song_waveforms;
@@ -0,0 +1,11 @@
+-- migrations/013_song_waveforms.sql
+-- Waveform peaks for the player UI, computed at upload time for WAV/FLAC.
+-- peaks holds 16-bit (min, max) pairs: min0, max0, min1, max1, ...
+
+CREATE TABLE IF NOT EXISTS song_waveforms (
+    song_id UUID PRIMARY KEY REFERENCES songs(id) ON DELETE CASCADE,
+    sample_rate INTEGER NOT NULL,
+    samples_per_pixel INTEGER NOT NULL,
+    peaks SMALLINT[] NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,5 @@
+-- Rollback 014_processing_jobs
+DROP TABLE IF EXISTS jobs;
+DROP INDEX IF EXISTS idx_songs_status;
+ALTER TABLE songs DROP CONSTRAINT IF EXISTS songs_status_check;
+ALTER TABLE songs DROP COLUMN IF EXISTS status;
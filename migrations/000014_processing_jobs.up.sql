@@ -0,0 +1,28 @@
+-- migrations/014_processing_jobs.sql
+-- Uploads are processed asynchronously: the song is created in 'processing'
+-- state and jobs (duration, tags, artwork, waveform, ...) are queued.
+-- Workers claim jobs with SELECT ... FOR UPDATE SKIP LOCKED.
+
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS status VARCHAR(20) DEFAULT 'ready' NOT NULL;
+ALTER TABLE songs ADD CONSTRAINT songs_status_check CHECK (status IN ('processing', 'ready', 'failed'));
+CREATE INDEX IF NOT EXISTS idx_songs_status ON songs(status) WHERE status <> 'ready';
+
+CREATE TABLE jobs (
+    id BIGSERIAL PRIMARY KEY,
+    kind VARCHAR(50) NOT NULL,
+    song_id UUID REFERENCES songs(id) ON DELETE CASCADE,
+    payload JSONB NOT NULL DEFAULT '{}',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'running', 'done', 'failed')),
+    attempts INT NOT NULL DEFAULT 0,
+    max_attempts INT NOT NULL DEFAULT 5,
+    last_error TEXT,
+    run_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,  -- not claimed before this time (retry backoff)
+    locked_at TIMESTAMP,                                  -- when a worker claimed it
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_jobs_pending ON jobs(run_at, id) WHERE status = 'pending';
+CREATE INDEX idx_jobs_running ON jobs(locked_at) WHERE status = 'running';
+CREATE INDEX idx_jobs_song ON jobs(song_id);
@@ -0,0 +1,12 @@
+-- Rollback 015_song_loudness
+DELETE FROM jobs WHERE kind = 'song.loudness';
+DROP TABLE IF EXISTS song_loudness_histograms;
+ALTER TABLE albums
+    DROP COLUMN IF EXISTS loudness_lufs,
+    DROP COLUMN IF EXISTS album_gain_db,
+    DROP COLUMN IF EXISTS album_peak;
+ALTER TABLE songs
+    DROP COLUMN IF EXISTS loudness_lufs,
+    DROP COLUMN IF EXISTS true_peak_dbtp,
+    DROP COLUMN IF EXISTS track_gain_db,
+    DROP COLUMN IF EXISTS track_peak;
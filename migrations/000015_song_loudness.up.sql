@@ -0,0 +1,27 @@
+-- migrations/015_song_loudness.sql
+-- EBU R128 / ReplayGain 2.0 normalization metadata, computed by the
+-- song.loudness job for WAV/FLAC. Gains are relative to -18 LUFS, peaks are
+-- linear (1.0 = full scale). Album values treat all tracks of the album as
+-- one programme, computed from the per-song histograms of gating blocks.
+
+ALTER TABLE songs
+    ADD COLUMN IF NOT EXISTS loudness_lufs DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS true_peak_dbtp DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS track_gain_db DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS track_peak DOUBLE PRECISION;
+
+ALTER TABLE albums
+    ADD COLUMN IF NOT EXISTS loudness_lufs DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS album_gain_db DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS album_peak DOUBLE PRECISION;
+
+-- Counts of 400 ms blocks per 0.1 LU bin from -70 to +30 LUFS
+CREATE TABLE IF NOT EXISTS song_loudness_histograms (
+    song_id UUID PRIMARY KEY REFERENCES songs(id) ON DELETE CASCADE,
+    histogram INTEGER[] NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Phân tích các bài WAV (5) / FLAC (0) đã có
+INSERT INTO jobs (kind, song_id)
+SELECT 'song.loudness', id FROM songs WHERE audio_format IN (0, 5);
@@ -0,0 +1,2 @@
+-- Rollback 016_song_lyrics
+DROP TABLE IF EXISTS song_lyrics;
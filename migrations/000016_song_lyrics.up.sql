@@ -0,0 +1,16 @@
+-- migrations/016_song_lyrics.sql
+-- Lyrics per song and language, uploaded (plain or LRC) or read from the
+-- file's tags (ID3v2 USLT/SYLT, LYRICS Vorbis comment).
+-- lines is a JSON array of {"time_ms": 12340, "text": "..."}; time_ms is 0
+-- for lyrics that are not synced.
+
+CREATE TABLE IF NOT EXISTS song_lyrics (
+    song_id UUID NOT NULL REFERENCES songs(id) ON DELETE CASCADE,
+    language VARCHAR(35) NOT NULL,  -- BCP 47 / ISO 639 code, 'und' if unknown
+    synced BOOLEAN NOT NULL DEFAULT FALSE,
+    lines JSONB NOT NULL,
+    source VARCHAR(20) NOT NULL DEFAULT 'upload' CHECK (source IN ('upload', 'tag')),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (song_id, language)
+);
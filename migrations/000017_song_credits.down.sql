@@ -0,0 +1,9 @@
+-- Rollback 017_song_credits
+-- Chỉ giữ lại performer, mỗi artist một dòng như trước
+DELETE FROM song_artists WHERE role <> 'performer';
+ALTER TABLE song_artists DROP CONSTRAINT song_artists_pkey;
+ALTER TABLE song_artists ADD PRIMARY KEY (song_id, artist_id);
+ALTER TABLE song_artists DROP CONSTRAINT IF EXISTS song_artists_role_check;
+ALTER TABLE song_artists
+    DROP COLUMN IF EXISTS role,
+    DROP COLUMN IF EXISTS position;
@@ -0,0 +1,24 @@
+-- migrations/017_song_credits.sql
+-- Credits: an artist can appear on a song with several roles (performer,
+-- featured, composer, lyricist, producer, remixer), ordered per role.
+-- is_primary is kept for the first performer.
+
+ALTER TABLE song_artists
+    ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'performer',
+    ADD COLUMN IF NOT EXISTS position INT NOT NULL DEFAULT 0;
+
+ALTER TABLE song_artists ADD CONSTRAINT song_artists_role_check
+    CHECK (role IN ('performer', 'featured', 'composer', 'lyricist', 'producer', 'remixer'));
+
+-- Thứ tự hiện tại: primary trước, sau đó theo tên
+UPDATE song_artists sa SET position = o.position
+FROM (
+    SELECT sa2.song_id, sa2.artist_id,
+        ROW_NUMBER() OVER (PARTITION BY sa2.song_id ORDER BY sa2.is_primary DESC, a.name ASC) - 1 AS position
+    FROM song_artists sa2
+    INNER JOIN artists a ON a.id = sa2.artist_id
+) o
+WHERE sa.song_id = o.song_id AND sa.artist_id = o.artist_id;
+
+ALTER TABLE song_artists DROP CONSTRAINT song_artists_pkey;
+ALTER TABLE song_artists ADD PRIMARY KEY (song_id, artist_id, role);
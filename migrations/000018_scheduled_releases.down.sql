@@ -0,0 +1,11 @@
+-- Rollback 018_scheduled_releases
+DROP INDEX IF EXISTS idx_albums_release;
+DROP INDEX IF EXISTS idx_songs_release;
+ALTER TABLE albums DROP CONSTRAINT IF EXISTS albums_visibility_check;
+ALTER TABLE albums
+    DROP COLUMN IF EXISTS visibility,
+    DROP COLUMN IF EXISTS release_at;
+ALTER TABLE songs DROP CONSTRAINT IF EXISTS songs_visibility_check;
+ALTER TABLE songs
+    DROP COLUMN IF EXISTS visibility,
+    DROP COLUMN IF EXISTS release_at;
@@ -0,0 +1,28 @@
+-- migrations/018_scheduled_releases.sql
+-- Songs and albums can be uploaded ahead of their release:
+--   public    - visible to everyone
+--   scheduled - hidden until release_at, then made public by the scheduler
+--   private   - only visible to the uploader and admins
+-- Scheduling an album applies the same visibility to its songs.
+-- release_at is TIMESTAMPTZ: releases are planned at a local time (midnight
+-- in the label's time zone).
+
+ALTER TABLE songs
+    ADD COLUMN IF NOT EXISTS visibility VARCHAR(20) NOT NULL DEFAULT 'public',
+    ADD COLUMN IF NOT EXISTS release_at TIMESTAMPTZ;
+ALTER TABLE songs ADD CONSTRAINT songs_visibility_check CHECK (
+    visibility IN ('public', 'scheduled', 'private')
+    AND (visibility <> 'scheduled' OR release_at IS NOT NULL)
+);
+
+ALTER TABLE albums
+    ADD COLUMN IF NOT EXISTS visibility VARCHAR(20) NOT NULL DEFAULT 'public',
+    ADD COLUMN IF NOT EXISTS release_at TIMESTAMPTZ;
+ALTER TABLE albums ADD CONSTRAINT albums_visibility_check CHECK (
+    visibility IN ('public', 'scheduled', 'private')
+    AND (visibility <> 'scheduled' OR release_at IS NOT NULL)
+);
+
+-- Scheduler tìm các release đến hạn
+CREATE INDEX IF NOT EXISTS idx_songs_release ON songs(release_at) WHERE visibility = 'scheduled';
+CREATE INDEX IF NOT EXISTS idx_albums_release ON albums(release_at) WHERE visibility = 'scheduled';
@@ -0,0 +1,7 @@
+-- Rollback 019_territories
+ALTER TABLE albums
+    DROP COLUMN IF EXISTS territory_allow,
+    DROP COLUMN IF EXISTS territory_deny;
+ALTER TABLE songs
+    DROP COLUMN IF EXISTS territory_allow,
+    DROP COLUMN IF EXISTS territory_deny;
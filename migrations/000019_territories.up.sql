@@ -0,0 +1,13 @@
+-- migrations/019_territories.sql
+-- Licensing restrictions per song and per album, ISO 3166-1 alpha-2 codes.
+-- Content is playable in a country if it is in territory_allow (when not
+-- empty) and not in territory_deny; a song must pass its own and its
+-- album's rules.
+
+ALTER TABLE songs
+    ADD COLUMN IF NOT EXISTS territory_allow VARCHAR(2)[] NOT NULL DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS territory_deny VARCHAR(2)[] NOT NULL DEFAULT '{}';
+
+ALTER TABLE albums
+    ADD COLUMN IF NOT EXISTS territory_allow VARCHAR(2)[] NOT NULL DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS territory_deny VARCHAR(2)[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,4 @@
+-- Rollback 020_explicit_content
+ALTER TABLE user_profiles DROP COLUMN IF EXISTS allow_explicit;
+DROP INDEX IF EXISTS idx_songs_explicit;
+ALTER TABLE songs DROP COLUMN IF EXISTS explicit;
@@ -0,0 +1,9 @@
+-- migrations/020_explicit_content.sql
+-- Explicit content flag of songs. NULL means unknown (not explicit) so the
+-- advisory tag of the file can still fill it in after upload.
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS explicit BOOLEAN;
+
+CREATE INDEX IF NOT EXISTS idx_songs_explicit ON songs(explicit) WHERE explicit;
+
+-- Listener preference, NULL = default (allowed unless under age)
+ALTER TABLE user_profiles ADD COLUMN IF NOT EXISTS allow_explicit BOOLEAN;
@@ -0,0 +1,3 @@
+-- Rollback 021_artist_catalog
+DROP INDEX IF EXISTS idx_albums_artist;
+DROP INDEX IF EXISTS idx_followed_artists_artist;
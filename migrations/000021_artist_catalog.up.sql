@@ -0,0 +1,5 @@
+-- migrations/021_artist_catalog.sql
+-- Lookups of the artist catalog API: follower counts and albums of an artist
+
+CREATE INDEX IF NOT EXISTS idx_followed_artists_artist ON followed_artists(artist_id);
+CREATE INDEX IF NOT EXISTS idx_albums_artist ON albums(artist_id);
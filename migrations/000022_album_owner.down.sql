@@ -0,0 +1,3 @@
+-- Rollback 022_album_owner
+DROP INDEX IF EXISTS idx_albums_owned_by;
+ALTER TABLE albums DROP COLUMN IF EXISTS owned_by;
@@ -0,0 +1,18 @@
+-- migrations/022_album_owner.sql
+-- Albums belong to the user who created them (from the tags of an upload).
+-- Only the owner and admins can add songs to an album, schedule it or change
+-- its cover and territories; albums without owner are managed by admins.
+ALTER TABLE albums ADD COLUMN IF NOT EXISTS owned_by UUID REFERENCES users(id) ON DELETE SET NULL;
+
+-- Album cũ: owner là uploader nếu mọi bài của album do cùng một user upload
+UPDATE albums a SET owned_by = o.uploaded_by
+FROM (
+    SELECT album_id, (array_agg(uploaded_by))[1] AS uploaded_by
+    FROM songs
+    WHERE album_id IS NOT NULL
+    GROUP BY album_id
+    HAVING count(DISTINCT uploaded_by) = 1 AND count(uploaded_by) = count(*)
+) o
+WHERE a.id = o.album_id AND a.owned_by IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_albums_owned_by ON albums(owned_by);
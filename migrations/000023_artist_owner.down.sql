@@ -0,0 +1,3 @@
+-- Rollback 023_artist_owner
+DROP INDEX IF EXISTS idx_artists_owned_by;
+ALTER TABLE artists DROP COLUMN IF EXISTS owned_by;
@@ -0,0 +1,7 @@
+-- migrations/023_artist_owner.sql
+-- An artist can be claimed by a user (set by admins). Only the owner and
+-- admins can rename an artist or change its profile and image; crediting an
+-- artist on an upload grants no rights on it.
+ALTER TABLE artists ADD COLUMN IF NOT EXISTS owned_by UUID REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_artists_owned_by ON artists(owned_by);
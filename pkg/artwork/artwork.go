@@ -0,0 +1,184 @@
+// Package artwork stores cover images with resized square variants.
+// Images are content addressed: the name of an image is "<sha256>.<ext>".
+package artwork
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // register GIF decoder
+	"image/jpeg"
+	"image/png"
+	"io"
+	"strconv"
+	"strings"
+
+	"spotify-clone/pkg/storage"
+)
+
+var (
+	ErrUnsupportedImage = errors.New("artwork: unsupported image format")
+	ErrImageTooLarge    = errors.New("artwork: image dimensions too large")
+	ErrInvalidName      = errors.New("artwork: invalid image name or variant")
+)
+
+// Sizes are the square variants generated for every image, in pixels
+var Sizes = []int{64, 300, 640}
+
+// VariantOriginal is the variant name of the image as uploaded
+const VariantOriginal = "original"
+
+// maxPixels bounds decoded images by area, checked from the header before
+// decoding: 4096x4096 is 64MB as RGBA, while a long thin image within a
+// per-side limit could still be huge
+const maxPixels = 4096 * 4096
+
+const jpegQuality = 85
+
+// Store saves images and their variants in blob storage under covers/<digest>/
+type Store struct {
+	blob storage.Blob
+}
+
+// NewStore creates an artwork store backed by blob
+func NewStore(blob storage.Blob) *Store {
+	return &Store{blob: blob}
+}
+
+func key(digest, variant, ext string) string {
+	return fmt.Sprintf("covers/%s/%s.%s", digest, variant, ext)
+}
+
+func contentType(ext string) string {
+	if ext == "png" {
+		return "image/png"
+	}
+	return "image/jpeg"
+}
+
+// Put stores an image with its resized variants and returns its name.
+// JPEG images get JPEG variants; PNG and GIF get PNG variants (keeps alpha).
+func (s *Store) Put(ctx context.Context, data []byte) (string, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", ErrUnsupportedImage
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return "", ErrUnsupportedImage
+	}
+	// Tính bằng int64 để chiều rộng x chiều cao không bị tràn số
+	if int64(cfg.Width)*int64(cfg.Height) > maxPixels {
+		return "", ErrImageTooLarge
+	}
+
+	sum := sha256.Sum256(data)
+	digest := hex.EncodeToString(sum[:])
+	ext := "jpg"
+	if format != "jpeg" {
+		ext = "png"
+	}
+	name := digest + "." + ext
+
+	// Original được ghi sau cùng: có original nghĩa là đã có đủ variant
+	if _, err := s.blob.Stat(ctx, key(digest, VariantOriginal, ext)); err == nil {
+		return name, nil
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		return "", err
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", ErrUnsupportedImage
+	}
+
+	sq := square(img)
+	for _, size := range Sizes {
+		var buf bytes.Buffer
+		if err := encode(&buf, downscale(sq, size), ext); err != nil {
+			return "", err
+		}
+		if err := s.put(ctx, key(digest, strconv.Itoa(size), ext), buf.Bytes(), ext); err != nil {
+			return "", err
+		}
+	}
+
+	original := data
+	if format != "jpeg" && format != "png" {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return "", err
+		}
+		original = buf.Bytes()
+	}
+	if err := s.put(ctx, key(digest, VariantOriginal, ext), original, ext); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+func (s *Store) put(ctx context.Context, k string, data []byte, ext string) error {
+	if err := s.blob.Put(ctx, k, bytes.NewReader(data), int64(len(data)), contentType(ext)); err != nil {
+		return fmt.Errorf("artwork: store %s: %w", k, err)
+	}
+	return nil
+}
+
+func encode(w io.Writer, img image.Image, ext string) error {
+	if ext == "png" {
+		return png.Encode(w, img)
+	}
+	return jpeg.Encode(w, img, &jpeg.Options{Quality: jpegQuality})
+}
+
+// ParseName splits an image name into its digest and extension
+func ParseName(name string) (digest, ext string, err error) {
+	digest, ext, ok := strings.Cut(name, ".")
+	if !ok || len(digest) != sha256.Size*2 || (ext != "jpg" && ext != "png") {
+		return "", "", ErrInvalidName
+	}
+	if _, err := hex.DecodeString(digest); err != nil {
+		return "", "", ErrInvalidName
+	}
+	return digest, ext, nil
+}
+
+// Open opens a variant ("original" or one of Sizes) of the named image
+func (s *Store) Open(ctx context.Context, name, variant string) (storage.Object, storage.ObjectInfo, error) {
+	digest, ext, err := ParseName(name)
+	if err != nil {
+		return nil, storage.ObjectInfo{}, err
+	}
+	if variant != VariantOriginal {
+		size, err := strconv.Atoi(variant)
+		if err != nil || !validSize(size) {
+			return nil, storage.ObjectInfo{}, ErrInvalidName
+		}
+	}
+
+	k := key(digest, variant, ext)
+	info, err := s.blob.Stat(ctx, k)
+	if err != nil {
+		return nil, storage.ObjectInfo{}, err
+	}
+	obj, err := s.blob.Open(ctx, k)
+	if err != nil {
+		return nil, storage.ObjectInfo{}, err
+	}
+	if info.ContentType == "" {
+		info.ContentType = contentType(ext)
+	}
+	return obj, info, nil
+}
+
+func validSize(size int) bool {
+	for _, s := range Sizes {
+		if s == size {
+			return true
+		}
+	}
+	return false
+}
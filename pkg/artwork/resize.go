@@ -0,0 +1,56 @@
+package artwork
+
+import (
+	"image"
+	"image/draw"
+)
+
+// square crops the centered square of src into an RGBA image
+func square(src image.Image) *image.RGBA {
+	b := src.Bounds()
+	side := min(b.Dx(), b.Dy())
+	x0 := b.Min.X + (b.Dx()-side)/2
+	y0 := b.Min.Y + (b.Dy()-side)/2
+
+	dst := image.NewRGBA(image.Rect(0, 0, side, side))
+	draw.Draw(dst, dst.Bounds(), src, image.Pt(x0, y0), draw.Src)
+	return dst
+}
+
+// downscale resizes a square image to size x size by averaging the source
+// pixels covered by each destination pixel (box filter). Images already
+// smaller than size are returned as is, covers are never upscaled.
+func downscale(src *image.RGBA, size int) *image.RGBA {
+	n := src.Bounds().Dx()
+	if n <= size {
+		return src
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		sy0, sy1 := y*n/size, max((y+1)*n/size, y*n/size+1)
+		for x := 0; x < size; x++ {
+			sx0, sx1 := x*n/size, max((x+1)*n/size, x*n/size+1)
+
+			// RGBA là premultiplied nên cộng trực tiếp cũng đúng với alpha
+			var r, g, b, a, count uint32
+			for sy := sy0; sy < sy1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := sx0; sx < sx1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r += uint32(p[0])
+					g += uint32(p[1])
+					b += uint32(p[2])
+					a += uint32(p[3])
+					count++
+				}
+			}
+			d := dst.Pix[y*dst.Stride+x*4:]
+			d[0] = uint8((r + count/2) / count)
+			d[1] = uint8((g + count/2) / count)
+			d[2] = uint8((b + count/2) / count)
+			d[3] = uint8((a + count/2) / count)
+		}
+	}
+	return dst
+}
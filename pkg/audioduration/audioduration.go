@@ -13,6 +13,7 @@ const (
 	TypeMp3  int = 2
 	TypeOgg  int = 3
 	TypeDsd  int = 4
+	TypeWav  int = 5
 )
 
 // Duration Get duration of specific music file type.
@@ -29,6 +30,8 @@ func Duration(file *os.File, filetype int) (float64, error) {
 		d, err = Mp3(file)
 	case TypeOgg:
 		d, err = Ogg(file)
+	case TypeWav:
+		d, err = Wav(file)
 	case TypeDsd:
 		// d, err = DSD(file)
 	default:
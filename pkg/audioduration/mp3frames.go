@@ -0,0 +1,148 @@
+package audioduration
+
+import (
+	"bufio"
+	"errors"
+	"io"
+)
+
+// Mp3Frame describes one MPEG audio frame in a file.
+type Mp3Frame struct {
+	Offset     int64 // byte offset of the frame header
+	Size       int   // frame length in bytes, header included
+	Samples    int   // samples per channel in this frame
+	SampleRate int
+}
+
+// ErrNoMp3Frames is returned when no MPEG audio frame is found.
+var ErrNoMp3Frames = errors.New("no mpeg audio frames found")
+
+// parseMp3Header Decode a 4 bytes frame header, ok is false if it is not a
+// valid header.
+// https://www.codeproject.com/Articles/8295/MPEG-Audio-Frame-Header#MPEGAudioFrameHeader
+func parseMp3Header(b []byte) (f Mp3Frame, ok bool) {
+	// 1111 1111, 111B BCCD, EEEE FFGH, IIJJ KLMM
+	if b[0] != 0xFF || b[1]>>5 != 0b111 {
+		return f, false
+	}
+	mpegVer := (b[1] >> 3) & 0b11
+	layer := (b[1] >> 1) & 0b11
+	bitRateIndex := b[2] >> 4
+	sampleFreqIndex := (b[2] >> 2) & 0b11
+	padding := (b[2] >> 1) & 0b1
+	// 01 version, 00 layer, free/bad bitrate, reserved sample rate are invalid
+	if mpegVer == 0b01 || layer == 0b00 || bitRateIndex == 0 || bitRateIndex == 0b1111 || sampleFreqIndex == 0b11 {
+		return f, false
+	}
+
+	f.SampleRate = getSampleRate(mpegVer, sampleFreqIndex)
+	f.Samples = getSamples(mpegVer, layer)
+	f.Size = frameLength(layer, padding, f.Samples, getBitRate(mpegVer, layer, bitRateIndex), f.SampleRate)
+	if f.Size < 4 {
+		return f, false
+	}
+	return f, true
+}
+
+// isInfoFrame Check whether the frame holds a Xing/Info/VBRI header instead of
+// audio. frame must start at the frame header.
+func isInfoFrame(frame []byte) bool {
+	mpegVer := (frame[1] >> 3) & 0b11
+	layer := (frame[1] >> 1) & 0b11
+	protection := frame[1] & 0x1
+	mode := frame[3] >> 6
+
+	// VBRI is always 32 bytes after the header
+	if len(frame) >= 40 && string(frame[36:40]) == "VBRI" {
+		return true
+	}
+	if layer != layerIII {
+		return false
+	}
+	pos := 4 + getSideInfoLen(mpegVer, mode)
+	if protection == 0 {
+		pos += 2
+	}
+	if int64(len(frame)) < pos+4 {
+		return false
+	}
+	tag := string(frame[pos : pos+4])
+	return tag == "Xing" || tag == "Info"
+}
+
+// Mp3Frames Scan r and return every MPEG audio frame. The ID3v2 tag, the
+// Xing/Info/VBRI frame and trailing tags (ID3v1, APE) are skipped.
+func Mp3Frames(r io.Reader) ([]Mp3Frame, error) {
+	br := bufio.NewReaderSize(r, 64*1024)
+	var offset int64 = 0
+
+	// Jump over the ID3v2 tags before really deal with audio data.
+	head, err := br.Peek(10)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if len(head) == 10 && string(head[0:3]) == "ID3" {
+		skip := 10 + parseID3v2Length(head)
+		n, err := br.Discard(int(skip))
+		if err != nil {
+			return nil, err
+		}
+		offset += int64(n)
+	}
+
+	var frames []Mp3Frame
+	synced := false
+	for {
+		hdr, err := br.Peek(4)
+		if len(hdr) < 4 {
+			if err == io.EOF || err == nil {
+				break
+			}
+			return nil, err
+		}
+
+		f, ok := parseMp3Header(hdr)
+		if ok && !synced {
+			// Sau khi mất sync, kiểm tra frame kế tiếp cũng hợp lệ để tránh
+			// nhận nhầm 0xFFE trong dữ liệu rác
+			next, _ := br.Peek(f.Size + 4)
+			if len(next) == f.Size+4 {
+				_, ok = parseMp3Header(next[f.Size:])
+			} else {
+				ok = len(next) == f.Size
+			}
+		}
+		if !ok {
+			synced = false
+			if _, err := br.Discard(1); err != nil {
+				return nil, err
+			}
+			offset++
+			continue
+		}
+
+		frame, err := br.Peek(f.Size)
+		if len(frame) < f.Size {
+			// Frame cuối bị cắt cụt
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		if len(frames) > 0 || !isInfoFrame(frame) {
+			f.Offset = offset
+			frames = append(frames, f)
+		}
+		synced = true
+
+		if _, err := br.Discard(f.Size); err != nil {
+			return nil, err
+		}
+		offset += int64(f.Size)
+	}
+
+	if len(frames) == 0 {
+		return nil, ErrNoMp3Frames
+	}
+	return frames, nil
+}
@@ -0,0 +1,56 @@
+package audioduration
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// http://soundfile.sapp.org/doc/WaveFormat/
+
+// Wav Calculate wav files duration.
+func Wav(r io.ReadSeeker) (float64, error) {
+	buf := make([]byte, 12)
+	_, err := io.ReadFull(r, buf)
+	if err != nil {
+		return 0, err
+	}
+	if string(buf[0:4]) != "RIFF" || string(buf[8:12]) != "WAVE" {
+		return 0, errors.New("expected 'RIFF....WAVE' at file start")
+	}
+	var byteRate uint32 = 0
+	chunk := make([]byte, 8)
+	for {
+		_, err = io.ReadFull(r, chunk)
+		if err != nil {
+			return 0, err
+		}
+		size := int64(binary.LittleEndian.Uint32(chunk[4:8]))
+		switch string(chunk[0:4]) {
+		case "fmt ":
+			fmtBuf := make([]byte, size)
+			_, err = io.ReadFull(r, fmtBuf)
+			if err != nil {
+				return 0, err
+			}
+			if size < 16 {
+				return 0, errors.New("fmt chunk too short")
+			}
+			byteRate = binary.LittleEndian.Uint32(fmtBuf[8:12])
+		case "data":
+			if byteRate == 0 {
+				return 0, errors.New("no fmt chunk before data")
+			}
+			return float64(size) / float64(byteRate), nil
+		default:
+			_, err = r.Seek(size, io.SeekCurrent)
+			if err != nil {
+				return 0, err
+			}
+		}
+		// Chunk size is padded to even
+		if size%2 == 1 {
+			r.Seek(1, io.SeekCurrent)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+// Package audiotag reads metadata tags from audio files: ID3v2/ID3v1 in MP3,
+// Vorbis comments in OGG (Vorbis, Opus) and FLAC.
+package audiotag
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNoTags is returned when the file has no supported tag
+var ErrNoTags = errors.New("audiotag: no tags found")
+
+// maxTagSize bounds how much tag data is read (embedded pictures included)
+const maxTagSize = 16 << 20
+
+// Tags is the metadata found in an audio file. Empty fields are unknown.
+type Tags struct {
+	Title       string
+	Artists     []string
+	Album       string
+	TrackNumber int
+	Year        int
+	Genres      []string
+	Composers   []string
+	Lyricists   []string
+	Pictures    []Picture
+	Lyrics      []Lyrics
+	Explicit    *bool // content advisory, nil if not tagged
+}
+
+// Read detects the tag format of r and parses it
+func Read(r io.ReadSeeker) (*Tags, error) {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return nil, ErrNoTags
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case string(magic[:3]) == "ID3":
+		t, err := readID3v2(r)
+		if err == nil && t.empty() {
+			// Tag rỗng -> thử ID3v1 ở cuối file
+			return readID3v1(r)
+		}
+		return t, err
+	case string(magic) == "fLaC":
+		return readFLAC(r)
+	case string(magic) == "OggS":
+		return readOgg(r)
+	default:
+		return readID3v1(r)
+	}
+}
+
+func (t *Tags) empty() bool {
+	return t.Title == "" && len(t.Artists) == 0 && t.Album == "" &&
+		t.TrackNumber == 0 && t.Year == 0 && len(t.Genres) == 0 && len(t.Pictures) == 0 &&
+		len(t.Composers) == 0 && len(t.Lyricists) == 0 && len(t.Lyrics) == 0 && t.Explicit == nil
+}
+
+// advisoryFields are the names of the content advisory field in ID3v2 TXXX
+// frames and Vorbis comments, written by iTunes (from the MP4 rtng atom)
+// and other taggers
+var advisoryFields = []string{"ITUNESADVISORY", "RTNG", "EXPLICIT"}
+
+// parseAdvisory parses a content advisory value: iTunes rtng uses 1 or 4
+// for explicit, 2 for clean and 0 for none
+func parseAdvisory(s string) (explicit, ok bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "1", "4", "explicit", "true", "yes":
+		return true, true
+	case "0", "2", "clean", "false", "no":
+		return false, true
+	}
+	return false, false
+}
+
+// setAdvisory sets Explicit from an advisory value, explicit wins when
+// there are several
+func (t *Tags) setAdvisory(s string) {
+	if explicit, ok := parseAdvisory(s); ok && (t.Explicit == nil || explicit) {
+		t.Explicit = &explicit
+	}
+}
+
+// addArtist appends non-empty, non-duplicate artist names
+func (t *Tags) addArtist(names ...string) {
+	t.Artists = appendUnique(t.Artists, names...)
+}
+
+func (t *Tags) addGenre(names ...string) {
+	t.Genres = appendUnique(t.Genres, names...)
+}
+
+func appendUnique(list []string, values ...string) []string {
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		dup := false
+		for _, existing := range list {
+			if strings.EqualFold(existing, v) {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// splitValues splits multi-value text fields ("A; B")
+func splitValues(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool { return r == ';' || r == 0 })
+}
+
+// parseTrack parses "3" or "3/12"
+func parseTrack(s string) int {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '/'); i >= 0 {
+		s = s[:i]
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// parseYear parses the year of "2001", "2001-05-12" or "2001-05-12T10:00"
+func parseYear(s string) int {
+	s = strings.TrimSpace(s)
+	if len(s) < 4 {
+		return 0
+	}
+	n, err := strconv.Atoi(s[:4])
+	if err != nil || n <= 0 {
+		return 0
+	}
+	return n
+}
+
+// Picture types (ID3v2 APIC / FLAC PICTURE)
+const (
+	PictureOther      = 0
+	PictureFrontCover = 3
+)
+
+// Picture is an embedded image, usually the cover art
+type Picture struct {
+	MIMEType    string
+	Type        int
+	Description string
+	Data        []byte
+}
+
+// Cover returns the front cover, or the first picture if none is marked as
+// front cover, or nil
+func (t *Tags) Cover() *Picture {
+	for i := range t.Pictures {
+		if t.Pictures[i].Type == PictureFrontCover {
+			return &t.Pictures[i]
+		}
+	}
+	if len(t.Pictures) > 0 {
+		return &t.Pictures[0]
+	}
+	return nil
+}
+
+// Lyrics are embedded lyrics: unsynchronised text (ID3v2 USLT, LYRICS Vorbis
+// comment, possibly in LRC format) or timed lines (ID3v2 SYLT)
+type Lyrics struct {
+	Language    string // ISO 639-2 code of ID3v2 frames, "" if unknown
+	Description string
+	Text        string
+	Lines       []LyricsLine // SYLT only
+}
+
+// LyricsLine is a line of synchronised lyrics
+type LyricsLine struct {
+	Time time.Duration
+	Text string
+}
+
+// parseFLACPicture parses a FLAC PICTURE block, also used base64 encoded in
+// the METADATA_BLOCK_PICTURE Vorbis comment
+// https://xiph.org/flac/format.html#metadata_block_picture
+func parseFLACPicture(b []byte) (Picture, bool) {
+	var p Picture
+	read32 := func() (uint32, bool) {
+		if len(b) < 4 {
+			return 0, false
+		}
+		v := binary.BigEndian.Uint32(b)
+		b = b[4:]
+		return v, true
+	}
+	readBytes := func() ([]byte, bool) {
+		n, ok := read32()
+		if !ok || uint64(n) > uint64(len(b)) {
+			return nil, false
+		}
+		v := b[:n]
+		b = b[n:]
+		return v, true
+	}
+
+	typ, ok := read32()
+	if !ok {
+		return p, false
+	}
+	mimeType, ok := readBytes()
+	if !ok {
+		return p, false
+	}
+	desc, ok := readBytes()
+	if !ok || len(b) < 16 {
+		return p, false
+	}
+	b = b[16:] // width, height, color depth, colors used
+	data, ok := readBytes()
+	if !ok {
+		return p, false
+	}
+
+	p.Type = int(typ)
+	p.MIMEType = string(mimeType)
+	p.Description = string(desc)
+	p.Data = data
+	return p, true
+}
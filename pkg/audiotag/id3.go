@@ -0,0 +1,545 @@
+package audiotag
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// https://id3.org/id3v2.3.0
+// https://id3.org/id3v2.4.0-structure
+// https://id3.org/id3v2.4.0-frames
+
+// id3v2Frames maps frame IDs (v2.2 and v2.3/v2.4) to the field they fill
+var id3v2Frames = map[string]string{
+	"TT2": "title", "TIT2": "title",
+	"TP1": "artist", "TPE1": "artist",
+	"TAL": "album", "TALB": "album",
+	"TRK": "track", "TRCK": "track",
+	"TYE": "year", "TYER": "year", "TDRC": "year",
+	"TCO": "genre", "TCON": "genre",
+	"TCM": "composer", "TCOM": "composer",
+	"TXT": "lyricist", "TEXT": "lyricist",
+}
+
+// id3Frame is one raw frame of an ID3v2 tag
+type id3Frame struct {
+	ID   string
+	Data []byte
+}
+
+// syncsafeInt decodes a 4 bytes ID3v2 synchsafe integer
+func syncsafeInt(b []byte) int {
+	return int(b[0]&0x7F)<<21 | int(b[1]&0x7F)<<14 | int(b[2]&0x7F)<<7 | int(b[3]&0x7F)
+}
+
+// removeUnsync reverses ID3v2 unsynchronisation (0xFF 0x00 -> 0xFF)
+func removeUnsync(b []byte) []byte {
+	out := make([]byte, 0, len(b))
+	for i := 0; i < len(b); i++ {
+		out = append(out, b[i])
+		if b[i] == 0xFF && i+1 < len(b) && b[i+1] == 0x00 {
+			i++
+		}
+	}
+	return out
+}
+
+// readID3v2Frames reads the ID3v2 tag at the start of r and returns its frames
+func readID3v2Frames(r io.Reader) (major byte, frames []id3Frame, err error) {
+	hdr := make([]byte, 10)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return 0, nil, err
+	}
+	if string(hdr[0:3]) != "ID3" {
+		return 0, nil, ErrNoTags
+	}
+	major = hdr[3]
+	flags := hdr[5]
+	size := syncsafeInt(hdr[6:10])
+	if major < 2 || major > 4 {
+		return 0, nil, errors.New("audiotag: unsupported ID3v2 version")
+	}
+	if size > maxTagSize {
+		return 0, nil, errors.New("audiotag: ID3v2 tag too large")
+	}
+
+	body := make([]byte, size)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	if flags&0x80 != 0 && major < 4 {
+		body = removeUnsync(body)
+	}
+
+	// Bỏ qua extended header
+	if flags&0x40 != 0 {
+		switch major {
+		case 2:
+			// v2.2 dùng bit này cho compression, không hỗ trợ
+			return 0, nil, errors.New("audiotag: compressed ID3v2.2 tag")
+		case 3:
+			if len(body) < 4 {
+				return 0, nil, ErrNoTags
+			}
+			body = body[min(len(body), 4+int(binary.BigEndian.Uint32(body))):]
+		case 4:
+			if len(body) < 4 {
+				return 0, nil, ErrNoTags
+			}
+			body = body[min(len(body), syncsafeInt(body)):]
+		}
+	}
+
+	idLen, hdrLen := 4, 10
+	if major == 2 {
+		idLen, hdrLen = 3, 6
+	}
+	for len(body) >= hdrLen && body[0] != 0 { // 0 = padding
+		id := string(body[:idLen])
+		var frameSize int
+		var frameFlags uint16
+		switch major {
+		case 2:
+			frameSize = int(body[3])<<16 | int(body[4])<<8 | int(body[5])
+		case 3:
+			frameSize = int(binary.BigEndian.Uint32(body[4:8]))
+			frameFlags = binary.BigEndian.Uint16(body[8:10])
+		case 4:
+			frameSize = syncsafeInt(body[4:8])
+			frameFlags = binary.BigEndian.Uint16(body[8:10])
+		}
+		if frameSize < 0 || hdrLen+frameSize > len(body) {
+			break
+		}
+		data := body[hdrLen : hdrLen+frameSize]
+		body = body[hdrLen+frameSize:]
+
+		switch major {
+		case 3:
+			// compression/encryption không hỗ trợ; grouping thêm 1 byte
+			if frameFlags&0x00C0 != 0 {
+				continue
+			}
+			if frameFlags&0x0020 != 0 && len(data) > 0 {
+				data = data[1:]
+			}
+		case 4:
+			if frameFlags&0x000C != 0 {
+				continue
+			}
+			if frameFlags&0x0040 != 0 && len(data) > 0 { // grouping
+				data = data[1:]
+			}
+			if frameFlags&0x0002 != 0 || flags&0x80 != 0 { // unsync
+				data = removeUnsync(data)
+			}
+			if frameFlags&0x0001 != 0 && len(data) >= 4 { // data length indicator
+				data = data[4:]
+			}
+		}
+		frames = append(frames, id3Frame{ID: id, Data: data})
+	}
+	return major, frames, nil
+}
+
+// readID3v2 parses text frames of the ID3v2 tag at the start of r
+func readID3v2(r io.Reader) (*Tags, error) {
+	major, frames, err := readID3v2Frames(r)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Tags{}
+	for _, f := range frames {
+		if f.ID == "APIC" || f.ID == "PIC" {
+			if p, ok := parseID3Picture(f.ID, f.Data); ok {
+				t.Pictures = append(t.Pictures, p)
+			}
+			continue
+		}
+		switch f.ID {
+		case "USLT", "ULT":
+			if l, ok := parseID3Lyrics(f.Data); ok {
+				t.Lyrics = append(t.Lyrics, l)
+			}
+			continue
+		case "SYLT", "SLT":
+			if l, ok := parseID3SyncedLyrics(f.Data); ok {
+				t.Lyrics = append(t.Lyrics, l)
+			}
+			continue
+		case "TXXX", "TXX":
+			if desc, value, ok := parseID3UserText(f.Data); ok && slices.Contains(advisoryFields, strings.ToUpper(desc)) {
+				t.setAdvisory(value)
+			}
+			continue
+		}
+
+		field, ok := id3v2Frames[f.ID]
+		if !ok || len(f.Data) == 0 {
+			continue
+		}
+		values := decodeID3Text(f.Data[0], f.Data[1:])
+		if len(values) == 0 {
+			continue
+		}
+		switch field {
+		case "title":
+			t.Title = strings.TrimSpace(values[0])
+		case "artist":
+			for _, v := range values {
+				t.addArtist(splitValues(v)...)
+			}
+		case "album":
+			t.Album = strings.TrimSpace(values[0])
+		case "track":
+			t.TrackNumber = parseTrack(values[0])
+		case "year":
+			// TDRC (v2.4) được ưu tiên hơn TYER nếu có cả hai
+			if y := parseYear(values[0]); y != 0 && (t.Year == 0 || major == 4) {
+				t.Year = y
+			}
+		case "genre":
+			for _, v := range values {
+				t.addGenre(parseID3Genre(v)...)
+			}
+		case "composer":
+			for _, v := range values {
+				t.Composers = appendUnique(t.Composers, splitValues(v)...)
+			}
+		case "lyricist":
+			for _, v := range values {
+				t.Lyricists = appendUnique(t.Lyricists, splitValues(v)...)
+			}
+		}
+	}
+	return t, nil
+}
+
+// parseID3Picture parses an APIC (v2.3/v2.4) or PIC (v2.2) frame
+func parseID3Picture(id string, b []byte) (Picture, bool) {
+	var p Picture
+	if len(b) < 2 {
+		return p, false
+	}
+	encoding := b[0]
+	b = b[1:]
+
+	if id == "PIC" {
+		// v2.2: 3 ký tự image format thay cho MIME type
+		if len(b) < 3 {
+			return p, false
+		}
+		switch strings.ToUpper(string(b[:3])) {
+		case "JPG":
+			p.MIMEType = "image/jpeg"
+		case "PNG":
+			p.MIMEType = "image/png"
+		}
+		b = b[3:]
+	} else {
+		end := bytes.IndexByte(b, 0)
+		if end < 0 {
+			return p, false
+		}
+		p.MIMEType = string(b[:end])
+		b = b[end+1:]
+	}
+
+	if len(b) < 1 {
+		return p, false
+	}
+	p.Type = int(b[0])
+	b = b[1:]
+
+	desc, rest, ok := splitID3String(encoding, b)
+	if !ok {
+		return p, false
+	}
+	p.Description = decodeID3String(encoding, desc)
+	p.Data = rest
+	return p, len(p.Data) > 0
+}
+
+// splitID3String splits b after a NUL terminated string (2 NUL bytes with
+// UTF-16 encodings)
+func splitID3String(encoding byte, b []byte) (s, rest []byte, ok bool) {
+	if encoding == 1 || encoding == 2 {
+		for i := 0; i+1 < len(b); i += 2 {
+			if b[i] == 0 && b[i+1] == 0 {
+				return b[:i], b[i+2:], true
+			}
+		}
+		return nil, nil, false
+	}
+	end := bytes.IndexByte(b, 0)
+	if end < 0 {
+		return nil, nil, false
+	}
+	return b[:end], b[end+1:], true
+}
+
+// decodeID3String decodes a single string, keeping its line breaks
+func decodeID3String(encoding byte, b []byte) string {
+	switch encoding {
+	case 0:
+		return latin1(b)
+	case 1, 2:
+		return strings.TrimRight(decodeUTF16(b, encoding == 2), "\x00")
+	case 3:
+		return string(b)
+	}
+	return ""
+}
+
+// id3LyricsLanguage returns the language code of a lyrics frame, "" if unknown
+func id3LyricsLanguage(b []byte) string {
+	lang := strings.ToLower(strings.TrimRight(latin1(b), "\x00 "))
+	if lang == "xxx" || lang == "und" {
+		return ""
+	}
+	return lang
+}
+
+// parseID3UserText parses a TXXX (v2.3/v2.4) or TXX (v2.2) frame:
+// encoding, NUL terminated description, value
+func parseID3UserText(b []byte) (desc, value string, ok bool) {
+	if len(b) < 2 {
+		return "", "", false
+	}
+	encoding := b[0]
+	d, v, ok := splitID3String(encoding, b[1:])
+	if !ok {
+		return "", "", false
+	}
+	return strings.TrimSpace(decodeID3String(encoding, d)), strings.Trim(decodeID3String(encoding, v), "\x00 "), true
+}
+
+// parseID3Lyrics parses an USLT (v2.3/v2.4) or ULT (v2.2) frame:
+// encoding, language, NUL terminated description, text
+func parseID3Lyrics(b []byte) (Lyrics, bool) {
+	var l Lyrics
+	if len(b) < 4 {
+		return l, false
+	}
+	encoding := b[0]
+	l.Language = id3LyricsLanguage(b[1:4])
+	desc, text, ok := splitID3String(encoding, b[4:])
+	if !ok {
+		return l, false
+	}
+	l.Description = decodeID3String(encoding, desc)
+	l.Text = decodeID3String(encoding, text)
+	return l, strings.TrimSpace(l.Text) != ""
+}
+
+// parseID3SyncedLyrics parses a SYLT (v2.3/v2.4) or SLT (v2.2) frame:
+// encoding, language, timestamp format, content type, description, then
+// (NUL terminated text, 32-bit timestamp) pairs. Only millisecond
+// timestamps are supported, MPEG frame timestamps need the audio.
+func parseID3SyncedLyrics(b []byte) (Lyrics, bool) {
+	var l Lyrics
+	if len(b) < 6 {
+		return l, false
+	}
+	encoding := b[0]
+	l.Language = id3LyricsLanguage(b[1:4])
+	if b[4] != 2 { // 1 = MPEG frames, 2 = milliseconds
+		return l, false
+	}
+	desc, b, ok := splitID3String(encoding, b[6:])
+	if !ok {
+		return l, false
+	}
+	l.Description = decodeID3String(encoding, desc)
+
+	for len(b) > 0 {
+		text, rest, ok := splitID3String(encoding, b)
+		if !ok || len(rest) < 4 {
+			break
+		}
+		ms := binary.BigEndian.Uint32(rest)
+		b = rest[4:]
+		// Mỗi dòng thường bắt đầu bằng "\n"
+		line := strings.TrimSpace(decodeID3String(encoding, text))
+		l.Lines = append(l.Lines, LyricsLine{Time: time.Duration(ms) * time.Millisecond, Text: line})
+	}
+	return l, len(l.Lines) > 0
+}
+
+// decodeID3Text decodes a text frame body. Values are separated by NUL
+// (multiple values are allowed in ID3v2.4).
+func decodeID3Text(encoding byte, b []byte) []string {
+	var s string
+	switch encoding {
+	case 0: // ISO-8859-1
+		s = latin1(b)
+	case 1, 2: // UTF-16 with BOM / UTF-16BE
+		s = decodeUTF16(b, encoding == 2)
+	case 3: // UTF-8
+		s = string(b)
+	default:
+		return nil
+	}
+
+	var values []string
+	for _, v := range strings.Split(s, "\x00") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+func latin1(b []byte) string {
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
+
+// decodeUTF16 decodes UTF-16 text; each NUL separated string may start with
+// its own BOM
+func decodeUTF16(b []byte, bigEndian bool) string {
+	units := make([]uint16, 0, len(b)/2)
+	be := bigEndian
+	atStart := true
+	for i := 0; i+1 < len(b); i += 2 {
+		if atStart {
+			switch {
+			case b[i] == 0xFE && b[i+1] == 0xFF:
+				be = true
+				continue
+			case b[i] == 0xFF && b[i+1] == 0xFE:
+				be = false
+				continue
+			}
+		}
+		var u uint16
+		if be {
+			u = uint16(b[i])<<8 | uint16(b[i+1])
+		} else {
+			u = uint16(b[i+1])<<8 | uint16(b[i])
+		}
+		units = append(units, u)
+		atStart = u == 0
+	}
+	return string(utf16.Decode(units))
+}
+
+// parseID3Genre parses TCON values: "Rock", "17", "(17)", "(17)Rock", "(RX)"
+func parseID3Genre(s string) []string {
+	var genres []string
+	s = strings.TrimSpace(s)
+	for strings.HasPrefix(s, "(") && !strings.HasPrefix(s, "((") {
+		end := strings.IndexByte(s, ')')
+		if end < 0 {
+			break
+		}
+		ref := s[1:end]
+		s = s[end+1:]
+		switch ref {
+		case "RX":
+			genres = append(genres, "Remix")
+		case "CR":
+			genres = append(genres, "Cover")
+		default:
+			if name := id3v1Genre(ref); name != "" {
+				genres = append(genres, name)
+			}
+		}
+	}
+	s = strings.TrimPrefix(s, "(") // "((" là ký tự '(' thật
+	if s != "" {
+		if name := id3v1Genre(s); name != "" {
+			genres = append(genres, name)
+		} else {
+			genres = append(genres, s)
+		}
+	}
+	return genres
+}
+
+// id3v1Genre returns the genre name for a numeric ID3v1 genre reference
+func id3v1Genre(ref string) string {
+	n, err := strconv.Atoi(ref)
+	if err != nil || n < 0 || n >= len(id3v1Genres) {
+		return ""
+	}
+	return id3v1Genres[n]
+}
+
+// readID3v1 parses the 128 bytes ID3v1(.1) tag at the end of r
+// https://id3.org/ID3v1
+func readID3v1(r io.ReadSeeker) (*Tags, error) {
+	if _, err := r.Seek(-128, io.SeekEnd); err != nil {
+		return nil, ErrNoTags
+	}
+	buf := make([]byte, 128)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, ErrNoTags
+	}
+	if string(buf[0:3]) != "TAG" {
+		return nil, ErrNoTags
+	}
+
+	field := func(b []byte) string {
+		if i := strings.IndexByte(string(b), 0); i >= 0 {
+			b = b[:i]
+		}
+		return strings.TrimSpace(latin1(b))
+	}
+
+	t := &Tags{
+		Title: field(buf[3:33]),
+		Album: field(buf[63:93]),
+		Year:  parseYear(field(buf[93:97])),
+	}
+	t.addArtist(field(buf[33:63]))
+	// ID3v1.1: byte 125 = 0 và byte 126 là track number
+	if buf[125] == 0 && buf[126] != 0 {
+		t.TrackNumber = int(buf[126])
+	}
+	if g := int(buf[127]); g < len(id3v1Genres) {
+		t.addGenre(id3v1Genres[g])
+	}
+	if t.empty() {
+		return nil, ErrNoTags
+	}
+	return t, nil
+}
+
+// id3v1Genres is the ID3v1 genre list with the Winamp extensions
+var id3v1Genres = []string{
+	"Blues", "Classic Rock", "Country", "Dance", "Disco", "Funk", "Grunge",
+	"Hip-Hop", "Jazz", "Metal", "New Age", "Oldies", "Other", "Pop", "R&B",
+	"Rap", "Reggae", "Rock", "Techno", "Industrial", "Alternative", "Ska",
+	"Death Metal", "Pranks", "Soundtrack", "Euro-Techno", "Ambient",
+	"Trip-Hop", "Vocal", "Jazz+Funk", "Fusion", "Trance", "Classical",
+	"Instrumental", "Acid", "House", "Game", "Sound Clip", "Gospel", "Noise",
+	"AlternRock", "Bass", "Soul", "Punk", "Space", "Meditative",
+	"Instrumental Pop", "Instrumental Rock", "Ethnic", "Gothic", "Darkwave",
+	"Techno-Industrial", "Electronic", "Pop-Folk", "Eurodance", "Dream",
+	"Southern Rock", "Comedy", "Cult", "Gangsta", "Top 40", "Christian Rap",
+	"Pop/Funk", "Jungle", "Native American", "Cabaret", "New Wave",
+	"Psychadelic", "Rave", "Showtunes", "Trailer", "Lo-Fi", "Tribal",
+	"Acid Punk", "Acid Jazz", "Polka", "Retro", "Musical", "Rock & Roll",
+	"Hard Rock",
+	// Winamp extensions
+	"Folk", "Folk-Rock", "National Folk", "Swing", "Fast Fusion", "Bebob",
+	"Latin", "Revival", "Celtic", "Bluegrass", "Avantgarde", "Gothic Rock",
+	"Progressive Rock", "Psychedelic Rock", "Symphonic Rock", "Slow Rock",
+	"Big Band", "Chorus", "Easy Listening", "Acoustic", "Humour", "Speech",
+	"Chanson", "Opera", "Chamber Music", "Sonata", "Symphony", "Booty Bass",
+	"Primus", "Porn Groove", "Satire", "Slow Jam", "Club", "Tango", "Samba",
+	"Folklore", "Ballad", "Power Ballad", "Rhythmic Soul", "Freestyle", "Duet",
+	"Punk Rock", "Drum Solo", "A capella", "Euro-House", "Dance Hall",
+}
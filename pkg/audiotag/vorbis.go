@@ -0,0 +1,265 @@
+package audiotag
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+)
+
+// https://www.xiph.org/vorbis/doc/v-comment.html
+
+// vorbisComments is a parsed comment header: upper-cased field name -> values
+type vorbisComments map[string][]string
+
+// parseVorbisComments parses a comment header (without packet type/magic)
+func parseVorbisComments(b []byte) (vorbisComments, error) {
+	rd := bytes.NewReader(b)
+	var n uint32
+	// Vendor string
+	if err := binary.Read(rd, binary.LittleEndian, &n); err != nil {
+		return nil, err
+	}
+	if _, err := rd.Seek(int64(n), io.SeekCurrent); err != nil {
+		return nil, err
+	}
+
+	var count uint32
+	if err := binary.Read(rd, binary.LittleEndian, &count); err != nil {
+		return nil, err
+	}
+	comments := vorbisComments{}
+	for i := uint32(0); i < count; i++ {
+		if err := binary.Read(rd, binary.LittleEndian, &n); err != nil {
+			return nil, err
+		}
+		if int64(n) > int64(rd.Len()) {
+			return nil, errors.New("audiotag: truncated vorbis comment")
+		}
+		field := make([]byte, n)
+		if _, err := io.ReadFull(rd, field); err != nil {
+			return nil, err
+		}
+		name, value, ok := strings.Cut(string(field), "=")
+		if !ok {
+			continue
+		}
+		name = strings.ToUpper(name)
+		comments[name] = append(comments[name], value)
+	}
+	return comments, nil
+}
+
+func (c vorbisComments) first(name string) string {
+	if v := c[name]; len(v) > 0 {
+		return strings.TrimSpace(v[0])
+	}
+	return ""
+}
+
+// tags maps Vorbis comment fields to Tags
+func (c vorbisComments) tags() *Tags {
+	t := &Tags{
+		Title:       c.first("TITLE"),
+		Album:       c.first("ALBUM"),
+		TrackNumber: parseTrack(c.first("TRACKNUMBER")),
+		Year:        parseYear(c.first("DATE")),
+	}
+	if t.Year == 0 {
+		t.Year = parseYear(c.first("YEAR"))
+	}
+	for _, v := range c["ARTIST"] {
+		t.addArtist(splitValues(v)...)
+	}
+	for _, v := range c["GENRE"] {
+		t.addGenre(splitValues(v)...)
+	}
+	for _, v := range c["COMPOSER"] {
+		t.Composers = appendUnique(t.Composers, splitValues(v)...)
+	}
+	for _, v := range c["LYRICIST"] {
+		t.Lyricists = appendUnique(t.Lyricists, splitValues(v)...)
+	}
+	for _, name := range []string{"LYRICS", "UNSYNCEDLYRICS"} {
+		for _, v := range c[name] {
+			if strings.TrimSpace(v) != "" {
+				t.Lyrics = append(t.Lyrics, Lyrics{Text: v})
+			}
+		}
+	}
+	for _, name := range advisoryFields {
+		for _, v := range c[name] {
+			t.setAdvisory(v)
+		}
+	}
+	for _, v := range c["METADATA_BLOCK_PICTURE"] {
+		data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(v))
+		if err != nil {
+			continue
+		}
+		if p, ok := parseFLACPicture(data); ok {
+			t.Pictures = append(t.Pictures, p)
+		}
+	}
+	return t
+}
+
+// flacBlocks calls fn for each metadata block of the FLAC stream in r
+// https://xiph.org/flac/format.html#metadata_block
+func flacBlocks(r io.Reader, fn func(blockType byte, data []byte) error) error {
+	br := bufio.NewReader(r)
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(br, magic); err != nil {
+		return err
+	}
+	if string(magic) != "fLaC" {
+		return ErrNoTags
+	}
+
+	hdr := make([]byte, 4)
+	for {
+		if _, err := io.ReadFull(br, hdr); err != nil {
+			return err
+		}
+		last := hdr[0]&0x80 != 0
+		blockType := hdr[0] & 0x7F
+		size := int64(binary.BigEndian.Uint32(hdr) & 0x00FFFFFF)
+
+		if blockType == 4 || blockType == 6 { // VORBIS_COMMENT, PICTURE
+			if size > maxTagSize {
+				return errors.New("audiotag: metadata block too large")
+			}
+			data := make([]byte, size)
+			if _, err := io.ReadFull(br, data); err != nil {
+				return err
+			}
+			if err := fn(blockType, data); err != nil {
+				return err
+			}
+		} else if _, err := io.CopyN(io.Discard, br, size); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+// readFLAC parses the VORBIS_COMMENT and PICTURE metadata blocks of a FLAC file
+func readFLAC(r io.Reader) (*Tags, error) {
+	var comments vorbisComments
+	var pictures []Picture
+	err := flacBlocks(r, func(blockType byte, data []byte) error {
+		switch blockType {
+		case 4:
+			if comments != nil {
+				return nil
+			}
+			var err error
+			comments, err = parseVorbisComments(data)
+			return err
+		case 6:
+			if p, ok := parseFLACPicture(data); ok {
+				pictures = append(pictures, p)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if comments == nil && len(pictures) == 0 {
+		return nil, ErrNoTags
+	}
+
+	t := &Tags{}
+	if comments != nil {
+		t = comments.tags()
+	}
+	t.Pictures = append(t.Pictures, pictures...)
+	return t, nil
+}
+
+// oggPackets reassembles the first n packets of the logical stream in r
+// https://www.xiph.org/ogg/doc/framing.html
+func oggPackets(r io.Reader, n int) ([][]byte, error) {
+	br := bufio.NewReader(r)
+	var packets [][]byte
+	var cur []byte
+	serial := uint32(0)
+	first := true
+
+	hdr := make([]byte, 27)
+	for len(packets) < n {
+		if _, err := io.ReadFull(br, hdr); err != nil {
+			return nil, err
+		}
+		if string(hdr[0:4]) != "OggS" {
+			return nil, errors.New("audiotag: bad ogg page")
+		}
+		pageSerial := binary.LittleEndian.Uint32(hdr[14:18])
+		segments := make([]byte, hdr[26])
+		if _, err := io.ReadFull(br, segments); err != nil {
+			return nil, err
+		}
+
+		total := 0
+		for _, s := range segments {
+			total += int(s)
+		}
+		body := make([]byte, total)
+		if _, err := io.ReadFull(br, body); err != nil {
+			return nil, err
+		}
+		if first {
+			serial, first = pageSerial, false
+		}
+		if pageSerial != serial {
+			continue // page của stream khác (multiplexed)
+		}
+
+		// Segment < 255 kết thúc một packet
+		pos := 0
+		for _, s := range segments {
+			cur = append(cur, body[pos:pos+int(s)]...)
+			pos += int(s)
+			if len(cur) > maxTagSize {
+				return nil, errors.New("audiotag: ogg packet too large")
+			}
+			if s < 255 {
+				packets = append(packets, cur)
+				cur = nil
+				if len(packets) == n {
+					break
+				}
+			}
+		}
+	}
+	return packets, nil
+}
+
+// readOgg parses the comment header of an Ogg Vorbis or Opus file.
+// It is the second packet of the stream.
+func readOgg(r io.Reader) (*Tags, error) {
+	packets, err := oggPackets(r, 2)
+	if err != nil {
+		return nil, err
+	}
+	p := packets[1]
+	switch {
+	case bytes.HasPrefix(p, []byte("\x03vorbis")):
+		p = p[7:]
+	case bytes.HasPrefix(p, []byte("OpusTags")):
+		p = p[8:]
+	default:
+		return nil, ErrNoTags
+	}
+	comments, err := parseVorbisComments(p)
+	if err != nil {
+		return nil, err
+	}
+	return comments.tags(), nil
+}
@@ -0,0 +1,139 @@
+// Package geoip resolves the country of an IP address from a local CSV
+// database of IP ranges, such as the free DB-IP "IP to Country Lite" or
+// IP2Location LITE DB1 files.
+package geoip
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidRange is returned for a line whose range cannot be parsed
+var ErrInvalidRange = errors.New("geoip: invalid IP range")
+
+// ipRange maps the addresses from start to end (inclusive) to a country
+type ipRange struct {
+	start, end netip.Addr
+	country    string // ISO 3166-1 alpha-2, upper case
+}
+
+// DB is an in-memory, read-only IP to country database
+type DB struct {
+	ranges []ipRange // sorted by start
+}
+
+// Open loads a database file
+func Open(path string) (*DB, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Load(f)
+}
+
+// Load reads CSV lines "start,end,country[,...]". Addresses are written
+// as IPs (DB-IP) or as decimal numbers (IP2Location); IPv4 and IPv6 may be
+// mixed. A header line and ranges without a country ("-", "ZZ") are skipped.
+func Load(r io.Reader) (*DB, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+
+	db := &DB{}
+	for line := 1; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("geoip: %w", err)
+		}
+		if len(record) < 3 {
+			return nil, fmt.Errorf("%w: line %d has %d fields", ErrInvalidRange, line, len(record))
+		}
+
+		start, errStart := parseAddr(record[0])
+		end, errEnd := parseAddr(record[1])
+		if errStart != nil || errEnd != nil {
+			if line == 1 {
+				continue // header
+			}
+			return nil, fmt.Errorf("%w: line %d", ErrInvalidRange, line)
+		}
+		if start.Is4() != end.Is4() || end.Less(start) {
+			return nil, fmt.Errorf("%w: line %d", ErrInvalidRange, line)
+		}
+
+		country := strings.ToUpper(strings.TrimSpace(record[2]))
+		if len(country) != 2 || country == "ZZ" {
+			continue
+		}
+		db.ranges = append(db.ranges, ipRange{start: start, end: end, country: country})
+	}
+
+	sort.Slice(db.ranges, func(i, j int) bool { return db.ranges[i].start.Less(db.ranges[j].start) })
+	return db, nil
+}
+
+// parseAddr parses an IP address or its decimal number. IPv4-mapped IPv6
+// addresses are converted to IPv4.
+func parseAddr(s string) (netip.Addr, error) {
+	s = strings.TrimSpace(s)
+	if addr, err := netip.ParseAddr(s); err == nil {
+		return addr.Unmap(), nil
+	}
+
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok || n.Sign() < 0 || n.BitLen() > 128 {
+		return netip.Addr{}, ErrInvalidRange
+	}
+	if n.BitLen() <= 32 {
+		var b [4]byte
+		n.FillBytes(b[:])
+		return netip.AddrFrom4(b), nil
+	}
+	var b [16]byte
+	n.FillBytes(b[:])
+	return netip.AddrFrom16(b).Unmap(), nil
+}
+
+// Len returns the number of ranges in the database
+func (db *DB) Len() int {
+	return len(db.ranges)
+}
+
+// Country returns the country of ip, "" if it is not in the database
+func (db *DB) Country(ip netip.Addr) string {
+	if db == nil || !ip.IsValid() {
+		return ""
+	}
+	ip = ip.Unmap()
+
+	// Range cuối cùng bắt đầu trước hoặc tại ip
+	i := sort.Search(len(db.ranges), func(i int) bool { return ip.Less(db.ranges[i].start) }) - 1
+	if i < 0 {
+		return ""
+	}
+	r := db.ranges[i]
+	if r.start.Is4() != ip.Is4() || r.end.Less(ip) {
+		return ""
+	}
+	return r.country
+}
+
+// LookupString is Country for an address in text form, e.g. a client IP
+func (db *DB) LookupString(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ""
+	}
+	return db.Country(addr)
+}
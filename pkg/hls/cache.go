@@ -0,0 +1,77 @@
+package hls
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var ErrInvalidName = errors.New("hls: invalid cache name")
+
+// Cache stores generated playlists and segments on disk
+type Cache struct {
+	root string
+}
+
+// NewCache creates a disk cache rooted at dir
+func NewCache(dir string) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Cache{root: dir}, nil
+}
+
+func (c *Cache) path(name string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(name))
+	if name == "" || filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", ErrInvalidName
+	}
+	return filepath.Join(c.root, clean), nil
+}
+
+// Open opens a cached file, the error satisfies errors.Is(err, os.ErrNotExist) on a miss
+func (c *Cache) Open(name string) (*os.File, error) {
+	p, err := c.path(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(p)
+}
+
+// Store writes a cache entry with fill. The entry only becomes visible once
+// fill succeeds, so concurrent readers never see a partial file.
+func (c *Cache) Store(name string, fill func(w io.Writer) error) error {
+	p, err := c.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op sau khi rename thành công
+
+	if err := fill(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), p)
+}
+
+// Remove deletes a cache entry or a directory of entries, a missing entry is not an error
+func (c *Cache) Remove(name string) error {
+	p, err := c.path(name)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(p)
+}
@@ -0,0 +1,80 @@
+// Package hls packages MP3 files as HTTP Live Streaming (packed audio).
+package hls
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"spotify-clone/pkg/audioduration"
+)
+
+// Segment is a run of whole MP3 frames served as one HLS segment
+type Segment struct {
+	Index       int     `json:"index"`
+	Offset      int64   `json:"offset"`       // byte offset of the first frame
+	Size        int64   `json:"size"`         // bytes of audio frames
+	StartSample int64   `json:"start_sample"` // samples before this segment
+	SampleRate  int     `json:"sample_rate"`
+	Duration    float64 `json:"duration"` // seconds
+}
+
+// Start returns the presentation time of the segment's first sample
+func (s Segment) Start() time.Duration {
+	return time.Duration(float64(s.StartSample) / float64(s.SampleRate) * float64(time.Second))
+}
+
+// Plan splits frames into segments of about target duration.
+// Segments are frame aligned so each one decodes on its own.
+func Plan(frames []audioduration.Mp3Frame, target time.Duration) []Segment {
+	var segments []Segment
+	var cur *Segment
+	var curSamples, totalSamples int64
+
+	for _, f := range frames {
+		if cur == nil {
+			segments = append(segments, Segment{
+				Index:       len(segments),
+				Offset:      f.Offset,
+				StartSample: totalSamples,
+				SampleRate:  f.SampleRate,
+			})
+			cur = &segments[len(segments)-1]
+			curSamples = 0
+		}
+
+		cur.Size = f.Offset + int64(f.Size) - cur.Offset
+		curSamples += int64(f.Samples)
+		totalSamples += int64(f.Samples)
+		cur.Duration = float64(curSamples) / float64(cur.SampleRate)
+
+		if cur.Duration >= target.Seconds() {
+			cur = nil
+		}
+	}
+	return segments
+}
+
+// WritePlaylist writes a VOD media playlist. uri returns the URI of segment i.
+func WritePlaylist(w io.Writer, segments []Segment, uri func(i int) string) error {
+	var maxDuration float64
+	for _, s := range segments {
+		maxDuration = math.Max(maxDuration, s.Duration)
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "#EXTM3U")
+	fmt.Fprintln(bw, "#EXT-X-VERSION:3")
+	fmt.Fprintf(bw, "#EXT-X-TARGETDURATION:%d\n", int(math.Ceil(maxDuration)))
+	fmt.Fprintln(bw, "#EXT-X-MEDIA-SEQUENCE:0")
+	fmt.Fprintln(bw, "#EXT-X-PLAYLIST-TYPE:VOD")
+	fmt.Fprintln(bw, "#EXT-X-INDEPENDENT-SEGMENTS")
+	for _, s := range segments {
+		fmt.Fprintf(bw, "#EXTINF:%.3f,\n", s.Duration)
+		fmt.Fprintln(bw, uri(s.Index))
+	}
+	fmt.Fprintln(bw, "#EXT-X-ENDLIST")
+	return bw.Flush()
+}
@@ -0,0 +1,56 @@
+package hls
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// timestampOwner is the PRIV owner HLS players read the segment start time from.
+// https://datatracker.ietf.org/doc/html/rfc8216#section-3.4
+const timestampOwner = "com.apple.streaming.transportStreamTimestamp"
+
+// timestampTag builds the ID3v2.4 tag with a PRIV frame holding the 33-bit
+// MPEG-2 PTS (90kHz clock) of the first sample of the segment
+func timestampTag(s Segment) []byte {
+	pts := (s.StartSample * 90000 / int64(s.SampleRate)) & (1<<33 - 1)
+
+	data := make([]byte, 0, len(timestampOwner)+1+8)
+	data = append(data, timestampOwner...)
+	data = append(data, 0)
+	data = binary.BigEndian.AppendUint64(data, uint64(pts))
+
+	frame := make([]byte, 0, 10+len(data))
+	frame = append(frame, "PRIV"...)
+	frame = append(frame, syncsafe(len(data))...)
+	frame = append(frame, 0, 0) // flags
+	frame = append(frame, data...)
+
+	tag := make([]byte, 0, 10+len(frame))
+	tag = append(tag, 'I', 'D', '3', 4, 0, 0)
+	tag = append(tag, syncsafe(len(frame))...)
+	tag = append(tag, frame...)
+	return tag
+}
+
+// syncsafe encodes n as a 4 byte ID3v2 synchsafe integer
+func syncsafe(n int) []byte {
+	return []byte{
+		byte(n>>21) & 0x7F,
+		byte(n>>14) & 0x7F,
+		byte(n>>7) & 0x7F,
+		byte(n) & 0x7F,
+	}
+}
+
+// WriteSegment writes segment s as packed audio: the timestamp tag followed by
+// the segment's frames read from src.
+func WriteSegment(w io.Writer, src io.ReadSeeker, s Segment) error {
+	if _, err := w.Write(timestampTag(s)); err != nil {
+		return err
+	}
+	if _, err := src.Seek(s.Offset, io.SeekStart); err != nil {
+		return err
+	}
+	_, err := io.CopyN(w, src, s.Size)
+	return err
+}
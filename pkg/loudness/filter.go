@@ -0,0 +1,68 @@
+package loudness
+
+import "math"
+
+// biquad is a second order IIR filter (direct form II transposed)
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	z1, z2             float64
+}
+
+func (f *biquad) process(x float64) float64 {
+	y := f.b0*x + f.z1
+	f.z1 = f.b1*x - f.a1*y + f.z2
+	f.z2 = f.b2*x - f.a2*y
+	return y
+}
+
+// kWeighting returns the two stages of the BS.1770 K-weighting filter for
+// a sample rate: a high shelf modelling the head, then the RLB high-pass.
+// The spec only lists 48 kHz coefficients; they are derived for other rates
+// from the analog prototypes, as done by libebur128.
+func kWeighting(sampleRate int) (shelf, highpass biquad) {
+	fs := float64(sampleRate)
+
+	// Stage 1: high shelf, +4 dB above ~1.5 kHz
+	f0 := 1681.974450955533
+	g := 3.999843853973347
+	q := 0.7071752369554196
+	k := math.Tan(math.Pi * f0 / fs)
+	vh := math.Pow(10, g/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + k/q + k*k
+	shelf = biquad{
+		b0: (vh + vb*k/q + k*k) / a0,
+		b1: 2 * (k*k - vh) / a0,
+		b2: (vh - vb*k/q + k*k) / a0,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+
+	// Stage 2: high-pass at ~38 Hz
+	f0 = 38.13547087602444
+	q = 0.5003270373238773
+	k = math.Tan(math.Pi * f0 / fs)
+	a0 = 1 + k/q + k*k
+	highpass = biquad{
+		b0: 1,
+		b1: -2,
+		b2: 1,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+	return shelf, highpass
+}
+
+// channelWeight is the BS.1770 weight of a channel in WAV/FLAC order:
+// 5.1 is L R C LFE Ls Rs, the LFE is ignored and surrounds get +1.5 dB
+func channelWeight(ch, channels int) float64 {
+	if channels == 6 {
+		switch ch {
+		case 3:
+			return 0
+		case 4, 5:
+			return 1.41
+		}
+	}
+	return 1
+}
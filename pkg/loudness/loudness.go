@@ -0,0 +1,274 @@
+// Package loudness measures programme loudness and true peak as defined by
+// ITU-R BS.1770-4 / EBU R128, and derives ReplayGain 2.0 gains.
+package loudness
+
+import (
+	"errors"
+	"io"
+	"math"
+
+	"spotify-clone/pkg/pcm"
+)
+
+// ReferenceLUFS is the ReplayGain 2.0 target loudness
+const ReferenceLUFS = -18.0
+
+const (
+	absoluteGate = -70.0 // LUFS
+	relativeGate = -10.0 // LU below the ungated loudness
+
+	// Histogram bins cover block loudness from -70 to +30 LUFS in 0.1 LU steps
+	histogramMin  = absoluteGate
+	histogramStep = 0.1
+	HistogramBins = 1000
+)
+
+// ErrSilent is returned when no block is above the absolute gate
+var ErrSilent = errors.New("loudness: audio is silent")
+
+// Result is the loudness analysis of a track
+type Result struct {
+	Integrated float64   // integrated loudness, LUFS
+	TruePeak   float64   // linear, 1.0 = full scale
+	Histogram  Histogram // gating block loudness, to compute album loudness
+}
+
+// TruePeakDBTP returns the true peak in dBTP
+func (r *Result) TruePeakDBTP() float64 {
+	return ToDB(r.TruePeak)
+}
+
+// Gain returns the ReplayGain 2.0 gain (dB) bringing loudness to ReferenceLUFS
+func Gain(lufs float64) float64 {
+	return ReferenceLUFS - lufs
+}
+
+// ToDB converts a linear amplitude to dB
+func ToDB(linear float64) float64 {
+	if linear <= 0 {
+		return math.Inf(-1)
+	}
+	return 20 * math.Log10(linear)
+}
+
+// Analyze decodes d to the end and measures it
+func Analyze(d pcm.Decoder) (*Result, error) {
+	format := d.Format()
+	if format.SampleRate <= 0 || format.Channels <= 0 || format.BitsPerSample <= 0 {
+		return nil, pcm.ErrUnsupported
+	}
+	m := newMeter(format)
+	for {
+		block, err := d.ReadBlock()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		m.write(block)
+	}
+	return m.result()
+}
+
+// meter accumulates K-weighted energy in 100 ms steps; a gating block is
+// 400 ms long with 75% overlap, i.e. the last four steps
+type meter struct {
+	channels []channelState
+	scale    float64 // int sample -> [-1, 1)
+	stepSize int     // samples per 100 ms
+	filled   int     // samples in the current step
+	steps    [4]float64
+	nsteps   int
+	blocks   []float64 // mean square (weighted) of each gating block
+	hist     Histogram
+}
+
+type channelState struct {
+	weight   float64
+	shelf    biquad
+	highpass biquad
+	sum      float64 // sum of squares in the current step
+	peak     *peakMeter
+}
+
+func newMeter(f pcm.Format) *meter {
+	m := &meter{
+		scale:    1 / math.Ldexp(1, f.BitsPerSample-1),
+		stepSize: f.SampleRate / 10,
+	}
+	for ch := 0; ch < f.Channels; ch++ {
+		shelf, highpass := kWeighting(f.SampleRate)
+		m.channels = append(m.channels, channelState{
+			weight:   channelWeight(ch, f.Channels),
+			shelf:    shelf,
+			highpass: highpass,
+			peak:     newPeakMeter(f.SampleRate),
+		})
+	}
+	return m
+}
+
+func (m *meter) write(block [][]int32) {
+	n := len(block[0])
+	for i := 0; i < n; i++ {
+		for ch := range m.channels {
+			c := &m.channels[ch]
+			x := float64(block[ch][i]) * m.scale
+			c.peak.process(x)
+			y := c.highpass.process(c.shelf.process(x))
+			c.sum += y * y
+		}
+		m.filled++
+		if m.filled == m.stepSize {
+			m.endStep()
+		}
+	}
+}
+
+func (m *meter) endStep() {
+	var energy float64
+	for ch := range m.channels {
+		c := &m.channels[ch]
+		energy += c.weight * c.sum
+		c.sum = 0
+	}
+	m.filled = 0
+
+	copy(m.steps[:], m.steps[1:])
+	m.steps[3] = energy
+	m.nsteps++
+	if m.nsteps < 4 {
+		return
+	}
+	z := (m.steps[0] + m.steps[1] + m.steps[2] + m.steps[3]) / float64(4*m.stepSize)
+	m.blocks = append(m.blocks, z)
+	m.hist.add(z)
+}
+
+func (m *meter) result() (*Result, error) {
+	r := &Result{Histogram: m.hist}
+	for ch := range m.channels {
+		r.TruePeak = max(r.TruePeak, m.channels[ch].peak.peak)
+	}
+
+	integrated, ok := gatedLoudness(m.blocks)
+	if !ok {
+		return r, ErrSilent
+	}
+	r.Integrated = integrated
+	return r, nil
+}
+
+// gatedLoudness applies the absolute then relative gate to block energies
+func gatedLoudness(blocks []float64) (float64, bool) {
+	absThreshold := energy(absoluteGate)
+	var sum float64
+	var count int
+	for _, z := range blocks {
+		if z > absThreshold {
+			sum += z
+			count++
+		}
+	}
+	if count == 0 {
+		return 0, false
+	}
+
+	relThreshold := sum / float64(count) * energy(relativeGate)
+	sum, count = 0, 0
+	for _, z := range blocks {
+		if z > absThreshold && z > relThreshold {
+			sum += z
+			count++
+		}
+	}
+	if count == 0 {
+		return 0, false
+	}
+	return lufs(sum / float64(count)), true
+}
+
+// lufs converts a weighted mean square to loudness
+func lufs(z float64) float64 {
+	return -0.691 + 10*math.Log10(z)
+}
+
+// energy is the inverse of lufs
+func energy(l float64) float64 {
+	return math.Pow(10, (l+0.691)/10)
+}
+
+// Histogram counts gating blocks by loudness. Histograms of several tracks
+// can be merged to compute the loudness of an album as if it was one track.
+type Histogram [HistogramBins]uint32
+
+func (h *Histogram) add(z float64) {
+	l := lufs(z)
+	if l < histogramMin || math.IsNaN(l) {
+		return
+	}
+	i := int((l - histogramMin) / histogramStep)
+	h[min(i, HistogramBins-1)]++
+}
+
+// Merge adds the blocks of o to h
+func (h *Histogram) Merge(o *Histogram) {
+	for i := range h {
+		h[i] += o[i]
+	}
+}
+
+// Loudness returns the gated integrated loudness of the blocks in h,
+// using the center of each bin. ok is false if there are no blocks.
+func (h *Histogram) Loudness() (float64, bool) {
+	var blocks []float64
+	var counts []uint32
+	for i, n := range h {
+		if n > 0 {
+			blocks = append(blocks, energy(histogramMin+(float64(i)+0.5)*histogramStep))
+			counts = append(counts, n)
+		}
+	}
+
+	var sum, count float64
+	for i, z := range blocks {
+		sum += z * float64(counts[i])
+		count += float64(counts[i])
+	}
+	if count == 0 {
+		return 0, false
+	}
+	relThreshold := sum / count * energy(relativeGate)
+	sum, count = 0, 0
+	for i, z := range blocks {
+		if z > relThreshold {
+			sum += z * float64(counts[i])
+			count += float64(counts[i])
+		}
+	}
+	if count == 0 {
+		return 0, false
+	}
+	return lufs(sum / count), true
+}
+
+// Counts returns the histogram as a slice (for storage)
+func (h *Histogram) Counts() []int32 {
+	out := make([]int32, HistogramBins)
+	for i, n := range h {
+		out[i] = int32(n)
+	}
+	return out
+}
+
+// HistogramFromCounts is the inverse of Counts
+func HistogramFromCounts(counts []int32) Histogram {
+	var h Histogram
+	for i := 0; i < len(counts) && i < HistogramBins; i++ {
+		if counts[i] > 0 {
+			h[i] = uint32(counts[i])
+		}
+	}
+	return h
+}
@@ -0,0 +1,77 @@
+package loudness
+
+import "math"
+
+const (
+	// truePeakTaps is the length of each polyphase sub-filter
+	truePeakTaps = 12
+)
+
+// peakMeter estimates the true (inter-sample) peak of a channel by
+// oversampling with a windowed-sinc interpolator (BS.1770 Annex 2).
+// Rates of 96 kHz and above are oversampled less, 192 kHz not at all.
+type peakMeter struct {
+	factor  int
+	phases  [][]float64 // phases[p][k]: coefficient for x[n-k] at sub-sample p
+	history []float64   // last truePeakTaps input samples, newest first
+	peak    float64
+}
+
+func newPeakMeter(sampleRate int) *peakMeter {
+	factor := 4
+	switch {
+	case sampleRate >= 192000:
+		factor = 1
+	case sampleRate >= 96000:
+		factor = 2
+	}
+
+	m := &peakMeter{factor: factor, history: make([]float64, truePeakTaps)}
+	if factor == 1 {
+		return m
+	}
+
+	// Low-pass windowed sinc (Hann) của độ dài factor*taps, chia thành các phase
+	n := factor * truePeakTaps
+	center := float64(n-1) / 2
+	m.phases = make([][]float64, factor)
+	for p := range m.phases {
+		m.phases[p] = make([]float64, truePeakTaps)
+	}
+	for i := 0; i < n; i++ {
+		t := (float64(i) - center) / float64(factor)
+		sinc := 1.0
+		if t != 0 {
+			sinc = math.Sin(math.Pi*t) / (math.Pi * t)
+		}
+		window := 0.5 - 0.5*math.Cos(2*math.Pi*(float64(i)+0.5)/float64(n))
+		m.phases[i%factor][i/factor] = sinc * window
+	}
+	// Mỗi phase có DC gain = 1
+	for _, phase := range m.phases {
+		var sum float64
+		for _, c := range phase {
+			sum += c
+		}
+		for k := range phase {
+			phase[k] /= sum
+		}
+	}
+	return m
+}
+
+func (m *peakMeter) process(x float64) {
+	m.peak = max(m.peak, math.Abs(x))
+	if m.factor == 1 {
+		return
+	}
+	copy(m.history[1:], m.history[:len(m.history)-1])
+	m.history[0] = x
+	for _, phase := range m.phases {
+		var y float64
+		for k, c := range phase {
+			y += c * m.history[k]
+		}
+		m.peak = max(m.peak, math.Abs(y))
+	}
+}
@@ -0,0 +1,195 @@
+// Package lyrics parses plain and LRC time-synced lyrics and writes them as
+// WebVTT.
+package lyrics
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrEmpty          = errors.New("lyrics: no lyrics text")
+	ErrBeyondDuration = errors.New("lyrics: timestamp after the end of the song")
+	ErrNotSynced      = errors.New("lyrics: lyrics are not time-synced")
+)
+
+// Line is one line of lyrics. Time is its start for synced lyrics, 0 otherwise.
+// An empty Text in synced lyrics marks the end of the previous line.
+type Line struct {
+	Time time.Duration
+	Text string
+}
+
+// Lyrics are the lines of a song, sorted by time when Synced
+type Lyrics struct {
+	Synced bool
+	Lines  []Line
+}
+
+// ParseError reports an invalid line of an LRC file
+type ParseError struct {
+	Line int // 1-based
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("lyrics: line %d: %v", e.Line, e.Err)
+}
+
+func (e *ParseError) Unwrap() error { return e.Err }
+
+var (
+	// [mm:ss], [mm:ss.xx], [mm:ss.xxx], [mm:ss:xx]
+	lrcTimeTag = regexp.MustCompile(`^\[(\d+):(\d{1,2})(?:[.:](\d{1,3}))?\]`)
+	// [ar:Artist], [offset:+250], ... (ID tags)
+	lrcIDTag = regexp.MustCompile(`^\[([A-Za-z#]+):([^\]]*)\]\s*$`)
+	// <mm:ss.xx> word timing of enhanced LRC
+	lrcWordTag = regexp.MustCompile(`<\d+:\d{1,2}(?:[.:]\d{1,3})?>`)
+)
+
+// Parse parses text as LRC if any line starts with a time tag, as plain
+// lyrics (one line per line of text) otherwise
+func Parse(text string) (*Lyrics, error) {
+	for _, line := range splitLines(text) {
+		if lrcTimeTag.MatchString(strings.TrimSpace(line)) {
+			return ParseLRC(text)
+		}
+	}
+	return ParsePlain(text)
+}
+
+// ParsePlain splits untimed lyrics in lines, trimming leading and trailing
+// blank lines
+func ParsePlain(text string) (*Lyrics, error) {
+	lines := splitLines(text)
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return nil, ErrEmpty
+	}
+
+	l := &Lyrics{}
+	for _, line := range lines {
+		l.Lines = append(l.Lines, Line{Text: strings.TrimSpace(line)})
+	}
+	return l, nil
+}
+
+// NewSynced returns synced lyrics from timed lines in any order
+func NewSynced(lines []Line) (*Lyrics, error) {
+	if len(lines) == 0 {
+		return nil, ErrEmpty
+	}
+	lines = append([]Line(nil), lines...)
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].Time < lines[j].Time })
+	return &Lyrics{Synced: true, Lines: lines}, nil
+}
+
+// ParseLRC parses LRC lyrics. A line may carry several time tags (repeated
+// chorus); the [offset:ms] tag is applied. Lines without time tag are ignored.
+func ParseLRC(text string) (*Lyrics, error) {
+	var lines []Line
+	var offset time.Duration
+	for i, raw := range splitLines(text) {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+
+		if m := lrcIDTag.FindStringSubmatch(raw); m != nil {
+			if strings.EqualFold(m[1], "offset") {
+				ms, err := strconv.Atoi(strings.TrimSpace(m[2]))
+				if err != nil {
+					return nil, &ParseError{Line: i + 1, Err: fmt.Errorf("invalid offset %q", m[2])}
+				}
+				offset = time.Duration(ms) * time.Millisecond
+			}
+			continue
+		}
+
+		var times []time.Duration
+		for {
+			m := lrcTimeTag.FindStringSubmatch(raw)
+			if m == nil {
+				break
+			}
+			t, err := parseTimeTag(m)
+			if err != nil {
+				return nil, &ParseError{Line: i + 1, Err: err}
+			}
+			times = append(times, t)
+			raw = strings.TrimSpace(raw[len(m[0]):])
+		}
+		if len(times) == 0 {
+			if strings.HasPrefix(raw, "[") {
+				return nil, &ParseError{Line: i + 1, Err: fmt.Errorf("invalid time tag in %q", raw)}
+			}
+			continue
+		}
+
+		text := strings.TrimSpace(lrcWordTag.ReplaceAllString(raw, ""))
+		for _, t := range times {
+			lines = append(lines, Line{Time: t, Text: text})
+		}
+	}
+	// Offset dương = lời hiện sớm hơn
+	for i := range lines {
+		lines[i].Time = max(lines[i].Time-offset, 0)
+	}
+	return NewSynced(lines)
+}
+
+func parseTimeTag(m []string) (time.Duration, error) {
+	minutes, err := strconv.Atoi(m[1])
+	if err != nil {
+		return 0, fmt.Errorf("invalid minutes %q", m[1])
+	}
+	seconds, _ := strconv.Atoi(m[2])
+	if seconds >= 60 {
+		return 0, fmt.Errorf("invalid seconds %q", m[2])
+	}
+	var fraction time.Duration
+	if m[3] != "" {
+		// .x = 1/10 s, .xx = 1/100 s, .xxx = ms
+		n, _ := strconv.Atoi(m[3])
+		fraction = time.Duration(n) * time.Second
+		for range len(m[3]) {
+			fraction /= 10
+		}
+	}
+	return time.Duration(minutes)*time.Minute + time.Duration(seconds)*time.Second + fraction, nil
+}
+
+func splitLines(text string) []string {
+	text = strings.TrimPrefix(text, "\ufeff")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.Split(strings.ReplaceAll(text, "\r", "\n"), "\n")
+}
+
+// Validate checks that no synced line starts after duration. A zero
+// duration (unknown) is not checked.
+func (l *Lyrics) Validate(duration time.Duration) error {
+	if !l.Synced || duration <= 0 {
+		return nil
+	}
+	last := l.Lines[len(l.Lines)-1]
+	if last.Time > duration {
+		return fmt.Errorf("%w: %s > %s", ErrBeyondDuration, formatLRCTime(last.Time), formatLRCTime(duration))
+	}
+	return nil
+}
+
+// formatLRCTime formats t as mm:ss.xx
+func formatLRCTime(t time.Duration) string {
+	cs := t.Milliseconds() / 10
+	return fmt.Sprintf("%02d:%02d.%02d", cs/6000, cs/100%60, cs%100)
+}
@@ -0,0 +1,55 @@
+package lyrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// lastCueLength is how long the last line is shown when the song duration
+// is unknown
+const lastCueLength = 5 * time.Second
+
+// WriteVTT writes synced lyrics as a WebVTT file: each line is shown until
+// the next one starts, the last one until duration
+// https://www.w3.org/TR/webvtt1/
+func (l *Lyrics) WriteVTT(w io.Writer, duration time.Duration) error {
+	if !l.Synced {
+		return ErrNotSynced
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString("WEBVTT\n")
+	cue := 0
+	for i, line := range l.Lines {
+		if line.Text == "" {
+			continue // dòng trống chỉ đánh dấu kết thúc dòng trước
+		}
+		end := line.Time + lastCueLength
+		if i+1 < len(l.Lines) {
+			end = l.Lines[i+1].Time
+		} else if duration > line.Time {
+			end = duration
+		}
+		if end <= line.Time {
+			continue // cùng thời điểm với dòng sau
+		}
+		cue++
+		fmt.Fprintf(bw, "\n%d\n%s --> %s\n%s\n", cue, formatVTTTime(line.Time), formatVTTTime(end), escapeVTT(line.Text))
+	}
+	return bw.Flush()
+}
+
+// formatVTTTime formats t as hh:mm:ss.ttt
+func formatVTTTime(t time.Duration) string {
+	ms := t.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+var vttEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func escapeVTT(s string) string {
+	return vttEscaper.Replace(s)
+}
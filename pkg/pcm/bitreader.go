@@ -0,0 +1,83 @@
+package pcm
+
+import (
+	"bufio"
+	"io"
+)
+
+// bitReader reads MSB-first bit fields
+type bitReader struct {
+	r     *bufio.Reader
+	cache uint64 // bits not consumed yet, right aligned
+	n     uint   // number of valid bits in cache
+}
+
+func newBitReader(r io.Reader) *bitReader {
+	if br, ok := r.(*bufio.Reader); ok {
+		return &bitReader{r: br}
+	}
+	return &bitReader{r: bufio.NewReaderSize(r, 64*1024)}
+}
+
+// readBits reads n (<= 56) bits as an unsigned value
+func (b *bitReader) readBits(n uint) (uint64, error) {
+	for b.n < n {
+		c, err := b.r.ReadByte()
+		if err != nil {
+			if err == io.EOF && b.n > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+		b.cache = b.cache<<8 | uint64(c)
+		b.n += 8
+	}
+	b.n -= n
+	v := (b.cache >> b.n) & (1<<n - 1)
+	b.cache &= 1<<b.n - 1
+	return v, nil
+}
+
+// readSigned reads n bits as a two's complement value
+func (b *bitReader) readSigned(n uint) (int64, error) {
+	if n == 0 {
+		return 0, nil
+	}
+	v, err := b.readBits(n)
+	if err != nil {
+		return 0, err
+	}
+	return int64(v<<(64-n)) >> (64 - n), nil
+}
+
+// readUnary counts zero bits before the next one bit
+func (b *bitReader) readUnary() (uint64, error) {
+	var count uint64
+	for {
+		if b.n == 0 {
+			c, err := b.r.ReadByte()
+			if err != nil {
+				return 0, err
+			}
+			// Byte toàn số 0: đếm nhanh
+			if c == 0 {
+				count += 8
+				continue
+			}
+			b.cache = uint64(c)
+			b.n = 8
+		}
+		b.n--
+		if (b.cache>>b.n)&1 == 1 {
+			b.cache &= 1<<b.n - 1
+			return count, nil
+		}
+		count++
+	}
+}
+
+// align drops the bits left in the current byte
+func (b *bitReader) align() {
+	b.n -= b.n % 8
+	b.cache &= 1<<b.n - 1
+}
@@ -0,0 +1,486 @@
+package pcm
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// https://xiph.org/flac/format.html
+
+type flacDecoder struct {
+	br     *bitReader
+	format Format
+	block  [][]int32
+}
+
+// NewFLACDecoder parses the FLAC metadata of r and returns a decoder positioned
+// at the first audio frame.
+func NewFLACDecoder(r io.Reader) (Decoder, error) {
+	br := bufio.NewReaderSize(r, 64*1024)
+
+	// Một số file FLAC có ID3v2 tag phía trước
+	if head, err := br.Peek(10); err == nil && string(head[0:3]) == "ID3" {
+		size := int(head[6])<<21 | int(head[7])<<14 | int(head[8])<<7 | int(head[9])
+		if head[5]&0x10 != 0 { // footer
+			size += 10
+		}
+		if _, err := br.Discard(10 + size); err != nil {
+			return nil, err
+		}
+	}
+
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(br, buf); err != nil {
+		return nil, err
+	}
+	if string(buf) != "fLaC" {
+		return nil, errors.New("pcm: expected 'fLaC' at file start")
+	}
+
+	d := &flacDecoder{}
+	haveStreamInfo := false
+	for {
+		if _, err := io.ReadFull(br, buf); err != nil {
+			return nil, err
+		}
+		last := buf[0]&0x80 != 0
+		blockType := buf[0] & 0x7f
+		blockSize := int64(binary.BigEndian.Uint32(buf) & 0x00FFFFFF)
+
+		if blockType == 0 { // Streaminfo
+			info := make([]byte, blockSize)
+			if _, err := io.ReadFull(br, info); err != nil || blockSize < 18 {
+				return nil, ErrCorrupt
+			}
+			packed := binary.BigEndian.Uint64(info[10:18])
+			d.format = Format{
+				SampleRate:    int(packed >> 44),
+				Channels:      int((packed>>41)&0x7) + 1,
+				BitsPerSample: int((packed>>36)&0x1f) + 1,
+			}
+			haveStreamInfo = true
+		} else if _, err := io.CopyN(io.Discard, br, blockSize); err != nil {
+			return nil, err
+		}
+		if last {
+			break
+		}
+	}
+	if !haveStreamInfo {
+		return nil, ErrCorrupt
+	}
+
+	d.br = newBitReader(br)
+	d.block = make([][]int32, d.format.Channels)
+	return d, nil
+}
+
+func (d *flacDecoder) Format() Format {
+	return d.format
+}
+
+// frameHeader holds the fields of a frame header needed to decode it
+type frameHeader struct {
+	blockSize  int
+	channels   int
+	assignment uint64 // 0-7 independent, 8 left/side, 9 side/right, 10 mid/side
+	bps        int
+}
+
+func (d *flacDecoder) readFrameHeader() (frameHeader, error) {
+	var h frameHeader
+	br := d.br
+
+	sync, err := br.readBits(14)
+	if err != nil {
+		return h, err
+	}
+	if sync != 0x3FFE {
+		return h, ErrCorrupt
+	}
+	// reserved + blocking strategy
+	if _, err := br.readBits(2); err != nil {
+		return h, err
+	}
+
+	fields, err := br.readBits(16)
+	if err != nil {
+		return h, err
+	}
+	blockSizeCode := fields >> 12
+	sampleRateCode := (fields >> 8) & 0xF
+	h.assignment = (fields >> 4) & 0xF
+	sampleSizeCode := (fields >> 1) & 0x7
+
+	// Frame/sample number coded như UTF-8
+	first, err := br.readBits(8)
+	if err != nil {
+		return h, err
+	}
+	extra := 0
+	for mask := uint64(0x80); first&mask != 0 && mask > 1; mask >>= 1 {
+		extra++
+	}
+	if extra == 1 || extra > 7 {
+		return h, ErrCorrupt
+	}
+	if extra > 1 {
+		if _, err := br.readBits(uint(8 * (extra - 1))); err != nil {
+			return h, err
+		}
+	}
+
+	switch {
+	case blockSizeCode == 1:
+		h.blockSize = 192
+	case blockSizeCode >= 2 && blockSizeCode <= 5:
+		h.blockSize = 576 << (blockSizeCode - 2)
+	case blockSizeCode == 6:
+		v, err := br.readBits(8)
+		if err != nil {
+			return h, err
+		}
+		h.blockSize = int(v) + 1
+	case blockSizeCode == 7:
+		v, err := br.readBits(16)
+		if err != nil {
+			return h, err
+		}
+		h.blockSize = int(v) + 1
+	case blockSizeCode >= 8:
+		h.blockSize = 256 << (blockSizeCode - 8)
+	default:
+		return h, ErrCorrupt
+	}
+
+	// Sample rate lấy từ streaminfo, chỉ cần bỏ qua các byte mở rộng
+	switch sampleRateCode {
+	case 12:
+		_, err = br.readBits(8)
+	case 13, 14:
+		_, err = br.readBits(16)
+	case 15:
+		err = ErrCorrupt
+	}
+	if err != nil {
+		return h, err
+	}
+
+	switch sampleSizeCode {
+	case 0:
+		h.bps = d.format.BitsPerSample
+	case 1:
+		h.bps = 8
+	case 2:
+		h.bps = 12
+	case 4:
+		h.bps = 16
+	case 5:
+		h.bps = 20
+	case 6:
+		h.bps = 24
+	case 7:
+		h.bps = 32
+	default:
+		return h, ErrCorrupt
+	}
+
+	switch {
+	case h.assignment < 8:
+		h.channels = int(h.assignment) + 1
+	case h.assignment <= 10:
+		h.channels = 2
+	default:
+		return h, ErrCorrupt
+	}
+	if h.channels != d.format.Channels {
+		return h, ErrCorrupt
+	}
+
+	// CRC-8
+	if _, err := br.readBits(8); err != nil {
+		return h, err
+	}
+	return h, nil
+}
+
+// ReadBlock decodes the next frame. A truncated last frame is dropped like
+// the end of the stream.
+func (d *flacDecoder) ReadBlock() ([][]int32, error) {
+	block, err := d.readFrame()
+	if err == io.ErrUnexpectedEOF {
+		return nil, io.EOF
+	}
+	return block, err
+}
+
+func (d *flacDecoder) readFrame() ([][]int32, error) {
+	// ID3v1 tag ở cuối file
+	if d.br.n == 0 {
+		if tag, _ := d.br.r.Peek(3); string(tag) == "TAG" {
+			return nil, io.EOF
+		}
+	}
+
+	h, err := d.readFrameHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	for ch := 0; ch < h.channels; ch++ {
+		if cap(d.block[ch]) < h.blockSize {
+			d.block[ch] = make([]int32, h.blockSize)
+		}
+		d.block[ch] = d.block[ch][:h.blockSize]
+
+		// Kênh side cần thêm 1 bit
+		bps := h.bps
+		if (h.assignment == 8 && ch == 1) || (h.assignment == 9 && ch == 0) || (h.assignment == 10 && ch == 1) {
+			bps++
+		}
+		if err := d.readSubframe(d.block[ch], bps); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+	}
+
+	d.decorrelate(h.assignment)
+
+	// Padding tới hết byte + CRC-16
+	d.br.align()
+	if _, err := d.br.readBits(16); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return d.block, nil
+}
+
+// decorrelate restores left/right channels from stereo decorrelation
+func (d *flacDecoder) decorrelate(assignment uint64) {
+	switch assignment {
+	case 8: // left/side
+		left, side := d.block[0], d.block[1]
+		for i := range side {
+			side[i] = left[i] - side[i]
+		}
+	case 9: // side/right
+		side, right := d.block[0], d.block[1]
+		for i := range side {
+			side[i] += right[i]
+		}
+	case 10: // mid/side
+		mid, side := d.block[0], d.block[1]
+		for i := range mid {
+			m := int64(mid[i])<<1 | int64(side[i])&1
+			s := int64(side[i])
+			mid[i] = int32((m + s) >> 1)
+			side[i] = int32((m - s) >> 1)
+		}
+	}
+}
+
+// fixedCoefficients are the fixed predictor polynomials by order
+var fixedCoefficients = [][]int64{
+	{},
+	{1},
+	{2, -1},
+	{3, -3, 1},
+	{4, -6, 4, -1},
+}
+
+func (d *flacDecoder) readSubframe(out []int32, bps int) error {
+	br := d.br
+	hdr, err := br.readBits(8)
+	if err != nil {
+		return err
+	}
+	if hdr&0x80 != 0 {
+		return ErrCorrupt
+	}
+	subType := (hdr >> 1) & 0x3F
+
+	wasted := 0
+	if hdr&1 == 1 {
+		k, err := br.readUnary()
+		if err != nil {
+			return err
+		}
+		wasted = int(k) + 1
+		bps -= wasted
+	}
+	if bps <= 0 {
+		return ErrCorrupt
+	}
+
+	switch {
+	case subType == 0: // constant
+		v, err := br.readSigned(uint(bps))
+		if err != nil {
+			return err
+		}
+		for i := range out {
+			out[i] = int32(v)
+		}
+	case subType == 1: // verbatim
+		for i := range out {
+			v, err := br.readSigned(uint(bps))
+			if err != nil {
+				return err
+			}
+			out[i] = int32(v)
+		}
+	case subType >= 8 && subType <= 12: // fixed
+		order := int(subType & 0x7)
+		if order > 4 || order > len(out) {
+			return ErrCorrupt
+		}
+		if err := d.readWarmup(out[:order], bps); err != nil {
+			return err
+		}
+		if err := d.readResidual(out, order); err != nil {
+			return err
+		}
+		predict(out, fixedCoefficients[order], 0)
+	case subType >= 32: // LPC
+		order := int(subType&0x1F) + 1
+		if order > len(out) {
+			return ErrCorrupt
+		}
+		if err := d.readWarmup(out[:order], bps); err != nil {
+			return err
+		}
+		precision, err := br.readBits(4)
+		if err != nil {
+			return err
+		}
+		if precision == 15 {
+			return ErrCorrupt
+		}
+		shift, err := br.readSigned(5)
+		if err != nil {
+			return err
+		}
+		if shift < 0 {
+			return ErrCorrupt
+		}
+		coeffs := make([]int64, order)
+		for i := range coeffs {
+			if coeffs[i], err = br.readSigned(uint(precision + 1)); err != nil {
+				return err
+			}
+		}
+		if err := d.readResidual(out, order); err != nil {
+			return err
+		}
+		predict(out, coeffs, uint(shift))
+	default:
+		return ErrCorrupt
+	}
+
+	if wasted > 0 {
+		for i := range out {
+			out[i] <<= wasted
+		}
+	}
+	return nil
+}
+
+func (d *flacDecoder) readWarmup(out []int32, bps int) error {
+	for i := range out {
+		v, err := d.br.readSigned(uint(bps))
+		if err != nil {
+			return err
+		}
+		out[i] = int32(v)
+	}
+	return nil
+}
+
+// readResidual decodes the partitioned Rice coded residual into out[order:]
+func (d *flacDecoder) readResidual(out []int32, order int) error {
+	br := d.br
+	method, err := br.readBits(2)
+	if err != nil {
+		return err
+	}
+	paramBits, escape := uint(4), uint64(15)
+	switch method {
+	case 0:
+	case 1:
+		paramBits, escape = 5, 31
+	default:
+		return ErrCorrupt
+	}
+
+	partitionOrder, err := br.readBits(4)
+	if err != nil {
+		return err
+	}
+	partitions := 1 << partitionOrder
+	perPartition := len(out) >> partitionOrder
+	if perPartition < order {
+		return ErrCorrupt
+	}
+
+	i := order
+	for p := 0; p < partitions; p++ {
+		n := perPartition
+		if p == 0 {
+			n -= order
+		}
+
+		param, err := br.readBits(paramBits)
+		if err != nil {
+			return err
+		}
+		if param == escape {
+			// Partition không nén: mỗi residual dùng rawBits bit
+			rawBits, err := br.readBits(5)
+			if err != nil {
+				return err
+			}
+			for j := 0; j < n; j++ {
+				v, err := br.readSigned(uint(rawBits))
+				if err != nil {
+					return err
+				}
+				out[i] = int32(v)
+				i++
+			}
+			continue
+		}
+
+		for j := 0; j < n; j++ {
+			q, err := br.readUnary()
+			if err != nil {
+				return err
+			}
+			r, err := br.readBits(uint(param))
+			if err != nil {
+				return err
+			}
+			u := q<<param | r
+			out[i] = int32(int64(u>>1) ^ -int64(u&1))
+			i++
+		}
+	}
+	return nil
+}
+
+// predict turns residuals in out[len(coeffs):] into samples
+func predict(out []int32, coeffs []int64, shift uint) {
+	order := len(coeffs)
+	for i := order; i < len(out); i++ {
+		var sum int64
+		for j, c := range coeffs {
+			sum += c * int64(out[i-j-1])
+		}
+		out[i] += int32(sum >> shift)
+	}
+}
@@ -0,0 +1,24 @@
+// Package pcm decodes WAV and FLAC files to PCM samples in pure Go.
+package pcm
+
+import "errors"
+
+var (
+	ErrUnsupported = errors.New("pcm: unsupported audio encoding")
+	ErrCorrupt     = errors.New("pcm: corrupt audio data")
+)
+
+// Format describes decoded PCM samples
+type Format struct {
+	SampleRate    int
+	Channels      int
+	BitsPerSample int
+}
+
+// Decoder yields PCM samples block by block
+type Decoder interface {
+	Format() Format
+	// ReadBlock returns the next block of samples, one slice per channel.
+	// The slices are reused by the next call. It returns io.EOF at the end.
+	ReadBlock() ([][]int32, error)
+}
@@ -0,0 +1,172 @@
+package pcm
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// WAV format tags
+const (
+	wavFormatPCM        = 0x0001
+	wavFormatExtensible = 0xFFFE
+)
+
+// wavBlockFrames is how many sample frames ReadBlock returns at most
+const wavBlockFrames = 4096
+
+// wavMaxChannels matches FLAC's limit: the block buffers are allocated per
+// channel, so the header must not choose their size
+const wavMaxChannels = 8
+
+// wavMaxFmtSize bounds the fmt chunk (40 bytes for WAVE_FORMAT_EXTENSIBLE)
+const wavMaxFmtSize = 1024
+
+type wavDecoder struct {
+	r         io.Reader
+	format    Format
+	remaining int64 // bytes left in the data chunk
+	raw       []byte
+	block     [][]int32
+}
+
+// NewWAVDecoder parses the RIFF/WAVE header of r and returns a decoder
+// positioned at the start of the sample data. Only integer PCM is supported.
+// http://soundfile.sapp.org/doc/WaveFormat/
+func NewWAVDecoder(r io.Reader) (Decoder, error) {
+	br := bufio.NewReader(r)
+
+	hdr := make([]byte, 12)
+	if _, err := io.ReadFull(br, hdr); err != nil {
+		return nil, err
+	}
+	if string(hdr[0:4]) != "RIFF" || string(hdr[8:12]) != "WAVE" {
+		return nil, errors.New("pcm: expected 'RIFF....WAVE' at file start")
+	}
+
+	d := &wavDecoder{r: br}
+	haveFmt := false
+	chunk := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(br, chunk); err != nil {
+			if err == io.EOF {
+				return nil, errors.New("pcm: no data chunk in wav file")
+			}
+			return nil, err
+		}
+		id := string(chunk[0:4])
+		size := int64(binary.LittleEndian.Uint32(chunk[4:8]))
+
+		switch id {
+		case "fmt ":
+			if size < 16 || size > wavMaxFmtSize {
+				return nil, ErrCorrupt
+			}
+			buf := make([]byte, size)
+			if _, err := io.ReadFull(br, buf); err != nil {
+				return nil, err
+			}
+			formatTag := binary.LittleEndian.Uint16(buf[0:2])
+			if formatTag == wavFormatExtensible && size >= 26 {
+				// Sub format GUID bắt đầu bằng format tag thật
+				formatTag = binary.LittleEndian.Uint16(buf[24:26])
+			}
+			d.format = Format{
+				Channels:      int(binary.LittleEndian.Uint16(buf[2:4])),
+				SampleRate:    int(binary.LittleEndian.Uint32(buf[4:8])),
+				BitsPerSample: int(binary.LittleEndian.Uint16(buf[14:16])),
+			}
+			if formatTag != wavFormatPCM || d.format.Channels == 0 || d.format.Channels > wavMaxChannels {
+				return nil, ErrUnsupported
+			}
+			switch d.format.BitsPerSample {
+			case 8, 16, 24, 32:
+			default:
+				return nil, ErrUnsupported
+			}
+			haveFmt = true
+		case "data":
+			if !haveFmt {
+				return nil, ErrCorrupt
+			}
+			d.remaining = size
+			frameSize := d.frameSize()
+			d.raw = make([]byte, wavBlockFrames*frameSize)
+			d.block = make([][]int32, d.format.Channels)
+			for i := range d.block {
+				d.block[i] = make([]int32, wavBlockFrames)
+			}
+			return d, nil
+		default:
+			// Bỏ qua các chunk khác (LIST, fact, ...)
+			if _, err := io.CopyN(io.Discard, br, size); err != nil {
+				return nil, err
+			}
+		}
+		// Chunk có độ dài lẻ được pad thêm 1 byte
+		if size%2 == 1 {
+			if _, err := br.Discard(1); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+func (d *wavDecoder) Format() Format {
+	return d.format
+}
+
+func (d *wavDecoder) frameSize() int {
+	return d.format.Channels * d.format.BitsPerSample / 8
+}
+
+func (d *wavDecoder) ReadBlock() ([][]int32, error) {
+	frameSize := d.frameSize()
+	n := int64(len(d.raw))
+	if n > d.remaining {
+		n = d.remaining - d.remaining%int64(frameSize)
+	}
+	if n == 0 {
+		return nil, io.EOF
+	}
+
+	buf := d.raw[:n]
+	read, err := io.ReadFull(d.r, buf)
+	if err == io.ErrUnexpectedEOF {
+		// File bị cắt cụt: trả về những frame đọc được
+		buf = buf[:read-read%frameSize]
+		d.remaining = 0
+	} else if err != nil {
+		return nil, err
+	} else {
+		d.remaining -= n
+	}
+
+	frames := len(buf) / frameSize
+	if frames == 0 {
+		return nil, io.EOF
+	}
+	width := d.format.BitsPerSample / 8
+	for ch := range d.block {
+		d.block[ch] = d.block[ch][:frames]
+	}
+	for i := 0; i < frames; i++ {
+		for ch := range d.block {
+			p := buf[i*frameSize+ch*width:]
+			var s int32
+			switch width {
+			case 1:
+				s = int32(p[0]) - 128 // 8-bit WAV là unsigned
+			case 2:
+				s = int32(int16(binary.LittleEndian.Uint16(p)))
+			case 3:
+				s = int32(uint32(p[0])<<8|uint32(p[1])<<16|uint32(p[2])<<24) >> 8
+			case 4:
+				s = int32(binary.LittleEndian.Uint32(p))
+			}
+			d.block[ch][i] = s
+		}
+	}
+	return d.block, nil
+}
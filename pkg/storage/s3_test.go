@@ -0,0 +1,306 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeS3 is an in-memory stand-in for the S3 object API (path-style URLs)
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string]fakeObject // "/bucket/key" -> object
+	ranges  []string              // Range headers of GET requests
+}
+
+type fakeObject struct {
+	data        []byte
+	contentType string
+	modified    time.Time
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !signed(r) {
+		http.Error(w, "missing or expired signature", http.StatusForbidden)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	obj, exists := f.objects[r.URL.Path]
+	switch r.Method {
+	case http.MethodPut:
+		// Như AWS S3: PUT phải có Content-Length
+		if r.ContentLength < 0 {
+			http.Error(w, "MissingContentLength", http.StatusLengthRequired)
+			return
+		}
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.objects[r.URL.Path] = fakeObject{data: data, contentType: r.Header.Get("Content-Type"), modified: time.Now()}
+	case http.MethodHead:
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(obj.data)))
+		w.Header().Set("Content-Type", obj.contentType)
+		w.Header().Set("Last-Modified", obj.modified.UTC().Format(http.TimeFormat))
+	case http.MethodGet:
+		if !exists {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		rangeHeader := r.Header.Get("Range")
+		f.ranges = append(f.ranges, rangeHeader)
+		start := 0
+		if rangeHeader != "" {
+			n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rangeHeader, "bytes="), "-"))
+			if err != nil || n >= len(obj.data) {
+				w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+				return
+			}
+			start = n
+			w.WriteHeader(http.StatusPartialContent)
+		}
+		w.Write(obj.data[start:])
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// signed reports whether r carries a Signature V4 Authorization header or
+// an unexpired presigned query string
+func signed(r *http.Request) bool {
+	if r.Header.Get("Authorization") != "" {
+		return strings.HasPrefix(r.Header.Get("Authorization"), sigAlgorithm+" Credential=test-key/") &&
+			r.Header.Get("x-amz-date") != ""
+	}
+	q := r.URL.Query()
+	if q.Get("X-Amz-Algorithm") != sigAlgorithm || q.Get("X-Amz-Signature") == "" ||
+		!strings.HasPrefix(q.Get("X-Amz-Credential"), "test-key/") {
+		return false
+	}
+	signedAt, err := time.Parse(amzDateFormat, q.Get("X-Amz-Date"))
+	if err != nil {
+		return false
+	}
+	expires, err := strconv.Atoi(q.Get("X-Amz-Expires"))
+	if err != nil {
+		return false
+	}
+	return time.Now().Before(signedAt.Add(time.Duration(expires) * time.Second))
+}
+
+// has reports whether an object is stored under path
+func (f *fakeS3) has(path string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, ok := f.objects[path]
+	return ok
+}
+
+// lastRange returns the Range header of the last GET request
+func (f *fakeS3) lastRange() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.ranges) == 0 {
+		return ""
+	}
+	return f.ranges[len(f.ranges)-1]
+}
+
+func newTestS3(t *testing.T) (*S3, *fakeS3) {
+	t.Helper()
+	fake := &fakeS3{objects: make(map[string]fakeObject)}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	s, err := NewS3(S3Config{
+		Endpoint:  server.URL,
+		Bucket:    "music",
+		AccessKey: "test-key",
+		SecretKey: "test-secret",
+		PathStyle: true,
+	})
+	if err != nil {
+		t.Fatalf("NewS3: %v", err)
+	}
+	return s, fake
+}
+
+func TestS3PutStatOpenDelete(t *testing.T) {
+	s, fake := newTestS3(t)
+	ctx := context.Background()
+	const key = "audio/abc.mp3"
+	content := "0123456789abcdef"
+
+	if err := s.Put(ctx, key, strings.NewReader(content), int64(len(content)), "audio/mpeg"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if !fake.has("/music/" + key) {
+		t.Fatalf("Put did not store the object under the path-style URL")
+	}
+
+	info, err := s.Stat(ctx, key)
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if info.Key != key || info.Size != int64(len(content)) || info.ContentType != "audio/mpeg" || info.LastModified.IsZero() {
+		t.Fatalf("Stat = %+v", info)
+	}
+
+	obj, err := s.Open(ctx, key)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer obj.Close()
+
+	data, err := io.ReadAll(obj)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if string(data) != content {
+		t.Fatalf("read %q, want %q", data, content)
+	}
+
+	if pos, err := obj.Seek(-6, io.SeekEnd); err != nil || pos != 10 {
+		t.Fatalf("Seek = %d, %v", pos, err)
+	}
+	buf := make([]byte, 3)
+	if _, err := io.ReadFull(obj, buf); err != nil {
+		t.Fatalf("read after seek: %v", err)
+	}
+	if string(buf) != "abc" {
+		t.Fatalf("read after seek %q, want %q", buf, "abc")
+	}
+	if last := fake.lastRange(); last != "bytes=10-" {
+		t.Fatalf("Range after seek = %q, want %q", last, "bytes=10-")
+	}
+
+	if err := s.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := s.Stat(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Stat after Delete = %v, want ErrNotFound", err)
+	}
+}
+
+func TestS3MissingObject(t *testing.T) {
+	s, _ := newTestS3(t)
+	ctx := context.Background()
+
+	if _, err := s.Stat(ctx, "audio/missing.mp3"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Stat = %v, want ErrNotFound", err)
+	}
+	if _, err := s.Open(ctx, "audio/missing.mp3"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Open = %v, want ErrNotFound", err)
+	}
+	// Xóa object không tồn tại không phải lỗi
+	if err := s.Delete(ctx, "audio/missing.mp3"); err != nil {
+		t.Fatalf("Delete = %v, want nil", err)
+	}
+}
+
+func TestS3InvalidKey(t *testing.T) {
+	s, _ := newTestS3(t)
+
+	for _, key := range []string{"", "/audio/abc.mp3"} {
+		if err := s.Put(context.Background(), key, strings.NewReader("x"), 1, ""); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Put(%q) = %v, want ErrInvalidKey", key, err)
+		}
+	}
+}
+
+func TestS3PresignedURL(t *testing.T) {
+	s, _ := newTestS3(t)
+	ctx := context.Background()
+	const key = "audio/abc.mp3"
+	content := "presigned content"
+
+	if err := s.Put(ctx, key, strings.NewReader(content), int64(len(content)), "audio/mpeg"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	presigned, err := s.PresignedURL(ctx, key, 15*time.Minute)
+	if err != nil {
+		t.Fatalf("PresignedURL: %v", err)
+	}
+	u, err := url.Parse(presigned)
+	if err != nil {
+		t.Fatalf("parse presigned URL: %v", err)
+	}
+	q := u.Query()
+	if q.Get("X-Amz-Signature") == "" {
+		t.Fatalf("presigned URL %q has no X-Amz-Signature", presigned)
+	}
+	if q.Get("X-Amz-Expires") != "900" {
+		t.Fatalf("X-Amz-Expires = %q, want %q", q.Get("X-Amz-Expires"), "900")
+	}
+
+	// GET không có header Authorization, chỉ dùng chữ ký trong query
+	resp, err := http.Get(presigned)
+	if err != nil {
+		t.Fatalf("GET presigned URL: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET presigned URL: status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read presigned body: %v", err)
+	}
+	if string(data) != content {
+		t.Fatalf("presigned body %q, want %q", data, content)
+	}
+}
+
+func TestS3PresignedURLInvalid(t *testing.T) {
+	s, _ := newTestS3(t)
+	ctx := context.Background()
+
+	if _, err := s.PresignedURL(ctx, "/audio/abc.mp3", time.Minute); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("PresignedURL with invalid key = %v, want ErrInvalidKey", err)
+	}
+	for _, expiry := range []time.Duration{0, 8 * 24 * time.Hour} {
+		if _, err := s.PresignedURL(ctx, "audio/abc.mp3", expiry); err == nil {
+			t.Errorf("PresignedURL with expiry %s succeeded, want an error", expiry)
+		}
+	}
+}
+
+func TestS3PutUnknownSize(t *testing.T) {
+	s, _ := newTestS3(t)
+	ctx := context.Background()
+
+	for _, content := range []string{"body of unknown length", ""} {
+		key := "audio/unknown-" + strconv.Itoa(len(content)) + ".mp3"
+		// MultiReader che độ dài để request không tự có Content-Length
+		if err := s.Put(ctx, key, io.MultiReader(strings.NewReader(content)), -1, "audio/mpeg"); err != nil {
+			t.Fatalf("Put(%q) with size -1: %v", content, err)
+		}
+		info, err := s.Stat(ctx, key)
+		if err != nil {
+			t.Fatalf("Stat: %v", err)
+		}
+		if info.Size != int64(len(content)) {
+			t.Fatalf("Stat size = %d, want %d", info.Size, len(content))
+		}
+	}
+}
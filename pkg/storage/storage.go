@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+var (
+	ErrNotFound           = errors.New("storage: object not found")
+	ErrInvalidKey         = errors.New("storage: invalid key")
+	ErrPresignUnsupported = errors.New("storage: presigned URLs not supported")
+)
+
+// ObjectInfo describes a stored object
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ContentType  string
+	LastModified time.Time
+}
+
+// Object is an opened object. It supports seeking so it can be served with
+// http.ServeContent (Range requests).
+type Object interface {
+	io.ReadSeeker
+	io.Closer
+}
+
+// Blob stores audio files and other binary objects under opaque keys.
+// Keys are relative slash-separated paths like "audio/0190c3b2.mp3".
+type Blob interface {
+	// Put stores r under key. size is the length of r, or -1 if unknown.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Open opens an object for reading
+	Open(ctx context.Context, key string) (Object, error)
+	// Stat returns object metadata, ErrNotFound if it doesn't exist
+	Stat(ctx context.Context, key string) (ObjectInfo, error)
+	// Delete removes an object. Deleting a missing object is not an error.
+	Delete(ctx context.Context, key string) error
+	// PresignedURL returns a URL that allows a GET of key until expiry, or
+	// ErrPresignUnsupported if the backend cannot sign URLs; the object must
+	// then be served through the API
+	PresignedURL(ctx context.Context, key string, expiry time.Duration) (string, error)
+}
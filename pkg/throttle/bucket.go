@@ -0,0 +1,116 @@
+package throttle
+
+import (
+	"sync"
+	"time"
+)
+
+// Bucket is a token bucket of bytes. The readers of all requests of one
+// stream share a bucket, so seeking with new Range requests, even in
+// parallel, does not get a new burst.
+type Bucket struct {
+	mu          sync.Mutex
+	bytesPerSec float64
+	burst       float64
+	tokens      float64
+	last        time.Time
+}
+
+// NewBucket creates a full bucket: burst bytes can be sent at once, then
+// bytesPerSec
+func NewBucket(bytesPerSec, burst int64) *Bucket {
+	if burst < 0 {
+		burst = 0
+	}
+	return &Bucket{
+		bytesPerSec: float64(bytesPerSec),
+		burst:       float64(burst),
+		tokens:      float64(burst),
+		last:        time.Now(),
+	}
+}
+
+// refill adds the tokens earned since the last call, b.mu must be held
+func (b *Bucket) refill(now time.Time) {
+	b.tokens += now.Sub(b.last).Seconds() * b.bytesPerSec
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+}
+
+// Take reserves n bytes and returns how long to wait before sending them.
+// The bucket can go into debt, so concurrent readers queue up behind each
+// other instead of all sending at once.
+func (b *Bucket) Take(n int64) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill(time.Now())
+	b.tokens -= float64(n)
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.bytesPerSec * float64(time.Second))
+}
+
+// full reports whether the bucket is back to its burst, i.e. unused
+func (b *Bucket) full(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill(now)
+	return b.tokens >= b.burst
+}
+
+// Buckets keeps one Bucket per key (e.g. account and song)
+type Buckets struct {
+	buckets map[string]*Bucket
+	mu      sync.Mutex
+}
+
+// NewBuckets creates an empty set of buckets
+func NewBuckets() *Buckets {
+	bs := &Buckets{buckets: make(map[string]*Bucket)}
+
+	// Cleanup goroutine to remove buckets nobody has used for a while
+	go bs.cleanup()
+
+	return bs
+}
+
+// cleanup periodically removes full buckets, a new bucket would be the same
+func (bs *Buckets) cleanup() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		bs.mu.Lock()
+		now := time.Now()
+		for key, b := range bs.buckets {
+			if b.full(now) {
+				delete(bs.buckets, key)
+			}
+		}
+		bs.mu.Unlock()
+	}
+}
+
+// Get returns the bucket of key, creating it with the given rate and burst.
+// A bucket with another rate (e.g. the account changed tier) is replaced.
+// bytesPerSec <= 0 means unlimited and returns nil.
+func (bs *Buckets) Get(key string, bytesPerSec, burst int64) *Bucket {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	b, exists := bs.buckets[key]
+	if !exists || b.bytesPerSec != float64(bytesPerSec) || b.burst != float64(max(burst, 0)) {
+		b = NewBucket(bytesPerSec, burst)
+		bs.buckets[key] = b
+	}
+	return b
+}
@@ -0,0 +1,66 @@
+package throttle
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// chunkSize caps a single Read so the sleep between reads stays short and the
+// output is smooth instead of bursty.
+const chunkSize = 16 * 1024
+
+// ReadSeeker wraps an io.ReadSeeker and limits how fast it can be read with
+// a Bucket. The bucket's burst is served at full speed so playback can start
+// immediately, after that reads are paced to the bucket's rate.
+type ReadSeeker struct {
+	ctx    context.Context
+	rs     io.ReadSeeker
+	bucket *Bucket
+}
+
+// NewReadSeeker returns an io.ReadSeeker throttled by bucket.
+// A nil bucket disables throttling and returns rs unchanged.
+// Reads stop with ctx.Err() when ctx is cancelled (e.g. client disconnected).
+func NewReadSeeker(ctx context.Context, rs io.ReadSeeker, bucket *Bucket) io.ReadSeeker {
+	if bucket == nil {
+		return rs
+	}
+	return &ReadSeeker{
+		ctx:    ctx,
+		rs:     rs,
+		bucket: bucket,
+	}
+}
+
+// Read reads up to len(p) bytes, sleeping as needed to respect the rate limit
+func (t *ReadSeeker) Read(p []byte) (int, error) {
+	if err := t.ctx.Err(); err != nil {
+		return 0, err
+	}
+	if len(p) > chunkSize {
+		p = p[:chunkSize]
+	}
+
+	n, err := t.rs.Read(p)
+
+	// Chờ tới khi bucket (dùng chung cho mọi request của stream) đủ token
+	if wait := t.bucket.Take(int64(n)); wait > 0 {
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-t.ctx.Done():
+			timer.Stop()
+			return n, t.ctx.Err()
+		}
+	}
+
+	return n, err
+}
+
+// Seek passes through to the underlying reader. The rate budget is in the
+// bucket, which is shared by the requests of a stream, so seeking with new
+// Range requests can't bypass it.
+func (t *ReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	return t.rs.Seek(offset, whence)
+}
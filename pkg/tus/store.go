@@ -0,0 +1,277 @@
+package tus
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Info describes an upload. It is saved next to the staged data as JSON.
+type Info struct {
+	ID        string            `json:"id"`
+	Owner     string            `json:"owner"` // user who created the upload
+	Length    int64             `json:"length"`
+	Offset    int64             `json:"offset"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	ExpiresAt time.Time         `json:"expires_at"`
+
+	// Result is set by the application once a complete upload has been
+	// processed (e.g. the ID of the created resource); the staged data is
+	// removed at that point
+	Result string `json:"result,omitempty"`
+}
+
+// Complete reports whether all bytes have been received
+func (i *Info) Complete() bool {
+	return i.Offset == i.Length
+}
+
+// Store keeps uploads in a directory: <id>.info (JSON) and <id>.bin (data).
+// Locks are held in memory, so a directory must be used by a single process.
+type Store struct {
+	dir    string
+	expiry time.Duration // an upload expires this long after its last PATCH
+
+	mu    sync.Mutex
+	locks map[string]bool
+}
+
+// NewStore creates a store in dir
+func NewStore(dir string, expiry time.Duration) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Store{dir: dir, expiry: expiry, locks: map[string]bool{}}, nil
+}
+
+func (s *Store) infoPath(id string) string { return filepath.Join(s.dir, id+".info") }
+func (s *Store) dataPath(id string) string { return filepath.Join(s.dir, id+".bin") }
+
+// validID rejects IDs that are not generated by Create (path traversal)
+func validID(id string) bool {
+	if len(id) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
+
+// Create starts a new upload of length bytes
+func (s *Store) Create(owner string, length int64, meta map[string]string) (*Info, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	info := &Info{
+		ID:        hex.EncodeToString(b),
+		Owner:     owner,
+		Length:    length,
+		Metadata:  meta,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.expiry),
+	}
+
+	f, err := os.OpenFile(s.dataPath(info.ID), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+	if err := s.saveInfo(info); err != nil {
+		os.Remove(s.dataPath(info.ID))
+		return nil, err
+	}
+	return info, nil
+}
+
+// Get returns an upload. Expired uploads are removed and reported as ErrNotFound.
+func (s *Store) Get(id string) (*Info, error) {
+	if !validID(id) {
+		return nil, ErrNotFound
+	}
+	data, err := os.ReadFile(s.infoPath(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var info Info
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, err
+	}
+	if time.Now().After(info.ExpiresAt) {
+		s.remove(id)
+		return nil, ErrNotFound
+	}
+	return &info, nil
+}
+
+// Lock reserves an upload for one request (PATCH, processing, DELETE).
+// It returns ErrLocked if another request holds it.
+func (s *Store) Lock(id string) (unlock func(), err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.locks[id] {
+		return nil, ErrLocked
+	}
+	s.locks[id] = true
+	return func() {
+		s.mu.Lock()
+		delete(s.locks, id)
+		s.mu.Unlock()
+	}, nil
+}
+
+// Append writes the data of r at offset. The caller must hold the lock.
+// Bytes received before a read error are kept, so the client can resume
+// from the returned Info's Offset. A chunk going past the upload length is
+// discarded entirely (ErrTooLarge).
+func (s *Store) Append(id string, offset int64, r io.Reader) (*Info, error) {
+	info, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if offset != info.Offset {
+		return info, ErrOffsetMismatch
+	}
+
+	f, err := os.OpenFile(s.dataPath(id), os.O_WRONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	// Cắt phần dữ liệu thừa của lần PATCH trước bị ngắt giữa chừng
+	if err := f.Truncate(offset); err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	// Đọc thêm 1 byte để phát hiện body dài hơn phần còn lại
+	n, copyErr := io.Copy(f, io.LimitReader(r, info.Length-offset+1))
+	if n > info.Length-offset {
+		// Bỏ cả chunk: client gửi sai dữ liệu
+		if err := f.Truncate(offset); err != nil {
+			return nil, err
+		}
+		return info, ErrTooLarge
+	}
+	if err := f.Sync(); err != nil {
+		return nil, err
+	}
+
+	info.Offset += n
+	info.ExpiresAt = time.Now().Add(s.expiry)
+	if err := s.saveInfo(info); err != nil {
+		return nil, err
+	}
+	return info, copyErr
+}
+
+// Open opens the staged data of an upload
+func (s *Store) Open(id string) (*os.File, error) {
+	if !validID(id) {
+		return nil, ErrNotFound
+	}
+	f, err := os.Open(s.dataPath(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// Finish records the result of processing a complete upload and removes
+// its staged data. The upload is kept until it expires so clients can
+// still query the result.
+func (s *Store) Finish(id, result string) (*Info, error) {
+	info, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	info.Result = result
+	if err := s.saveInfo(info); err != nil {
+		return nil, err
+	}
+	os.Remove(s.dataPath(id))
+	return info, nil
+}
+
+// Delete removes an upload (termination)
+func (s *Store) Delete(id string) error {
+	if _, err := s.Get(id); err != nil {
+		return err
+	}
+	s.remove(id)
+	return nil
+}
+
+func (s *Store) remove(id string) {
+	os.Remove(s.dataPath(id))
+	os.Remove(s.infoPath(id))
+}
+
+// saveInfo writes the info file atomically (temp file + rename)
+func (s *Store) saveInfo(info *Info) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	tmp := s.infoPath(info.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.infoPath(info.ID))
+}
+
+// Cleanup removes expired uploads and returns how many were removed
+func (s *Store) Cleanup() (int, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".info")
+		if !ok || !validID(id) {
+			continue
+		}
+		unlock, err := s.Lock(id)
+		if err != nil {
+			continue // đang được PATCH
+		}
+		// Get xoá upload đã hết hạn
+		if _, err := s.Get(id); errors.Is(err, ErrNotFound) {
+			removed++
+		}
+		unlock()
+	}
+	return removed, nil
+}
+
+// Run removes expired uploads every interval until ctx is cancelled
+func (s *Store) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Cleanup() // lỗi (vd. đọc thư mục) sẽ được thử lại ở lần sau
+		}
+	}
+}
@@ -0,0 +1,71 @@
+// Package tus implements the server side storage of the tus 1.0 resumable
+// upload protocol: uploads are staged on disk and appended chunk by chunk.
+// https://tus.io/protocols/resumable-upload
+package tus
+
+import (
+	"encoding/base64"
+	"errors"
+	"sort"
+	"strings"
+)
+
+// Version is the protocol version implemented (Tus-Resumable, Tus-Version)
+const Version = "1.0.0"
+
+// Extensions lists the supported protocol extensions (Tus-Extension)
+const Extensions = "creation,termination,expiration"
+
+// ContentType is the required Content-Type of PATCH requests
+const ContentType = "application/offset+octet-stream"
+
+var (
+	ErrNotFound       = errors.New("tus: upload not found")
+	ErrOffsetMismatch = errors.New("tus: upload offset mismatch")
+	ErrLocked         = errors.New("tus: upload is locked by another request")
+	ErrTooLarge       = errors.New("tus: data exceeds upload length")
+	ErrInvalidMeta    = errors.New("tus: invalid Upload-Metadata")
+)
+
+// ParseMetadata decodes an Upload-Metadata header:
+// comma separated "key base64(value)" pairs, the value may be omitted
+func ParseMetadata(header string) (map[string]string, error) {
+	meta := map[string]string{}
+	if strings.TrimSpace(header) == "" {
+		return meta, nil
+	}
+	for _, pair := range strings.Split(header, ",") {
+		key, encoded, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" || strings.ContainsAny(key, " ,") {
+			return nil, ErrInvalidMeta
+		}
+		if _, dup := meta[key]; dup {
+			return nil, ErrInvalidMeta
+		}
+		value, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, ErrInvalidMeta
+		}
+		meta[key] = string(value)
+	}
+	return meta, nil
+}
+
+// EncodeMetadata encodes metadata as an Upload-Metadata header
+func EncodeMetadata(meta map[string]string) string {
+	keys := make([]string, 0, len(meta))
+	for k := range meta {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if meta[k] == "" {
+			pairs = append(pairs, k)
+			continue
+		}
+		pairs = append(pairs, k+" "+base64.StdEncoding.EncodeToString([]byte(meta[k])))
+	}
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,144 @@
+// Package waveform computes min/max peak data for drawing audio waveforms,
+// compatible with the BBC audiowaveform JSON and binary (.dat) formats.
+// https://github.com/bbc/audiowaveform/blob/master/doc/DataFormat.md
+package waveform
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+
+	"spotify-clone/pkg/pcm"
+)
+
+// Bits is the resolution of peak values, data is always stored as 16-bit
+const Bits = 16
+
+// Waveform is a mono waveform: one (min, max) pair per pixel
+type Waveform struct {
+	SampleRate      int
+	SamplesPerPixel int
+	Data            []int16 // min0, max0, min1, max1, ...
+}
+
+// Length returns the number of (min, max) pairs
+func (w *Waveform) Length() int {
+	return len(w.Data) / 2
+}
+
+// Generate decodes d and computes peaks over samplesPerPixel samples.
+// Channels are mixed down to mono.
+func Generate(d pcm.Decoder, samplesPerPixel int) (*Waveform, error) {
+	if samplesPerPixel <= 0 {
+		return nil, errors.New("waveform: samples per pixel must be positive")
+	}
+	format := d.Format()
+	w := &Waveform{SampleRate: format.SampleRate, SamplesPerPixel: samplesPerPixel}
+
+	// Đưa sample về 16-bit
+	shift := format.BitsPerSample - Bits
+	count := 0
+	lo, hi := int16(math.MaxInt16), int16(math.MinInt16)
+
+	for {
+		block, err := d.ReadBlock()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range block[0] {
+			var sum int64
+			for ch := range block {
+				sum += int64(block[ch][i])
+			}
+			s := sum / int64(len(block))
+			if shift > 0 {
+				s >>= shift
+			} else {
+				s <<= -shift
+			}
+			v := int16(max(math.MinInt16, min(math.MaxInt16, s)))
+
+			lo, hi = min(lo, v), max(hi, v)
+			count++
+			if count == samplesPerPixel {
+				w.Data = append(w.Data, lo, hi)
+				count = 0
+				lo, hi = math.MaxInt16, math.MinInt16
+			}
+		}
+	}
+	if count > 0 {
+		w.Data = append(w.Data, lo, hi)
+	}
+	return w, nil
+}
+
+// Resample returns a waveform with at most points pairs by merging whole
+// pixels, so samples per pixel stays an integer multiple of the original.
+func (w *Waveform) Resample(points int) *Waveform {
+	length := w.Length()
+	if points <= 0 || points >= length {
+		return w
+	}
+
+	factor := (length + points - 1) / points
+	out := &Waveform{
+		SampleRate:      w.SampleRate,
+		SamplesPerPixel: w.SamplesPerPixel * factor,
+		Data:            make([]int16, 0, 2*((length+factor-1)/factor)),
+	}
+	for start := 0; start < length; start += factor {
+		end := min(start+factor, length)
+		lo, hi := w.Data[2*start], w.Data[2*start+1]
+		for i := start + 1; i < end; i++ {
+			lo, hi = min(lo, w.Data[2*i]), max(hi, w.Data[2*i+1])
+		}
+		out.Data = append(out.Data, lo, hi)
+	}
+	return out
+}
+
+// MarshalJSON encodes the waveform in audiowaveform JSON format (version 2)
+func (w *Waveform) MarshalJSON() ([]byte, error) {
+	data := w.Data
+	if data == nil {
+		data = []int16{}
+	}
+	return json.Marshal(struct {
+		Version         int     `json:"version"`
+		Channels        int     `json:"channels"`
+		SampleRate      int     `json:"sample_rate"`
+		SamplesPerPixel int     `json:"samples_per_pixel"`
+		Bits            int     `json:"bits"`
+		Length          int     `json:"length"`
+		Data            []int16 `json:"data"`
+	}{2, 1, w.SampleRate, w.SamplesPerPixel, Bits, w.Length(), data})
+}
+
+// WriteDat writes the waveform in audiowaveform binary format version 1
+func (w *Waveform) WriteDat(out io.Writer) error {
+	bw := bufio.NewWriter(out)
+	header := []any{
+		int32(1),  // version
+		uint32(0), // flags: 16-bit data
+		int32(w.SampleRate),
+		int32(w.SamplesPerPixel),
+		uint32(w.Length()),
+	}
+	for _, v := range header {
+		if err := binary.Write(bw, binary.LittleEndian, v); err != nil {
+			return err
+		}
+	}
+	if err := binary.Write(bw, binary.LittleEndian, w.Data); err != nil {
+		return err
+	}
+	return bw.Flush()
+}